@@ -0,0 +1,285 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Aggregation holds a value computed bottom-up for every node of a tree, such
+// as the total size of the files below a directory. Create one with Aggregate.
+//
+// Values are cached per node. The tree's mutation methods, including undo and
+// redo, invalidate the changed node and its ancestors, and Value recomputes
+// just those on the next read, so formatters such as WithFileStatsFormatter
+// can read them on every render. Changes made to nodes directly, for example
+// with Node.SetData, are not seen; call Refresh afterwards. Nodes of a
+// Snapshot get values of their own, which are kept until the next snapshot
+// is taken.
+type Aggregation[T, A any] struct {
+	tree    *Tree[T]
+	leaf    func(*Node[T]) A
+	combine func(A, A) A
+
+	mu     sync.Mutex
+	values map[*Node[T]]A
+	// frozen holds the values of snapshot copies. They never go stale, but
+	// every snapshot brings new copies, so they are dropped whenever the
+	// next snapshot is taken rather than kept alongside the live nodes.
+	frozen map[*Node[T]]A
+}
+
+// aggregateCache is the part of an Aggregation the tree talks to. Its methods
+// take the aggregation's lock; tree methods holding t.mu may call them, never
+// the other way round.
+type aggregateCache[T any] interface {
+	// invalidate drops the values of node and its ancestors.
+	invalidate(node *Node[T])
+	// forget drops the values of a subtree removed from the tree.
+	forget(node *Node[T])
+	// reset drops every value.
+	reset()
+	// dropFrozen drops the values of snapshot copies.
+	dropFrozen()
+}
+
+// Aggregate computes a value for every node of tree: leaf gives the node's own
+// value, which combine then merges with the values of its children in order.
+// The result stays registered with the tree until Close is called. Returns
+// context errors unwrapped.
+//
+// Example:
+//
+//	sizes, err := treeview.Aggregate(ctx, tree,
+//	    func(n *treeview.Node[Item]) int64 { return n.Data().Size },
+//	    func(a, b int64) int64 { return a + b },
+//	)
+//	total := sizes.Value(root)
+func Aggregate[T, A any](ctx context.Context, tree *Tree[T], leaf func(*Node[T]) A, combine func(A, A) A) (*Aggregation[T, A], error) {
+	a := &Aggregation[T, A]{
+		tree:    tree,
+		leaf:    leaf,
+		combine: combine,
+		values:  make(map[*Node[T]]A),
+		frozen:  make(map[*Node[T]]A),
+	}
+
+	// Register before computing so no mutation in between goes unnoticed
+	tree.mu.Lock()
+	tree.aggregates = append(tree.aggregates, a)
+	tree.mu.Unlock()
+
+	if err := a.Refresh(ctx); err != nil {
+		a.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Value returns the aggregate of node and everything below it, computing it
+// if it isn't cached. It takes the tree's read lock, so it must not be called
+// from code the tree runs under its lock, such as Format or a LessFn given to
+// Sort; use ValueLocked there.
+func (a *Aggregation[T, A]) Value(node *Node[T]) A {
+	// The tree lock comes first: it must not be acquired under a.mu
+	a.tree.mu.RLock()
+	defer a.tree.mu.RUnlock()
+	return a.ValueLocked(node)
+}
+
+// ValueLocked is Value for callers that already hold the tree lock: a
+// provider's Format or a formatter given to WithFormatter, which run while
+// the tree renders, and a LessFn given to Sort. It is also safe on the nodes
+// of a Snapshot, which never change.
+//
+// Example:
+//
+//	treeview.WithFormatter(func(n *treeview.Node[Item]) (string, bool) {
+//	    return fmt.Sprintf("%s (%d)", n.Name(), sizes.ValueLocked(n)), true
+//	})
+func (a *Aggregation[T, A]) ValueLocked(node *Node[T]) A {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.value(node)
+}
+
+// value returns the cached aggregate of node or computes it. Callers must
+// hold a.mu.
+func (a *Aggregation[T, A]) value(node *Node[T]) A {
+	cache := a.values
+	if node.frozen {
+		cache = a.frozen
+	}
+	if v, ok := cache[node]; ok {
+		return v
+	}
+	v := a.leaf(node)
+	for _, child := range node.Children() {
+		v = a.combine(v, a.value(child))
+	}
+	cache[node] = v
+	return v
+}
+
+// Refresh recomputes the aggregate of every node, for example after nodes
+// were changed directly rather than through the tree's methods. Returns
+// context errors unwrapped, in which case the cache is left empty and values
+// are computed on demand.
+func (a *Aggregation[T, A]) Refresh(ctx context.Context) error {
+	// The tree lock comes first: it must not be acquired under a.mu
+	a.tree.mu.RLock()
+	defer a.tree.mu.RUnlock()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.values = make(map[*Node[T]]A)
+	for info, err := range bottomUpSeq(ctx, a.tree.nodes, true, nil) {
+		if err != nil {
+			a.values = make(map[*Node[T]]A)
+			return err
+		}
+		// Children come first, so their values are already cached
+		a.value(info.Node)
+	}
+	return nil
+}
+
+// Close unregisters the aggregation from its tree, which stops invalidating
+// it, and drops the cached values.
+func (a *Aggregation[T, A]) Close() {
+	a.tree.mu.Lock()
+	a.tree.aggregates = slices.DeleteFunc(slices.Clone(a.tree.aggregates), func(c aggregateCache[T]) bool {
+		return c == aggregateCache[T](a)
+	})
+	a.tree.mu.Unlock()
+	a.reset()
+}
+
+func (a *Aggregation[T, A]) invalidate(node *Node[T]) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for n := node; n != nil; n = n.Parent() {
+		delete(a.values, n)
+	}
+}
+
+func (a *Aggregation[T, A]) forget(node *Node[T]) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for info := range dfsSeq(context.Background(), []*Node[T]{node}, true, nil) {
+		delete(a.values, info.Node)
+	}
+}
+
+func (a *Aggregation[T, A]) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.values = make(map[*Node[T]]A)
+	a.frozen = make(map[*Node[T]]A)
+}
+
+func (a *Aggregation[T, A]) dropFrozen() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.frozen = make(map[*Node[T]]A)
+}
+
+// invalidateAggregates, forgetAggregates and resetAggregates keep the
+// registered aggregations in sync with a mutation. Callers must hold t.mu.
+func (t *Tree[T]) invalidateAggregates(node *Node[T]) {
+	if node == nil {
+		return
+	}
+	for _, a := range t.aggregates {
+		a.invalidate(node)
+	}
+}
+
+func (t *Tree[T]) forgetAggregates(node *Node[T]) {
+	for _, a := range t.aggregates {
+		a.forget(node)
+	}
+}
+
+func (t *Tree[T]) resetAggregates() {
+	for _, a := range t.aggregates {
+		a.reset()
+	}
+}
+
+// FileStats summarises the files in a file system subtree: their number,
+// total size and the latest modification time.
+type FileStats struct {
+	Files  int       // Regular files, not counting directories
+	Bytes  int64     // Total size of the files
+	Newest time.Time // Latest modification time of any entry
+}
+
+// String formats the stats as "12 files, 3.4 MB".
+func (s FileStats) String() string {
+	noun := "files"
+	if s.Files == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("%d %s, %s", s.Files, noun, formatBytes(s.Bytes))
+}
+
+// FileStatsLeaf is the leaf function of AggregateFileStats: the stats of a
+// single file system entry.
+func FileStatsLeaf(node *Node[FileInfo]) FileStats {
+	info := node.Data().FileInfo
+	if info == nil {
+		return FileStats{}
+	}
+	stats := FileStats{Newest: info.ModTime()}
+	if !info.IsDir() {
+		stats.Files = 1
+		stats.Bytes = info.Size()
+	}
+	return stats
+}
+
+// CombineFileStats is the combine function of AggregateFileStats.
+func CombineFileStats(a, b FileStats) FileStats {
+	newest := a.Newest
+	if b.Newest.After(newest) {
+		newest = b.Newest
+	}
+	return FileStats{Files: a.Files + b.Files, Bytes: a.Bytes + b.Bytes, Newest: newest}
+}
+
+// AggregateFileStats aggregates the file count, total size and newest
+// modification time of every directory in a file system tree. Returns
+// context errors unwrapped.
+func AggregateFileStats(ctx context.Context, tree *Tree[FileInfo]) (*Aggregation[FileInfo, FileStats], error) {
+	return Aggregate(ctx, tree, FileStatsLeaf, CombineFileStats)
+}
+
+// WithFileStatsFormatter is a provider Option that appends the stats of
+// collapsed directories to their name, as in "src/ (12 files, 3.4 MB)".
+// Expanded directories show their contents instead. Install the provider
+// with Tree.SetProvider once the aggregation exists.
+func WithFileStatsFormatter(stats *Aggregation[FileInfo, FileStats]) ProviderOption[FileInfo] {
+	return WithNameFormatter(func(n *Node[FileInfo]) (string, int, bool) {
+		if !isDir(n) || n.IsExpanded() {
+			return "", 0, false
+		}
+		return fmt.Sprintf("%s/ (%s)", n.Name(), stats.ValueLocked(n)), 0, true
+	})
+}
+
+// formatBytes formats a byte count in binary units, such as "3.4 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
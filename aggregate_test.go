@@ -0,0 +1,376 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// createAggregateTree returns root "r" with children "a" (children "a1", "a2")
+// and "b", plus root "s". Every node's data is its weight.
+func createAggregateTree() *Tree[int] {
+	a := NewNode("a", "a", 1)
+	a.SetChildren([]*Node[int]{NewNode("a1", "a1", 2), NewNode("a2", "a2", 3)})
+	r := NewNode("r", "r", 0)
+	r.SetChildren([]*Node[int]{a, NewNode("b", "b", 4)})
+	return NewTree([]*Node[int]{r, NewNode("s", "s", 5)})
+}
+
+// sumWeights aggregates the weights of a createAggregateTree tree.
+func sumWeights(t *testing.T, tree *Tree[int]) *Aggregation[int, int] {
+	t.Helper()
+	sums, err := Aggregate(context.Background(), tree,
+		func(n *Node[int]) int { return *n.Data() },
+		func(a, b int) int { return a + b },
+	)
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+	return sums
+}
+
+// aggregateValues returns the aggregate of every node by ID.
+func aggregateValues[A any](t *testing.T, tree *Tree[int], agg *Aggregation[int, A]) map[string]A {
+	t.Helper()
+	values := make(map[string]A)
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		values[info.Node.ID()] = agg.Value(info.Node)
+	}
+	return values
+}
+
+func TestAggregate(t *testing.T) {
+	tree := createAggregateTree()
+	sums := sumWeights(t, tree)
+
+	want := map[string]int{"r": 10, "a": 6, "a1": 2, "a2": 3, "b": 4, "s": 5}
+	if diff := cmp.Diff(want, aggregateValues(t, tree, sums)); diff != "" {
+		t.Errorf("Aggregate() values mismatch (-want +got):\n%s", diff)
+	}
+
+	// Children are combined in order
+	order, err := Aggregate(context.Background(), tree,
+		func(n *Node[int]) string { return n.ID() },
+		func(a, b string) string { return a + "," + b },
+	)
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+	root, _ := tree.FindByID(context.Background(), "r")
+	if got := order.Value(root); got != "r,a,a1,a2,b" {
+		t.Errorf("Aggregate().Value(r) = %q, want %q", got, "r,a,a1,a2,b")
+	}
+}
+
+func TestAggregate_Invalidation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		mutate   func(*Tree[int]) error
+		want     map[string]int
+		keptOnly []string // Cached values that must survive the mutation
+	}{
+		{
+			name:     "set_data",
+			mutate:   func(tree *Tree[int]) error { return tree.SetNodeData(ctx, "a1", 12) },
+			want:     map[string]int{"r": 20, "a": 16, "a1": 12, "a2": 3, "b": 4, "s": 5},
+			keptOnly: []string{"a2", "b", "s"},
+		},
+		{
+			name: "insert",
+			mutate: func(tree *Tree[int]) error {
+				return tree.InsertNode(ctx, "b", 0, NewNode("b1", "b1", 7))
+			},
+			want:     map[string]int{"r": 17, "a": 6, "a1": 2, "a2": 3, "b": 11, "b1": 7, "s": 5},
+			keptOnly: []string{"a", "a1", "a2", "s"},
+		},
+		{
+			name: "remove",
+			mutate: func(tree *Tree[int]) error {
+				_, err := tree.RemoveNode(ctx, "a")
+				return err
+			},
+			want:     map[string]int{"r": 4, "b": 4, "s": 5},
+			keptOnly: []string{"b", "s"},
+		},
+		{
+			name:     "move",
+			mutate:   func(tree *Tree[int]) error { return tree.MoveNode(ctx, "a2", "s", 0) },
+			want:     map[string]int{"r": 7, "a": 3, "a1": 2, "a2": 3, "b": 4, "s": 8},
+			keptOnly: []string{"a1", "a2", "b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createAggregateTree()
+			sums := sumWeights(t, tree)
+			before := aggregateValues(t, tree, sums)
+
+			if err := test.mutate(tree); err != nil {
+				t.Fatalf("mutation error = %v", err)
+			}
+
+			var kept []string
+			for node := range sums.values {
+				kept = append(kept, node.ID())
+			}
+			if diff := cmp.Diff(test.keptOnly, kept, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("cached after mutation mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.want, aggregateValues(t, tree, sums)); diff != "" {
+				t.Errorf("Value() after mutation mismatch (-want +got):\n%s", diff)
+			}
+
+			if _, err := tree.Undo(ctx); err != nil {
+				t.Fatalf("Undo() error = %v", err)
+			}
+			if diff := cmp.Diff(before, aggregateValues(t, tree, sums)); diff != "" {
+				t.Errorf("Value() after undo mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAggregate_RefreshAndClose(t *testing.T) {
+	ctx := context.Background()
+	tree := createAggregateTree()
+	sums := sumWeights(t, tree)
+	root, _ := tree.FindByID(ctx, "r")
+	a1, _ := tree.FindByID(ctx, "a1")
+
+	// Direct changes are not seen until Refresh
+	a1.SetData(100)
+	if got := sums.Value(root); got != 10 {
+		t.Errorf("Value(r) before Refresh = %d, want 10", got)
+	}
+	if err := sums.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got := sums.Value(root); got != 108 {
+		t.Errorf("Value(r) after Refresh = %d, want 108", got)
+	}
+
+	sums.Close()
+	if len(tree.aggregates) != 0 {
+		t.Errorf("Close() left %d registered aggregations, want 0", len(tree.aggregates))
+	}
+}
+
+func TestAggregate_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	tree := createAggregateTree()
+	sums := sumWeights(t, tree)
+
+	old := tree.Snapshot()
+	for i := range 20 {
+		if err := tree.SetNodeData(ctx, "a1", i); err != nil {
+			t.Fatalf("SetNodeData() error = %v", err)
+		}
+		snap := tree.Snapshot()
+		if got, want := sums.Value(snap.Nodes()[0]), 8+i; got != want {
+			t.Errorf("Value(snapshot r) = %d, want %d", got, want)
+		}
+	}
+	// Only the copies valued since the last snapshot are kept
+	if len(sums.frozen) > 5 {
+		t.Errorf("%d snapshot values cached, want at most one snapshot's 5", len(sums.frozen))
+	}
+	for node := range sums.values {
+		if node.frozen {
+			t.Errorf("live cache holds snapshot copy %s", node.ID())
+		}
+	}
+
+	// Frozen copies never change, so an old snapshot keeps its values
+	if got := sums.Value(old.Nodes()[0]); got != 10 {
+		t.Errorf("Value(first snapshot r) = %d, want 10", got)
+	}
+}
+
+// Run with -race: Value walks children while the tree changes.
+func TestAggregate_ConcurrentValue(t *testing.T) {
+	ctx := context.Background()
+	tree := createAggregateTree()
+	sums := sumWeights(t, tree)
+	root, _ := tree.FindByID(ctx, "r")
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		defer close(done)
+		close(started)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				sums.Value(root)
+			}
+		}
+	}()
+	<-started
+	for i := range 100 {
+		id := fmt.Sprint("n", i)
+		if err := tree.InsertNode(ctx, "a", 0, NewNode(id, id, 1)); err != nil {
+			t.Fatalf("InsertNode() error = %v", err)
+		}
+	}
+	close(stop)
+	<-done
+
+	if got := sums.Value(root); got != 110 {
+		t.Errorf("Value(r) = %d, want 110", got)
+	}
+}
+
+// A formatter runs under the render lock, so it must be able to read values
+// while a writer is queued behind that lock.
+func TestAggregate_ValueLockedFromFormatter(t *testing.T) {
+	ctx := context.Background()
+	tree := createAggregateTree()
+	sums := sumWeights(t, tree)
+
+	var once sync.Once
+	renamed := make(chan error, 1)
+	tree.SetProvider(NewDefaultNodeProvider(WithFormatter(func(n *Node[int]) (string, bool) {
+		once.Do(func() {
+			go func() { renamed <- tree.Rename(ctx, "s", "s2") }()
+			// Give the writer time to queue behind the render lock
+			time.Sleep(20 * time.Millisecond)
+		})
+		return fmt.Sprintf("%s=%d", n.Name(), sums.ValueLocked(n)), true
+	})))
+
+	rendered := make(chan string, 1)
+	go func() {
+		out, err := tree.Render(ctx)
+		if err != nil {
+			t.Errorf("Render() error = %v", err)
+		}
+		rendered <- out
+	}()
+
+	select {
+	case out := <-rendered:
+		if !strings.Contains(out, "r=10") {
+			t.Errorf("Render() = %q, want it to contain %q", out, "r=10")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Render() deadlocked with a queued writer")
+	}
+	if err := <-renamed; err != nil {
+		t.Errorf("Rename() error = %v", err)
+	}
+}
+
+func TestAggregate_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree := createAggregateTree()
+
+	_, err := Aggregate(ctx, tree,
+		func(n *Node[int]) int { return *n.Data() },
+		func(a, b int) int { return a + b },
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Aggregate(cancelled) error = %v, want context.Canceled", err)
+	}
+	if len(tree.aggregates) != 0 {
+		t.Errorf("Aggregate(cancelled) left %d registered aggregations, want 0", len(tree.aggregates))
+	}
+}
+
+func TestAggregateFileStats(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	newest := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	files := map[string]int{"a.txt": 100, "sub/b.txt": 2000, "sub/deep/c.txt": 3000}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, newest.Add(-time.Hour), newest.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sub := range []string{"sub/deep", "sub", ""} {
+		path := filepath.Join(dir, sub)
+		if err := os.Chtimes(path, newest.Add(-time.Hour), newest.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(filepath.Join(dir, "sub", "deep", "c.txt"), newest, newest); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := NewTreeFromFileSystem(ctx, dir, false)
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	stats, err := AggregateFileStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("AggregateFileStats() error = %v", err)
+	}
+
+	root := tree.Nodes()[0]
+	got := stats.Value(root)
+	if got.Files != 3 || got.Bytes != 5100 || !got.Newest.Equal(newest) {
+		t.Errorf("AggregateFileStats() root = %+v, want 3 files, 5100 bytes, newest %v", got, newest)
+	}
+	if s := got.String(); s != "3 files, 5.0 KB" {
+		t.Errorf("FileStats.String() = %q, want %q", s, "3 files, 5.0 KB")
+	}
+
+	sub, err := tree.FindByID(ctx, filepath.Join(dir, "sub"))
+	if err != nil {
+		t.Fatalf("FindByID(sub) error = %v", err)
+	}
+	tree.SetProvider(NewFileNodeProvider(WithFileStatsFormatter(stats)))
+	if label := tree.Provider().Format(sub); label != "sub/ (2 files, 4.9 KB)" {
+		t.Errorf("Format(collapsed sub) = %q, want %q", label, "sub/ (2 files, 4.9 KB)")
+	}
+	sub.Expand()
+	if label := tree.Provider().Format(sub); label != "sub/" {
+		t.Errorf("Format(expanded sub) = %q, want %q", label, "sub/")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{3565158, "3.4 MB"},
+		{5 << 30, "5.0 GB"},
+	}
+	for _, test := range tests {
+		if got := formatBytes(test.n); got != test.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", test.n, got, test.want)
+		}
+	}
+	if got := (FileStats{Files: 1, Bytes: 10}).String(); got != "1 file, 10 B" {
+		t.Errorf("FileStats.String() = %q, want %q", got, "1 file, 10 B")
+	}
+}
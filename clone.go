@@ -0,0 +1,90 @@
+package treeview
+
+import (
+	"context"
+)
+
+// Clone returns an independent copy of the tree: deep copies of its nodes
+// (see Node.DeepClone) with the same configuration, focus and selection.
+// Undo history, subscriptions, aggregations and an active filter or hoist
+// belong to the original and are not copied.
+func (t *Tree[T]) Clone() *Tree[T] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	roots := make([]*Node[T], len(t.nodes))
+	for i, root := range t.nodes {
+		roots[i] = root.DeepClone(nil)
+	}
+	clone := t.derive(roots)
+	clone.fsSource = t.fsSource
+	return clone
+}
+
+// Subtree returns a new tree whose only root is a deep copy of the node with
+// the given ID, for example to open a zoomed-in view of one branch. It has
+// the same searcher, matcher, focus and move policies, provider and options
+// as t, and keeps the focus and selection of the copied nodes; if no copied
+// node was focused the root is. The original tree is not changed. Returns
+// ErrNodeNotFound if the ID doesn't exist, or context errors unwrapped.
+func (t *Tree[T]) Subtree(ctx context.Context, id string) (*Tree[T], error) {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.derive([]*Node[T]{node.DeepClone(nil)}), nil
+}
+
+// derive returns a tree of nodes configured like t, with the focus and
+// selection of t carried over to the nodes with the same IDs. Callers must
+// hold t.mu.
+func (t *Tree[T]) derive(nodes []*Node[T]) *Tree[T] {
+	d := &Tree[T]{
+		nodes:         nodes,
+		focusedIDs:    make(map[string]bool),
+		selectedIDs:   make(map[string]bool),
+		partialIDs:    make(map[string]bool),
+		searcher:      t.searcher,
+		matcher:       t.matcher,
+		focusPol:      t.focusPol,
+		provider:      t.provider,
+		movePolicy:    t.movePolicy,
+		truncateWidth: t.truncateWidth,
+		checkboxes:    t.checkboxes,
+		history:       history[T]{limit: t.history.limit},
+
+		filterDescendants: t.filterDescendants,
+		searchWorkers:     t.searchWorkers,
+	}
+	if t.index != nil {
+		d.index = newSearchIndex(t.index.text)
+	}
+
+	byID := make(map[string]*Node[T])
+	for info := range dfsSeq(context.Background(), nodes, true, nil) {
+		id := info.Node.ID()
+		if t.focusedIDs[id] {
+			byID[id] = info.Node
+		}
+		if t.selectedIDs[id] {
+			d.selectedIDs[id] = true
+		}
+		if t.partialIDs[id] {
+			d.partialIDs[id] = true
+		}
+	}
+	for _, node := range t.focusedNodes {
+		if copied := byID[node.ID()]; copied != nil {
+			d.focusedNodes = append(d.focusedNodes, copied)
+		}
+	}
+	if len(d.focusedNodes) == 0 && len(nodes) > 0 {
+		d.focusedNodes = []*Node[T]{nodes[0]}
+	}
+	for _, node := range d.focusedNodes {
+		d.focusedIDs[node.ID()] = true
+	}
+	return d
+}
@@ -0,0 +1,208 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNode_DeepClone(t *testing.T) {
+	ctx := context.Background()
+	type payload struct{ tags []string }
+
+	root := NewNode("root", "Root", payload{tags: []string{"r"}})
+	child := NewNode("child", "Child", payload{tags: []string{"c"}})
+	child.AddChild(NewNode("leaf", "Leaf", payload{tags: []string{"l"}}))
+	child.Expand()
+	root.AddChild(child)
+	_ = NewTree([]*Node[payload]{root})
+
+	tests := []struct {
+		name     string
+		copyData func(payload) payload
+		shared   bool
+	}{
+		{name: "assigned", shared: true},
+		{
+			name: "copied",
+			copyData: func(p payload) payload {
+				return payload{tags: append([]string(nil), p.tags...)}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clone := child.DeepClone(test.copyData)
+			if clone == child || clone.Parent() != nil {
+				t.Fatalf("DeepClone() = original or has a parent, want a detached copy")
+			}
+			var got []string
+			for info, err := range clone.All(ctx) {
+				if err != nil {
+					t.Fatalf("All() error = %v", err)
+				}
+				got = append(got, info.Node.ID()+":"+info.Node.Name())
+			}
+			if diff := cmp.Diff([]string{"child:Child", "leaf:Leaf"}, got); diff != "" {
+				t.Errorf("DeepClone() nodes mismatch (-want +got):\n%s", diff)
+			}
+			if leaf := clone.Children()[0]; leaf.Parent() != clone {
+				t.Errorf("leaf parent is not the copied child")
+			}
+			if !clone.IsExpanded() {
+				t.Errorf("DeepClone() lost the expanded flag")
+			}
+			if child.Parent() != root || len(root.Children()) != 1 {
+				t.Errorf("DeepClone() changed the original")
+			}
+
+			clone.Children()[0].Data().tags[0] = "changed"
+			if shared := child.Children()[0].Data().tags[0] == "changed"; shared != test.shared {
+				t.Errorf("payload shared = %v, want %v", shared, test.shared)
+			}
+			child.Children()[0].Data().tags[0] = "l"
+		})
+	}
+}
+
+func TestTree_Clone(t *testing.T) {
+	ctx := context.Background()
+	tree := createEventTree()
+	if err := tree.SetSelected(ctx, "a2", true); err != nil {
+		t.Fatalf("SetSelected() error = %v", err)
+	}
+	if _, err := tree.SetFocusedID(ctx, "a1"); err != nil {
+		t.Fatalf("SetFocusedID() error = %v", err)
+	}
+
+	clone := tree.Clone()
+	want, _ := tree.Render(ctx)
+	if got, _ := clone.Render(ctx); got != want {
+		t.Errorf("Clone() renders %q, want %q", got, want)
+	}
+	if id := clone.GetFocusedID(); id != "a1" {
+		t.Errorf("GetFocusedID() = %q, want %q", id, "a1")
+	}
+	if state := clone.CheckState("a"); state != PartiallyChecked {
+		t.Errorf("CheckState(a) = %v, want %v", state, PartiallyChecked)
+	}
+
+	// Changing the clone leaves the original alone
+	if _, err := clone.RemoveNode(ctx, "a"); err != nil {
+		t.Fatalf("RemoveNode() error = %v", err)
+	}
+	if got, _ := tree.Render(ctx); got != want {
+		t.Errorf("original renders %q after changing the clone, want %q", got, want)
+	}
+	if clone.CanUndo() == tree.CanUndo() {
+		t.Errorf("CanUndo() of clone = original, want separate histories")
+	}
+}
+
+func TestTree_Subtree(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		id        string
+		focus     string
+		wantIDs   []string
+		wantFocus string
+		wantErr   error
+	}{
+		{
+			name:      "keeps_focus_inside",
+			id:        "a",
+			focus:     "a2",
+			wantIDs:   []string{"a", "a1", "a2"},
+			wantFocus: "a2",
+		},
+		{
+			name:      "focuses_root",
+			id:        "a",
+			focus:     "b",
+			wantIDs:   []string{"a", "a1", "a2"},
+			wantFocus: "a",
+		},
+		{
+			name:      "leaf",
+			id:        "a1",
+			focus:     "a1",
+			wantIDs:   []string{"a1"},
+			wantFocus: "a1",
+		},
+		{
+			name:    "not_found",
+			id:      "missing",
+			focus:   "a",
+			wantErr: ErrNodeNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createEventTree()
+			if _, err := tree.SetFocusedID(ctx, test.focus); err != nil {
+				t.Fatalf("SetFocusedID() error = %v", err)
+			}
+			before, _ := tree.Render(ctx)
+			sub, err := tree.Subtree(ctx, test.id)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Subtree() error = %v, want %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var ids []string
+			for info, err := range sub.All(ctx) {
+				if err != nil {
+					t.Fatalf("All() error = %v", err)
+				}
+				ids = append(ids, info.Node.ID())
+			}
+			if diff := cmp.Diff(test.wantIDs, ids); diff != "" {
+				t.Errorf("Subtree() nodes mismatch (-want +got):\n%s", diff)
+			}
+			if id := sub.GetFocusedID(); id != test.wantFocus {
+				t.Errorf("GetFocusedID() = %q, want %q", id, test.wantFocus)
+			}
+			if root := sub.Nodes()[0]; root.Parent() != nil {
+				t.Errorf("Subtree() root has a parent")
+			}
+			if _, err := sub.RemoveNode(ctx, test.wantIDs[len(test.wantIDs)-1]); err != nil {
+				t.Fatalf("RemoveNode() error = %v", err)
+			}
+			if after, _ := tree.Render(ctx); after != before {
+				t.Errorf("original renders %q after changing the subtree, want %q", after, before)
+			}
+		})
+	}
+}
+
+func TestTree_Subtree_Configuration(t *testing.T) {
+	ctx := context.Background()
+	byID := func(ctx context.Context, node *Node[string], term string) bool {
+		return node.ID() == term
+	}
+	tree := createDiffTree([][3]string{{"root", "", ""}, {"x", "root", ""}, {"y", "root", ""}})
+	tree = NewTree(tree.Nodes(), WithSearcher(byID), WithProvider(NewDefaultNodeProvider[string]()))
+
+	sub, err := tree.Subtree(ctx, "root")
+	if err != nil {
+		t.Fatalf("Subtree() error = %v", err)
+	}
+	found, err := sub.Search(ctx, "y")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"y"}, nodeIDs(found)); diff != "" {
+		t.Errorf("Search() with the tree's searcher mismatch (-want +got):\n%s", diff)
+	}
+	if sub.Provider() != tree.Provider() {
+		t.Errorf("Provider() differs from the original tree")
+	}
+}
@@ -2,7 +2,6 @@ package treeview
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +15,7 @@ import (
 //   - WithFilterFunc:  Filters nodes recursively, keeping parents with matching children
 //   - WithMaxDepth:    Limits tree depth (0 = root only, 1 = root + children, etc.)
 //   - WithExpandFunc:  Sets initial expansion state for nodes
+//   - WithSort:        Sorts siblings once the tree is built
 //   - WithProgressCallback: Reports progress for each provided root node
 //   - WithSearcher:    Custom search algorithm
 //   - WithFocusPolicy: Custom focus navigation logic
@@ -58,6 +58,10 @@ func NewTree[T any](nodes []*Node[T], opts ...Option[T]) *Tree[T] {
 
 // NewTreeFromCfg creates a new Tree with the provided nodes and the configuration `cfg`.
 func NewTreeFromCfg[T any](nodes []*Node[T], cfg *MasterConfig[T]) *Tree[T] {
+	if cfg.sortLess != nil {
+		sortNodes(nodes, cfg.sortLess)
+	}
+
 	// Initialize focus to the first node if available
 	var focusedNodes []*Node[T]
 	focusedIDs := make(map[string]bool)
@@ -71,10 +75,22 @@ func NewTreeFromCfg[T any](nodes []*Node[T], cfg *MasterConfig[T]) *Tree[T] {
 		nodes:         nodes,
 		focusedNodes:  focusedNodes,
 		focusedIDs:    focusedIDs,
+		selectedIDs:   make(map[string]bool),
+		partialIDs:    make(map[string]bool),
 		searcher:      cfg.searcher,
+		matcher:       cfg.matcher,
 		focusPol:      cfg.focusPol,
 		provider:      cfg.provider,
+		movePolicy:    cfg.movePolicy,
 		truncateWidth: cfg.truncateWidth,
+		checkboxes:    cfg.checkboxes,
+		history:       history[T]{limit: cfg.historyLimit},
+
+		filterDescendants: cfg.filterDescendants,
+		searchWorkers:     cfg.searchWorkers,
+	}
+	if cfg.indexed && cfg.indexSafe {
+		t.index = newSearchIndex(cfg.indexText)
 	}
 	return t
 }
@@ -114,6 +130,7 @@ type NestedDataProvider[T any] interface {
 //   - WithFilterFunc:   Filters items during tree building
 //   - WithMaxDepth:     Limits tree depth during construction
 //   - WithExpandFunc:   Sets initial expansion state for nodes
+//   - WithSort:         Sorts siblings once the tree is built
 //   - WithTraversalCap: Limits total nodes processed (returns partial tree + error if exceeded)
 //   - WithProgressCallback: Invoked after each node creation (depth-first)
 //
@@ -228,9 +245,44 @@ type FlatDataProvider[T any] interface {
 	ParentID(T) string
 }
 
+// OrphanPolicy decides what NewTreeFromFlatData does with items whose parent
+// ID doesn't match any item.
+type OrphanPolicy int
+
+const (
+	// OrphanError fails the build with ErrOrphanedNode. This is the default.
+	OrphanError OrphanPolicy = iota
+	// OrphanPromote makes orphans roots, in input order among the others.
+	OrphanPromote
+	// OrphanDrop leaves orphans, and everything below them, out of the tree.
+	OrphanDrop
+)
+
+// FilterPolicy decides what NewTreeFromFlatData does with an item rejected by
+// WithFilterFunc that has children.
+type FilterPolicy int
+
+const (
+	// FilterKeepAncestors keeps rejected items that have an accepted
+	// descendant, like NewTree does, so matches stay where they were. This is
+	// the default.
+	FilterKeepAncestors FilterPolicy = iota
+	// FilterPrune drops rejected items together with everything below them.
+	FilterPrune
+	// FilterSplice drops rejected items and moves their remaining children up
+	// to take their place under the grandparent, or among the roots.
+	FilterSplice
+)
+
+// OrphanReportFn is called for every orphan found by NewTreeFromFlatData with
+// the item and the parent ID that could not be resolved.
+type OrphanReportFn[T any] func(item T, parentID string)
+
 // NewTreeFromFlatData builds a Tree from a flat list of items, where each item
 // references its parent by ID. This is useful for data from databases or APIs.
-// Returns context errors unwrapped.
+// Roots and siblings keep the order of items unless WithSort is given. Items
+// whose parent is missing fail the build unless WithOrphanPolicy says
+// otherwise. Returns context errors unwrapped.
 //
 // Example:
 //
@@ -244,11 +296,16 @@ type FlatDataProvider[T any] interface {
 //
 // Supported options:
 // Build options:
-//   - WithFilterFunc:   Filters items during tree building
+//   - WithFilterFunc:   Filters items once the hierarchy is built
+//   - WithFilterPolicy: Keeps ancestors of matches, prunes or splices rejected items
+//   - WithFilterReport: Reports how many items the filter excluded
 //   - WithMaxDepth:     Limits tree depth after hierarchy is built
 //   - WithExpandFunc:   Sets initial expansion state for nodes
+//   - WithSort:         Sorts siblings once the tree is built
 //   - WithTraversalCap: Limits total nodes processed (returns partial tree + error if exceeded)
 //   - WithProgressCallback: Invoked after each node creation (flat pass order)
+//   - WithOrphanPolicy: Fails, promotes or drops items whose parent is missing
+//   - WithOrphanReport: Invoked for each item whose parent is missing
 //
 // Options used during a tree's runtime:
 //   - WithSearcher:     Custom search algorithm
@@ -283,6 +340,12 @@ func buildTreeFromFlatData[T any](ctx context.Context, items []T, provider FlatD
 	parentLookup := make(map[string]string, len(items))
 	// idToNode lets us resolve a parent ID to the corresponding *Node.
 	idToNode := make(map[string]*Node[T], len(items))
+	// order lists the IDs in input order, so roots and siblings keep the order
+	// of the items rather than the random order of the maps.
+	order := make([]string, 0, len(items))
+	// rejected holds the nodes the filter excludes. They are removed once the
+	// hierarchy is known, since an accepted item may sit below them.
+	rejected := make(map[*Node[T]]bool)
 
 	// Track if we hit the traversal cap
 	nodeCount := 0
@@ -292,9 +355,6 @@ func buildTreeFromFlatData[T any](ctx context.Context, items []T, provider FlatD
 		if err := ctx.Err(); err != nil {
 			return nil, err // Context has ended
 		}
-		if cfg.ShouldFilter(item) {
-			return nil, nil // Item was filtered out
-		}
 		if cfg.HasTraversalCapBeenReached(nodeCount) {
 			hitTraversalCap = true // We've hit the traversal cap
 			break
@@ -307,9 +367,16 @@ func buildTreeFromFlatData[T any](ctx context.Context, items []T, provider FlatD
 		}
 		n := NewNode(id, provider.Name(item), item)
 
-		// Add to tracking collections
+		// Add to tracking collections. A repeated ID replaces the earlier
+		// item but keeps its position.
+		if _, seen := idToNode[id]; !seen {
+			order = append(order, id)
+		}
 		parentLookup[id] = provider.ParentID(item)
 		idToNode[id] = n
+		if cfg.ShouldFilter(item) {
+			rejected[n] = true
+		}
 		nodeCount++
 		cfg.ReportProgress(nodeCount, n)
 	}
@@ -317,10 +384,11 @@ func buildTreeFromFlatData[T any](ctx context.Context, items []T, provider FlatD
 	// Pass 2: Establish parent/child relationships and validate tree has no cycles
 	// Collect root nodes to return
 	roots := make([]*Node[T], 0)
-	for id, parentID := range parentLookup {
+	for _, id := range order {
 		if err := ctx.Err(); err != nil {
 			return nil, err // Context has ended
 		}
+		parentID := parentLookup[id]
 		node := idToNode[id]
 		cfg.HandleExpansion(node)
 
@@ -338,12 +406,36 @@ func buildTreeFromFlatData[T any](ctx context.Context, items []T, provider FlatD
 		// Set up hierarchical relationships
 		parent, ok := idToNode[parentID]
 		if !ok {
-			return nil, errors.Join(ErrTreeConstruction, fmt.Errorf("treeview: parent id %q not found for node %q", parentID, id))
+			// The parent is missing, handle the orphan as configured. Dropping
+			// the orphan drops its descendants with it.
+			if cfg.orphanReport != nil {
+				cfg.orphanReport(*node.Data(), parentID)
+			}
+			switch cfg.orphanPolicy {
+			case OrphanPromote:
+				roots = append(roots, node)
+			case OrphanDrop:
+			default:
+				return nil, orphanedNodeError(id, parentID)
+			}
+			continue
 		}
 		parent.AddChild(node)
 	}
 
-	// Pass 3: Apply depth limiting if configured
+	// Pass 3: Remove the items rejected by the filter
+	if cfg.filterFunc != nil {
+		var excluded int
+		roots, excluded = filterFlatNodes(roots, rejected, cfg.filterPolicy)
+		for _, root := range roots {
+			root.parent = nil
+		}
+		if cfg.filterReport != nil {
+			cfg.filterReport(excluded)
+		}
+	}
+
+	// Pass 4: Apply depth limiting if configured
 	if cfg.maxDepth >= 0 {
 		roots = limitDepth(roots, cfg.maxDepth, 0)
 	}
@@ -356,6 +448,48 @@ func buildTreeFromFlatData[T any](ctx context.Context, items []T, provider FlatD
 	return roots, nil
 }
 
+// filterFlatNodes removes the rejected nodes from nodes and their descendants
+// as policy says, rewiring the remaining nodes in place. It returns the nodes
+// left at this level and the number of nodes removed.
+func filterFlatNodes[T any](nodes []*Node[T], rejected map[*Node[T]]bool, policy FilterPolicy) ([]*Node[T], int) {
+	var kept []*Node[T]
+	excluded := 0
+	for _, node := range nodes {
+		if policy == FilterPrune && rejected[node] {
+			excluded += subtreeSize(node)
+			continue
+		}
+
+		children, n := filterFlatNodes(node.children, rejected, policy)
+		excluded += n
+
+		switch {
+		case !rejected[node]:
+			node.SetChildren(children)
+			kept = append(kept, node)
+		case policy == FilterSplice:
+			excluded++
+			kept = append(kept, children...)
+		case len(children) > 0:
+			// FilterKeepAncestors: the node leads to an accepted descendant
+			node.SetChildren(children)
+			kept = append(kept, node)
+		default:
+			excluded++
+		}
+	}
+	return kept, excluded
+}
+
+// subtreeSize returns the number of nodes in the subtree rooted at node.
+func subtreeSize[T any](node *Node[T]) int {
+	size := 1
+	for _, child := range node.children {
+		size += subtreeSize(child)
+	}
+	return size
+}
+
 // detectCycle checks if adding a parent-child relationship would create a cycle.
 // It traverses the parent chain starting from parentID to see if we would eventually
 // reach childID, which would indicate a cycle.
@@ -384,11 +518,14 @@ func detectCycle(childID, parentID string, parentLookup map[string]string) bool
 //   - WithFilterFunc:   Filters items during tree building
 //   - WithMaxDepth:     Limits tree depth during construction
 //   - WithExpandFunc:   Sets initial expansion state for nodes
+//   - WithSort:         Sorts siblings once the tree is built
 //   - WithTraversalCap: Limits total nodes processed (returns partial tree + error if exceeded)
 //   - WithProgressCallback: Invoked after each filesystem entry is processed (breadth-first per directory)
+//   - WithDeepSizes:    Totals file sizes below directories cut off by WithMaxDepth
 //
 // Options used during a tree's runtime:
 //   - WithSearcher:     Custom search algorithm
+//   - WithContentSearch: Search file contents as well as names
 //   - WithFocusPolicy:  Custom focus navigation logic
 //   - WithProvider:     Custom node rendering provider
 func NewTreeFromFileSystem(
@@ -410,6 +547,7 @@ func NewTreeFromFileSystem(
 
 	// 3. Create the final tree with a specialized filesystem provider.
 	tree := NewTreeFromCfg(nodes, cfg)
+	tree.fsSource = &fileSystemSource{followSymlinks: followSymlinks, cfg: cfg}
 	return tree, nil
 }
 
@@ -460,6 +598,9 @@ func buildFileSystemTree(ctx context.Context, path string, followSymlinks bool,
 func scanDir(ctx context.Context, parent *Node[FileInfo], depth int, followSymlinks bool, cfg *MasterConfig[FileInfo], visited map[string]struct{}, count *int) error {
 	// Enforce depth limit if configured
 	if cfg.HasDepthLimitBeenReached(depth) {
+		if cfg.deepSizes {
+			return setDeepSize(ctx, parent)
+		}
 		return nil
 	}
 
@@ -558,30 +699,11 @@ func filterNodes[T any](nodes []*Node[T], filterFunc FilterFn[T]) []*Node[T] {
 // limitDepth recursively limits the tree depth to maxDepth levels.
 // currentDepth tracks how deep we are in the tree (0 for root level).
 func limitDepth[T any](nodes []*Node[T], maxDepth int, currentDepth int) []*Node[T] {
-	// If we're at the max depth, return nodes without children
-	if currentDepth >= maxDepth {
-		var limited []*Node[T]
-		for _, node := range nodes {
-			// Create a copy without children
-			limited = append(limited, NewNodeClone(node))
-		}
-		return limited
-	}
-
-	// Otherwise, recursively limit depth of children
 	var limited []*Node[T]
 	for _, node := range nodes {
-		// Create a copy of the node
-		nodeCopy := NewNodeClone(node)
-
-		// Recursively limit children at the next depth level
-		children := node.Children()
-		limitedChildren := limitDepth(children, maxDepth, currentDepth+1)
-		nodeCopy.SetChildren(limitedChildren)
-
-		limited = append(limited, nodeCopy)
+		// Copy the node with the levels left below it
+		limited = append(limited, cloneBelow(node, nil, max(maxDepth-currentDepth, 0)))
 	}
-
 	return limited
 }
 
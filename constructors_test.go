@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestNewTree(t *testing.T) {
@@ -629,6 +631,206 @@ func TestNewTreeFromFlatData(t *testing.T) {
 	}
 }
 
+// flatIDs returns the IDs of every node of tree in depth-first order.
+func flatIDs(t *testing.T, tree *Tree[testFlatItem]) []string {
+	t.Helper()
+	var ids []string
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		ids = append(ids, info.Node.ID())
+	}
+	return ids
+}
+
+func TestNewTreeFromFlatData_Order(t *testing.T) {
+	ctx := context.Background()
+	items := []testFlatItem{
+		{id: "r2", name: "b", parentID: ""},
+		{id: "c3", name: "z", parentID: "r1"},
+		{id: "r1", name: "a", parentID: ""},
+		{id: "c1", name: "y", parentID: "r1"},
+		{id: "c2", name: "x", parentID: "r1"},
+		{id: "g1", name: "g", parentID: "c1"},
+	}
+
+	tests := []struct {
+		name string
+		opts []Option[testFlatItem]
+		want []string
+	}{
+		{
+			name: "input_order",
+			want: []string{"r2", "r1", "c3", "c1", "g1", "c2"},
+		},
+		{
+			name: "sorted",
+			opts: []Option[testFlatItem]{WithSort(ByName[testFlatItem]())},
+			want: []string{"r1", "c2", "c1", "g1", "c3", "r2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Repeat the build, map iteration order would differ between runs
+			for range 20 {
+				tree, err := NewTreeFromFlatData(ctx, items, &testFlatProvider{}, test.opts...)
+				if err != nil {
+					t.Fatalf("NewTreeFromFlatData() error = %v", err)
+				}
+				if diff := cmp.Diff(test.want, flatIDs(t, tree)); diff != "" {
+					t.Fatalf("NewTreeFromFlatData() order mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTreeFromFlatData_Orphans(t *testing.T) {
+	ctx := context.Background()
+	items := []testFlatItem{
+		{id: "r1", name: "Root", parentID: ""},
+		{id: "o1", name: "Orphan", parentID: "missing"},
+		{id: "c1", name: "Orphan child", parentID: "o1"},
+		{id: "r2", name: "Root 2", parentID: ""},
+		{id: "c2", name: "Child", parentID: "r1"},
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option[testFlatItem]
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "error_by_default",
+			wantErr: true,
+		},
+		{
+			name:    "error",
+			opts:    []Option[testFlatItem]{WithOrphanPolicy[testFlatItem](OrphanError)},
+			wantErr: true,
+		},
+		{
+			name: "promote",
+			opts: []Option[testFlatItem]{WithOrphanPolicy[testFlatItem](OrphanPromote)},
+			want: []string{"r1", "c2", "o1", "c1", "r2"},
+		},
+		{
+			name: "drop",
+			opts: []Option[testFlatItem]{WithOrphanPolicy[testFlatItem](OrphanDrop)},
+			want: []string{"r1", "c2", "r2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var reported []string
+			opts := append(test.opts, WithOrphanReport(func(item testFlatItem, parentID string) {
+				reported = append(reported, item.id+"->"+parentID)
+			}))
+
+			tree, err := NewTreeFromFlatData(ctx, items, &testFlatProvider{}, opts...)
+			if diff := cmp.Diff([]string{"o1->missing"}, reported); diff != "" {
+				t.Errorf("NewTreeFromFlatData() reported orphans mismatch (-want +got):\n%s", diff)
+			}
+			if test.wantErr {
+				if !errors.Is(err, ErrOrphanedNode) || !errors.Is(err, ErrTreeConstruction) {
+					t.Errorf("NewTreeFromFlatData() error = %v, want ErrOrphanedNode and ErrTreeConstruction", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTreeFromFlatData() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, flatIDs(t, tree)); diff != "" {
+				t.Errorf("NewTreeFromFlatData() nodes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewTreeFromFlatData_Filter(t *testing.T) {
+	ctx := context.Background()
+	// Only items named "keep" pass the filter
+	items := []testFlatItem{
+		{id: "r1", name: "drop", parentID: ""},
+		{id: "a", name: "drop", parentID: "r1"},
+		{id: "a1", name: "keep", parentID: "a"},
+		{id: "a2", name: "drop", parentID: "a"},
+		{id: "b", name: "keep", parentID: "r1"},
+		{id: "r2", name: "drop", parentID: ""},
+		{id: "c", name: "drop", parentID: "r2"},
+		{id: "r3", name: "keep", parentID: ""},
+		{id: "d", name: "drop", parentID: "r3"},
+		{id: "d1", name: "keep", parentID: "d"},
+	}
+	keep := WithFilterFunc(func(item testFlatItem) bool { return item.name == "keep" })
+
+	tests := []struct {
+		name         string
+		policy       []Option[testFlatItem]
+		want         []string
+		wantParents  map[string]string
+		wantExcluded int
+	}{
+		{
+			name:         "keep_ancestors_by_default",
+			want:         []string{"r1", "a", "a1", "b", "r3", "d", "d1"},
+			wantParents:  map[string]string{"a1": "a", "d1": "d", "r1": ""},
+			wantExcluded: 3,
+		},
+		{
+			name:         "prune",
+			policy:       []Option[testFlatItem]{WithFilterPolicy[testFlatItem](FilterPrune)},
+			want:         []string{"r3"},
+			wantParents:  map[string]string{"r3": ""},
+			wantExcluded: 9,
+		},
+		{
+			name:         "splice",
+			policy:       []Option[testFlatItem]{WithFilterPolicy[testFlatItem](FilterSplice)},
+			want:         []string{"a1", "b", "r3", "d1"},
+			wantParents:  map[string]string{"a1": "", "b": "", "d1": "r3"},
+			wantExcluded: 6,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			excluded := -1
+			opts := append([]Option[testFlatItem]{keep, WithFilterReport[testFlatItem](func(n int) {
+				excluded = n
+			})}, test.policy...)
+
+			tree, err := NewTreeFromFlatData(ctx, items, &testFlatProvider{}, opts...)
+			if err != nil {
+				t.Fatalf("NewTreeFromFlatData() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, flatIDs(t, tree)); diff != "" {
+				t.Errorf("NewTreeFromFlatData() nodes mismatch (-want +got):\n%s", diff)
+			}
+			if excluded != test.wantExcluded {
+				t.Errorf("NewTreeFromFlatData() excluded = %d, want %d", excluded, test.wantExcluded)
+			}
+			for id, wantParent := range test.wantParents {
+				node, err := tree.FindByID(ctx, id)
+				if err != nil {
+					t.Fatalf("FindByID(%q) error = %v", id, err)
+				}
+				gotParent := ""
+				if node.Parent() != nil {
+					gotParent = node.Parent().ID()
+				}
+				if gotParent != wantParent {
+					t.Errorf("FindByID(%q).Parent() = %q, want %q", id, gotParent, wantParent)
+				}
+			}
+		})
+	}
+}
+
 func TestNewTreeFromFileSystem(t *testing.T) {
 	ctx := context.Background()
 
@@ -0,0 +1,215 @@
+package treeview
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContentMatchesKey is the FileInfo.Extra key under which a tree stores the
+// []ContentMatch of a file found by the last SearchAndExpand or Filter with
+// ContentMatcher. The key is removed again by the next search, ClearSearch
+// and ClearFilter, so results of earlier searches don't linger.
+const ContentMatchesKey = "contentMatches"
+
+// ContentMatch is a line of a file that contains the search term.
+type ContentMatch struct {
+	Line    int    // 1-based line number
+	Snippet string // The line, trimmed and cut to the snippet width
+}
+
+// Defaults for ContentMatcher.
+const (
+	defaultContentMaxSize    = 1 << 20 // 1 MiB
+	defaultContentMaxMatches = 100
+	defaultContentSnippet    = 120
+	binarySniffLen           = 8000 // Same heuristic as git: a NUL byte in the first 8000 bytes
+)
+
+// contentConfig holds the ContentMatcher settings.
+type contentConfig struct {
+	maxSize    int64
+	maxMatches int
+	snippet    int
+}
+
+// ContentOption configures ContentMatcher.
+type ContentOption func(*contentConfig)
+
+// WithContentMaxSize skips files larger than n bytes. Defaults to 1 MiB;
+// n ≤ 0 removes the limit.
+func WithContentMaxSize(n int64) ContentOption {
+	return func(c *contentConfig) { c.maxSize = n }
+}
+
+// WithContentMaxMatches stops recording lines after n matches per file. The
+// file still matches. Defaults to 100; n ≤ 0 records every line.
+func WithContentMaxMatches(n int) ContentOption {
+	return func(c *contentConfig) { c.maxMatches = n }
+}
+
+// WithContentSnippetWidth cuts snippets to n runes. Defaults to 120; n ≤ 0
+// keeps whole lines.
+func WithContentSnippetWidth(n int) ContentOption {
+	return func(c *contentConfig) { c.snippet = n }
+}
+
+// ContentMatcher returns a MatchFn for file system trees that matches the
+// term, ignoring case, against a node's name and the contents of the file at
+// FileInfo.Path. Directories, binary files and files above the size limit are
+// only matched by name. Name matches score higher and are highlighted;
+// matching lines are returned in Match.Lines. The matcher itself doesn't
+// change node payloads, since it may run on copies without the tree lock;
+// SearchAndExpand and Filter store the lines of the matching nodes in
+// FileInfo.Extra under ContentMatchesKey once the search is done, where a
+// detail panel reads them with ContentMatches.
+//
+// Reading stops when ctx is done. Combine it with WithSearchWorkers to read
+// several files at once, or use WithContentSearch which does both.
+func ContentMatcher(opts ...ContentOption) MatchFn[FileInfo] {
+	cfg := contentConfig{
+		maxSize:    defaultContentMaxSize,
+		maxMatches: defaultContentMaxMatches,
+		snippet:    defaultContentSnippet,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(ctx context.Context, node *Node[FileInfo], term string) (Match, bool) {
+		if term == "" {
+			return Match{}, false
+		}
+		lines := cfg.grep(ctx, node.Data(), strings.ToLower(term))
+		if ranges := substringRanges(node.Name(), term); ranges != nil {
+			return Match{Score: 1, Ranges: ranges, Lines: lines}, true
+		}
+		return Match{Lines: lines}, len(lines) > 0
+	}
+}
+
+// WithContentSearch configures a file system tree to search file contents
+// with ContentMatcher, reading files on workers goroutines.
+func WithContentSearch(workers int, opts ...ContentOption) Option[FileInfo] {
+	return func(cfg *MasterConfig[FileInfo]) {
+		WithMatcher(ContentMatcher(opts...))(cfg)
+		WithSearchWorkers[FileInfo](workers)(cfg)
+	}
+}
+
+// ContentMatches returns the matching lines stored for node by the last
+// content search, or nil.
+func ContentMatches(node *Node[FileInfo]) []ContentMatch {
+	lines, _ := node.Data().Extra[ContentMatchesKey].([]ContentMatch)
+	return lines
+}
+
+// setMatches replaces the match details recorded for highlighting and
+// MatchFor. The matching lines of nodes with FileInfo payloads are stored in
+// their Extra under ContentMatchesKey and removed from the nodes of the
+// previous search. Callers must hold t.mu.
+func (t *Tree[T]) setMatches(matches map[string]Match, nodes []*Node[T]) {
+	t.matches = matches
+	var changed []string
+	for _, node := range t.contentNodes {
+		if setContentMatches(node, nil) {
+			t.touch(node)
+			changed = append(changed, node.ID())
+		}
+	}
+	t.contentNodes = nil
+	for _, node := range nodes {
+		if setContentMatches(node, matches[node.ID()].Lines) {
+			t.touch(node)
+			t.contentNodes = append(t.contentNodes, node)
+			changed = append(changed, node.ID())
+		}
+	}
+	if len(changed) > 0 {
+		t.emit(EventData, changed)
+	}
+}
+
+// setContentMatches stores lines in the FileInfo.Extra of node, or removes
+// the key when there are none. The map is replaced rather than changed in
+// place, as copies of the payload may share it. Reports whether node has a
+// FileInfo payload whose Extra changed.
+func setContentMatches[T any](node *Node[T], lines []ContentMatch) bool {
+	info, ok := any(&node.data).(*FileInfo)
+	if !ok {
+		return false
+	}
+	if _, had := info.Extra[ContentMatchesKey]; !had && len(lines) == 0 {
+		return false
+	}
+	extra := maps.Clone(info.Extra)
+	if len(lines) == 0 {
+		delete(extra, ContentMatchesKey)
+	} else {
+		if extra == nil {
+			extra = make(map[string]any)
+		}
+		extra[ContentMatchesKey] = lines
+	}
+	info.Extra = extra
+	return true
+}
+
+// grep returns the lines of the file described by info containing the
+// lower-cased term. Unreadable, binary and oversized files have no lines.
+func (c contentConfig) grep(ctx context.Context, info *FileInfo, term string) []ContentMatch {
+	if info.FileInfo == nil || !info.Mode().IsRegular() || info.Path == "" {
+		return nil
+	}
+	if c.maxSize > 0 && info.Size() > c.maxSize || ctx.Err() != nil {
+		return nil
+	}
+
+	f, err := os.Open(info.Path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, binarySniffLen)
+	head, err := r.Peek(binarySniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	var lines []ContentMatch
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if n%1024 == 0 && ctx.Err() != nil {
+			return nil
+		}
+		line := scanner.Text()
+		if !strings.Contains(strings.ToLower(line), term) {
+			continue
+		}
+		lines = append(lines, ContentMatch{Line: n, Snippet: c.snip(line)})
+		if c.maxMatches > 0 && len(lines) >= c.maxMatches {
+			break
+		}
+	}
+	// A line longer than the scanner buffer ends the scan; keep what we have
+	return lines
+}
+
+// snip trims line and cuts it to the snippet width.
+func (c contentConfig) snip(line string) string {
+	line = strings.TrimSpace(line)
+	if c.snippet > 0 && utf8.RuneCountInString(line) > c.snippet {
+		line = string([]rune(line)[:c.snippet]) + "…"
+	}
+	return line
+}
@@ -0,0 +1,247 @@
+package treeview
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// createContentTree writes files into a temporary directory and builds a file
+// system tree over it.
+func createContentTree(t *testing.T, files map[string]string, opts ...Option[FileInfo]) *Tree[FileInfo] {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree, err := NewTreeFromFileSystem(context.Background(), dir, false, opts...)
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	return tree
+}
+
+func TestContentMatcher(t *testing.T) {
+	files := map[string]string{
+		"main.go":        "package main\n\nfunc main() {\n\t// TODO: parse flags\n}\n",
+		"lib/util.go":    "package lib\n// todo later\n",
+		"notes.txt":      "nothing here\n",
+		"todo.md":        "empty\n",
+		"image.bin":      "TODO\x00\x01\x02",
+		"big.txt":        "TODO " + strings.Repeat("x", 200) + "\n",
+		"lib/nomatch.go": "package lib\n",
+	}
+	tree := createContentTree(t, files, WithContentSearch(4, WithContentMaxSize(100)))
+
+	results, err := tree.SearchMatches(context.Background(), "todo")
+	if err != nil {
+		t.Fatalf("SearchMatches() error = %v", err)
+	}
+
+	got := make(map[string][]ContentMatch)
+	var order []string
+	for _, result := range results {
+		order = append(order, result.Node.Name())
+		got[result.Node.Name()] = result.Match.Lines
+	}
+
+	// The name match scores higher, the rest keep tree order
+	if diff := cmp.Diff([]string{"todo.md", "util.go", "main.go"}, order); diff != "" {
+		t.Errorf("result order mismatch (-want +got):\n%s", diff)
+	}
+	want := map[string][]ContentMatch{
+		"todo.md": nil,
+		"util.go": {{Line: 2, Snippet: "// todo later"}},
+		"main.go": {{Line: 4, Snippet: "// TODO: parse flags"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("content matches mismatch (-want +got):\n%s", diff)
+	}
+
+	// The matcher leaves the payloads alone; only recorded searches store lines
+	for _, result := range results {
+		if extra := result.Node.Data().Extra; len(extra) > 0 {
+			t.Errorf("%s: FileInfo.Extra = %v after searching, want it untouched", result.Node.Name(), extra)
+		}
+	}
+}
+
+func TestContentMatcher_MatchFor(t *testing.T) {
+	files := map[string]string{
+		"main.go":   "package main\n\n// TODO: parse flags\n",
+		"notes.txt": "nothing here\n",
+	}
+	tree := createContentTree(t, files, WithContentSearch(2))
+	model := NewTuiTreeModel(tree)
+	if _, err := model.Search("todo"); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	ids := make(map[string]string)
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		ids[info.Node.Name()] = info.Node.ID()
+	}
+
+	m, ok := model.MatchFor(ids["main.go"])
+	if !ok {
+		t.Fatalf("MatchFor(%q) = false, want true", ids["main.go"])
+	}
+	want := []ContentMatch{{Line: 3, Snippet: "// TODO: parse flags"}}
+	if diff := cmp.Diff(want, m.Lines); diff != "" {
+		t.Errorf("MatchFor().Lines mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, ok := model.MatchFor(ids["notes.txt"]); ok {
+		t.Errorf("MatchFor(%q) = true for a node that didn't match", ids["notes.txt"])
+	}
+
+	_, _ = model.Search("")
+	if _, ok := model.MatchFor(ids["main.go"]); ok {
+		t.Errorf("MatchFor(%q) = true after clearing the search", ids["main.go"])
+	}
+}
+
+func TestContentMatcher_Extra(t *testing.T) {
+	ctx := context.Background()
+	files := map[string]string{
+		"main.go":   "package main\n\n// TODO: parse flags\n",
+		"notes.txt": "nothing here\n",
+	}
+	tree := createContentTree(t, files, WithContentSearch(2))
+	nodes := make(map[string]*Node[FileInfo])
+	for info, err := range tree.All(ctx) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		nodes[info.Node.Name()] = info.Node
+	}
+	want := []ContentMatch{{Line: 3, Snippet: "// TODO: parse flags"}}
+
+	steps := []struct {
+		name   string
+		search func() error
+		want   map[string][]ContentMatch
+	}{
+		{
+			name:   "search",
+			search: func() error { _, err := tree.SearchAndExpand(ctx, "todo"); return err },
+			want:   map[string][]ContentMatch{"main.go": want},
+		},
+		{
+			name:   "next_search",
+			search: func() error { _, err := tree.SearchAndExpand(ctx, "nothing"); return err },
+			want:   map[string][]ContentMatch{"notes.txt": {{Line: 1, Snippet: "nothing here"}}},
+		},
+		{
+			name:   "clear_search",
+			search: func() error { tree.ClearSearch(); return nil },
+			want:   map[string][]ContentMatch{},
+		},
+		{
+			name:   "filter",
+			search: func() error { _, err := tree.Filter(ctx, "todo"); return err },
+			want:   map[string][]ContentMatch{"main.go": want},
+		},
+		{
+			name:   "clear_filter",
+			search: func() error { tree.ClearFilter(); return nil },
+			want:   map[string][]ContentMatch{},
+		},
+	}
+
+	for _, step := range steps {
+		if err := step.search(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		got := make(map[string][]ContentMatch)
+		for name, node := range nodes {
+			if lines := ContentMatches(node); lines != nil {
+				got[name] = lines
+			}
+			if _, ok := node.Data().Extra[ContentMatchesKey]; ok && step.want[name] == nil {
+				t.Errorf("%s: %s still has %q in FileInfo.Extra", step.name, name, ContentMatchesKey)
+			}
+		}
+		if diff := cmp.Diff(step.want, got); diff != "" {
+			t.Errorf("%s: ContentMatches() mismatch (-want +got):\n%s", step.name, diff)
+		}
+	}
+}
+
+func TestContentMatcher_Options(t *testing.T) {
+	tree := createContentTree(t, map[string]string{
+		"a.txt": "match one\nmatch two has a long tail\nmatch three\n",
+	}, WithMatcher(ContentMatcher(WithContentMaxMatches(2), WithContentSnippetWidth(9))))
+
+	results, err := tree.SearchMatches(context.Background(), "MATCH")
+	if err != nil {
+		t.Fatalf("SearchMatches() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("SearchMatches() returned %d results, want 1", len(results))
+	}
+	want := []ContentMatch{{Line: 1, Snippet: "match one"}, {Line: 2, Snippet: "match two…"}}
+	if diff := cmp.Diff(want, results[0].Match.Lines); diff != "" {
+		t.Errorf("Match.Lines mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchWorkers_Cancelled(t *testing.T) {
+	var nodes []*Node[string]
+	for i := range 50 {
+		name := string(rune('a' + i%26))
+		nodes = append(nodes, NewNode(name+string(rune('0'+i/26)), name, ""))
+	}
+	slow := func(ctx context.Context, node *Node[string], term string) (Match, bool) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Millisecond):
+		}
+		return Match{}, true
+	}
+	tree := NewTree(nodes, WithMatcher(slow), WithSearchWorkers[string](2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, err := tree.SearchMatches(ctx, "x")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("SearchMatches() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(results) == len(nodes) {
+		t.Errorf("SearchMatches() evaluated all %d nodes despite the deadline", len(nodes))
+	}
+}
+
+func TestSearchWorkers_SameResults(t *testing.T) {
+	sequential := createSearchModel().Tree
+	concurrent := createSearchModel().Tree
+	concurrent.searchWorkers = 3
+
+	for _, term := range []string{"go", "main", "zzz"} {
+		want, err := sequential.SearchMatches(context.Background(), term)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := concurrent.SearchMatches(context.Background(), term)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(nodeIDs(resultNodes(want)), nodeIDs(resultNodes(got))); diff != "" {
+			t.Errorf("term %q: results mismatch (-want +got):\n%s", term, diff)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DeleteFn deletes whatever a node stands for, such as a file. It runs in a
+// Bubble Tea command, off the event loop; see WithTuiDelete.
+type DeleteFn[T any] func(ctx context.Context, node *Node[T]) error
+
+// WithTuiDelete enables KeyMap.Delete: after the user confirms, fn is called
+// for every checked node (see WithCheckboxes), or for the focused nodes if
+// none is checked, and the deleted nodes are removed from the tree. Use
+// DeleteFiles for file system trees. Without it the binding does nothing.
+func WithTuiDelete[T any](fn DeleteFn[T]) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) { m.deleteFn = fn }
+}
+
+// DeleteFiles is a DeleteFn that removes a file or directory with everything
+// in it from disk.
+func DeleteFiles(_ context.Context, node *Node[FileInfo]) error {
+	return os.RemoveAll(node.Data().Path)
+}
+
+// nodesDeletedMsg carries the outcome of a delete command back to Update.
+type nodesDeletedMsg[T any] struct {
+	deleted   []*Node[T]
+	selection bool // The nodes were the checked ones
+	err       error
+}
+
+// BeginDelete asks the user to confirm deleting the checked nodes, or the
+// focused nodes if none is checked. Nodes below another deleted node are
+// left out since they go with it. Does nothing without WithTuiDelete.
+func (m *TuiTreeModel[T]) BeginDelete() {
+	if m.deleteFn == nil {
+		return
+	}
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		var nodes []*Node[T]
+		fromSelection := true
+		for info, err := range m.AllSelected(ctx) {
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, info.Node)
+		}
+		if len(nodes) == 0 {
+			fromSelection = false
+			for info, err := range m.AllFocused(ctx) {
+				if err != nil {
+					return err
+				}
+				nodes = append(nodes, info.Node)
+			}
+		}
+		m.confirmDelete = topmostNodes(nodes)
+		m.deleteSelection = fromSelection
+		return nil
+	})
+	m.updateViewportDimensions()
+}
+
+// CancelDelete dismisses the delete confirmation, or stops a confirmed
+// delete that is still running: the context passed to the DeleteFn is
+// cancelled and no further nodes are deleted. The nodes deleted so far are
+// still reported.
+func (m *TuiTreeModel[T]) CancelDelete() {
+	m.closeDeletePrompt()
+	m.stopDelete()
+}
+
+// closeDeletePrompt dismisses the delete confirmation.
+func (m *TuiTreeModel[T]) closeDeletePrompt() {
+	m.confirmDelete = nil
+	m.updateViewportDimensions()
+}
+
+// stopDelete cancels the running delete, if any.
+func (m *TuiTreeModel[T]) stopDelete() {
+	if m.deleteCancel != nil {
+		m.deleteCancel()
+		m.deleteCancel = nil
+	}
+}
+
+// ConfirmDelete closes the confirmation and returns the command that deletes
+// the nodes. Update then removes them from the tree and emits a
+// NodesDeletedMsg. The delete can be stopped with CancelDelete until then.
+func (m *TuiTreeModel[T]) ConfirmDelete() tea.Cmd {
+	nodes, selection, fn := m.confirmDelete, m.deleteSelection, m.deleteFn
+	m.closeDeletePrompt()
+	if len(nodes) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stopDelete()
+	m.deleteCancel = cancel
+	return func() tea.Msg {
+		msg := nodesDeletedMsg[T]{selection: selection}
+		for _, node := range nodes {
+			if err := ctx.Err(); err != nil {
+				msg.err = err
+				break
+			}
+			if err := fn(ctx, node); err != nil {
+				msg.err = err
+				break
+			}
+			msg.deleted = append(msg.deleted, node)
+		}
+		return msg
+	}
+}
+
+// Deleting reports whether a delete confirmation is open.
+func (m *TuiTreeModel[T]) Deleting() bool {
+	return len(m.confirmDelete) > 0
+}
+
+// DeleteRunning reports whether a confirmed delete is still running. The
+// KeyMap.DeleteCancel keys stop it.
+func (m *TuiTreeModel[T]) DeleteRunning() bool {
+	return m.deleteCancel != nil
+}
+
+// handleDeletedMsg removes the deleted nodes from the tree.
+func (m *TuiTreeModel[T]) handleDeletedMsg(msg nodesDeletedMsg[T]) tea.Cmd {
+	m.stopDelete()
+	m.removeDeleted(msg.deleted)
+	m.updateViewportDimensions() // Removing the hoisted node may drop the hoist
+	if msg.selection {
+		m.ClearSelection()
+	}
+	if len(m.GetAllFocusedIDs()) == 0 {
+		m.execWithNavigationTimeout(func(ctx context.Context) error {
+			if hoisted := m.HoistedNode(); hoisted != nil {
+				_, err := m.SetFocusedID(ctx, hoisted.ID())
+				return err
+			}
+			if roots := m.Nodes(); len(roots) > 0 {
+				_, err := m.SetFocusedID(ctx, roots[0].ID())
+				return err
+			}
+			return nil
+		})
+	}
+	return msgCmd(NodesDeletedMsg{IDs: nodeIDs(msg.deleted), Err: msg.err})
+}
+
+// removeDeleted removes nodes whose data was deleted, such as files removed
+// from disk. Deleting can't be undone, so instead of recording a step the
+// undo steps involving the nodes, their descendants or their parents are
+// dropped, as replaying them would bring the nodes back; the rest of the
+// history is kept. Nodes already gone from the tree are skipped.
+func (t *Tree[T]) removeDeleted(nodes []*Node[T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changed := make(map[*Node[T]]bool)
+	for _, node := range nodes {
+		if !t.contains(node) {
+			continue
+		}
+		if parent := node.Parent(); parent != nil {
+			changed[parent] = true
+		}
+		for info := range dfsSeq(context.Background(), []*Node[T]{node}, true, nil) {
+			changed[info.Node] = true
+		}
+		t.removeNode(node)
+	}
+	t.history.forget(changed)
+}
+
+// deletePrompt is the confirmation line shown above the tree.
+func (m *TuiTreeModel[T]) deletePrompt() string {
+	what := fmt.Sprintf("%d items", len(m.confirmDelete))
+	if len(m.confirmDelete) == 1 {
+		what = fmt.Sprintf("%q", m.confirmDelete[0].Name())
+	}
+	return fmt.Sprintf("Delete %s? %s", what, m.addNavItem(m.keyMap.DeleteConfirm, "yes"))
+}
+
+// topmostNodes drops the nodes that have an ancestor in nodes.
+func topmostNodes[T any](nodes []*Node[T]) []*Node[T] {
+	set := make(map[*Node[T]]bool, len(nodes))
+	for _, node := range nodes {
+		set[node] = true
+	}
+	var top []*Node[T]
+	for _, node := range nodes {
+		covered := false
+		for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+			if set[ancestor] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			top = append(top, node)
+		}
+	}
+	return top
+}
@@ -0,0 +1,301 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-cmp/cmp"
+)
+
+// createDeleteModel returns a model over "a" (children "a1", "a2"), "b" and
+// "c" whose delete function records the deleted IDs and fails for failID.
+func createDeleteModel(deleted *[]string, failID string) *TuiTreeModel[string] {
+	a := NewNode("a", "a", "")
+	a.SetChildren([]*Node[string]{NewNode("a1", "a1", ""), NewNode("a2", "a2", "")})
+	a.Expand()
+	tree := NewTree([]*Node[string]{a, NewNode("b", "b", ""), NewNode("c", "c", "")})
+	return NewTuiTreeModel(tree, WithTuiDelete(func(_ context.Context, node *Node[string]) error {
+		if node.ID() == failID {
+			return errors.New("permission denied")
+		}
+		*deleted = append(*deleted, node.ID())
+		return nil
+	}))
+}
+
+// pressKey sends a single key to model and returns the messages it produced,
+// feeding internal messages back into the model.
+func pressKey(model *TuiTreeModel[string], key string) []tea.Msg {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	if key == "esc" {
+		msg = tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	_, cmd := model.Update(msg)
+	var out []tea.Msg
+	for _, m := range collectMsgs(cmd) {
+		if deleted, ok := m.(nodesDeletedMsg[string]); ok {
+			_, cmd := model.Update(deleted)
+			out = append(out, collectMsgs(cmd)...)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestTuiDelete(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		setup       func(*TuiTreeModel[string])
+		keys        []string
+		failID      string
+		wantDeleted []string
+		wantIDs     []string
+		wantErr     bool
+	}{
+		{
+			name:        "focused",
+			setup:       func(m *TuiTreeModel[string]) { _, _ = m.SetFocusedID(ctx, "b") },
+			keys:        []string{"d", "y"},
+			wantDeleted: []string{"b"},
+			wantIDs:     []string{"a", "a1", "a2", "c"},
+		},
+		{
+			name: "checked_topmost_only",
+			setup: func(m *TuiTreeModel[string]) {
+				_ = m.SetSelected(ctx, "a", true)
+				_ = m.SetSelected(ctx, "c", true)
+			},
+			keys:        []string{"d", "y"},
+			wantDeleted: []string{"a", "c"},
+			wantIDs:     []string{"b"},
+		},
+		{
+			name:    "cancelled",
+			setup:   func(m *TuiTreeModel[string]) { _, _ = m.SetFocusedID(ctx, "b") },
+			keys:    []string{"d", "n"},
+			wantIDs: []string{"a", "a1", "a2", "b", "c"},
+		},
+		{
+			name:    "other_keys_ignored_while_confirming",
+			setup:   func(m *TuiTreeModel[string]) { _, _ = m.SetFocusedID(ctx, "b") },
+			keys:    []string{"d", "s", "esc"},
+			wantIDs: []string{"a", "a1", "a2", "b", "c"},
+		},
+		{
+			name: "stops_at_failure",
+			setup: func(m *TuiTreeModel[string]) {
+				_ = m.SetSelected(ctx, "a1", true)
+				_ = m.SetSelected(ctx, "b", true)
+				_ = m.SetSelected(ctx, "c", true)
+			},
+			keys:        []string{"d", "y"},
+			failID:      "b",
+			wantDeleted: []string{"a1"},
+			wantIDs:     []string{"a", "a2", "b", "c"},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var deleted []string
+			model := createDeleteModel(&deleted, test.failID)
+			test.setup(model)
+
+			var msgs []tea.Msg
+			for _, key := range test.keys {
+				msgs = append(msgs, pressKey(model, key)...)
+			}
+
+			if model.Deleting() {
+				t.Errorf("Deleting() = true after %v, want false", test.keys)
+			}
+			if diff := cmp.Diff(test.wantDeleted, deleted); diff != "" {
+				t.Errorf("deleted mismatch (-want +got):\n%s", diff)
+			}
+			var ids []string
+			for info, err := range model.All(ctx) {
+				if err != nil {
+					t.Fatalf("All() error = %v", err)
+				}
+				ids = append(ids, info.Node.ID())
+			}
+			if diff := cmp.Diff(test.wantIDs, ids); diff != "" {
+				t.Errorf("tree after delete mismatch (-want +got):\n%s", diff)
+			}
+
+			var got *NodesDeletedMsg
+			for _, msg := range msgs {
+				if m, ok := msg.(NodesDeletedMsg); ok {
+					got = &m
+				}
+			}
+			if test.wantDeleted == nil && !test.wantErr {
+				if got != nil {
+					t.Errorf("NodesDeletedMsg = %+v, want none", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("NodesDeletedMsg missing")
+			}
+			if diff := cmp.Diff(test.wantDeleted, got.IDs); diff != "" {
+				t.Errorf("NodesDeletedMsg.IDs mismatch (-want +got):\n%s", diff)
+			}
+			if (got.Err != nil) != test.wantErr {
+				t.Errorf("NodesDeletedMsg.Err = %v, wantErr %v", got.Err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestTuiDelete_ChangeMessages(t *testing.T) {
+	ctx := context.Background()
+	var deleted []string
+	model := createDeleteModel(&deleted, "")
+	_ = model.SetSelected(ctx, "a", true)
+	_ = model.SetSelected(ctx, "c", true)
+
+	var got []tea.Msg
+	for _, key := range []string{"d", "y"} {
+		for _, msg := range pressKey(model, key) {
+			switch msg.(type) {
+			case FocusChangedMsg, SelectionChangedMsg:
+				got = append(got, msg)
+			}
+		}
+	}
+
+	// The focused a is deleted and the checked nodes are unchecked
+	want := []tea.Msg{
+		FocusChangedMsg{ID: "b", PreviousID: "a"},
+		SelectionChangedMsg{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("messages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTuiDelete_History(t *testing.T) {
+	ctx := context.Background()
+	var deleted []string
+	model := createDeleteModel(&deleted, "")
+	if err := model.Rename(ctx, "c", "c2"); err != nil {
+		t.Fatalf("Rename(c) error = %v", err)
+	}
+	if err := model.Rename(ctx, "a1", "a1x"); err != nil {
+		t.Fatalf("Rename(a1) error = %v", err)
+	}
+	_, _ = model.SetFocusedID(ctx, "a")
+	pressKey(model, "d")
+	pressKey(model, "y")
+
+	// The rename inside the deleted subtree is dropped, the other one stays
+	if ok, err := model.Undo(ctx); !ok || err != nil {
+		t.Fatalf("Undo() = %v, %v, want true, nil", ok, err)
+	}
+	if c, _ := model.FindByID(ctx, "c"); c.Name() != "c" {
+		t.Errorf("c name after Undo() = %q, want %q", c.Name(), "c")
+	}
+	if model.CanUndo() {
+		t.Error("CanUndo() = true, want the steps involving the deleted nodes dropped")
+	}
+	if _, err := model.FindByID(ctx, "a1"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("FindByID(a1) error = %v, want the deleted node to stay gone", err)
+	}
+}
+
+func TestTuiDelete_Cancel(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	a := NewNode("a", "a", "")
+	tree := NewTree([]*Node[string]{a, NewNode("b", "b", "")})
+	model := NewTuiTreeModel(tree, WithTuiDelete(func(ctx context.Context, node *Node[string]) error {
+		close(started)
+		<-ctx.Done() // A slow delete that honours cancellation
+		return ctx.Err()
+	}))
+	_ = model.SetSelected(ctx, "a", true)
+	_ = model.SetSelected(ctx, "b", true)
+
+	pressKey(model, "d")
+	run := model.ConfirmDelete()
+	result := make(chan tea.Msg, 1)
+	go func() { result <- run() }()
+	<-started
+
+	if !model.DeleteRunning() {
+		t.Fatal("DeleteRunning() = false while the delete runs")
+	}
+	pressKey(model, "n")
+	msg, ok := (<-result).(nodesDeletedMsg[string])
+	if !ok {
+		t.Fatalf("delete command returned %T, want nodesDeletedMsg", msg)
+	}
+	if !errors.Is(msg.err, context.Canceled) || len(msg.deleted) != 0 {
+		t.Errorf("delete result = %v deleted, err %v, want none deleted and context.Canceled", len(msg.deleted), msg.err)
+	}
+	model.Update(msg)
+	if model.DeleteRunning() {
+		t.Error("DeleteRunning() = true after the delete finished")
+	}
+	if got := len(model.Nodes()); got != 2 {
+		t.Errorf("len(Nodes()) = %d after a cancelled delete, want 2", got)
+	}
+}
+
+func TestTuiDelete_Prompt(t *testing.T) {
+	var deleted []string
+	model := createDeleteModel(&deleted, "")
+	_, _ = model.SetFocusedID(context.Background(), "b")
+
+	pressKey(model, "d")
+	if !model.Deleting() {
+		t.Fatalf("Deleting() = false after d, want true")
+	}
+	view := model.View()
+	if !strings.HasPrefix(view, `Delete "b"? y: yes`) {
+		t.Errorf("View() = %q, want the confirmation prompt first", view)
+	}
+	if nav := model.NavBar(); nav != "y: Delete  n/esc: Cancel" {
+		t.Errorf("NavBar() = %q, want the confirmation keys", nav)
+	}
+}
+
+func TestTuiDelete_Disabled(t *testing.T) {
+	model := createSearchModel()
+	pressKey(model, "d")
+	if model.Deleting() {
+		t.Errorf("Deleting() = true without WithTuiDelete, want false")
+	}
+}
+
+func TestDeleteFiles(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.MkdirAll(filepath.Join(sub, "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "deep", "f.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteFiles(context.Background(), NewFileSystemNode(sub, info)); err != nil {
+		t.Fatalf("DeleteFiles() error = %v", err)
+	}
+	if _, err := os.Stat(sub); !os.IsNotExist(err) {
+		t.Errorf("Stat(sub) after DeleteFiles() error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,329 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DiffKind classifies a node in the result of Diff.
+type DiffKind int
+
+const (
+	// DiffUnchanged means the node has the same parent, name and data in both
+	// trees.
+	DiffUnchanged DiffKind = iota
+	// DiffAdded means the node only exists in the new tree.
+	DiffAdded
+	// DiffRemoved means the node only exists in the old tree.
+	DiffRemoved
+	// DiffModified means the node's name or data changed.
+	DiffModified
+	// DiffMoved means the node has a different parent. Its name or data may
+	// have changed as well; see DiffEntry.Modified.
+	DiffMoved
+)
+
+// String returns the lower-case name of the kind.
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffModified:
+		return "modified"
+	case DiffMoved:
+		return "moved"
+	default:
+		return "unchanged"
+	}
+}
+
+// DiffEntry is the payload of a node in the tree returned by Diff. Old and
+// New are the matching nodes of the compared trees; Old is nil for added
+// nodes and New is nil for removed ones.
+type DiffEntry[T any] struct {
+	Kind     DiffKind
+	Old, New *Node[T]
+	Modified bool // Name or data changed, also set for moved nodes
+	Changes  bool // The node or something below it changed
+}
+
+// Diff compares two trees, matching nodes by ID, and returns a merged tree
+// holding every node of both: the structure of b with removed nodes of a
+// placed where they used to be. Moved nodes appear at their new position.
+// eq reports whether two payloads are equal; a nil eq uses
+// reflect.DeepEqual. Subtrees without changes start collapsed, everything
+// else expanded, and the tree renders with NewDiffProvider. Both trees are
+// read-locked for the whole comparison, so eq must not call their methods.
+// Returns context errors unwrapped.
+func Diff[T any](ctx context.Context, a, b *Tree[T], eq func(T, T) bool) (*Tree[DiffEntry[T]], error) {
+	defer readLockPair(a, b)()
+	return diffTrees(ctx, a.nodes, b.nodes, eq, (*Node[T]).ID)
+}
+
+// readLockPair read-locks a and b in address order and returns the function
+// that unlocks them. A fixed order keeps Diff(a, b) and Diff(b, a) from
+// deadlocking with writers waiting on both trees; the same tree is locked
+// once.
+func readLockPair[T any](a, b *Tree[T]) func() {
+	if a == b {
+		a.mu.RLock()
+		return a.mu.RUnlock
+	}
+	if reflect.ValueOf(a).Pointer() > reflect.ValueOf(b).Pointer() {
+		a, b = b, a
+	}
+	a.mu.RLock()
+	b.mu.RLock()
+	return func() {
+		b.mu.RUnlock()
+		a.mu.RUnlock()
+	}
+}
+
+// DiffFileSystem compares the directories at oldPath and newPath, matching
+// entries by their path relative to the directory, and returns the merged
+// tree as Diff does. Entries count as modified when their type, size or
+// modification time differ; contents are not read. opts are applied to both
+// scans, so filters and depth limits work as with NewTreeFromFileSystem.
+// Node IDs of the result are relative paths, "." for the root. Returns
+// context errors unwrapped, ErrFileSystem for filesystem errors, or
+// ErrTreeConstruction if the scans don't have the same number of roots.
+func DiffFileSystem(ctx context.Context, oldPath, newPath string, opts ...Option[FileInfo]) (*Tree[DiffEntry[FileInfo]], error) {
+	a, err := NewTreeFromFileSystem(ctx, oldPath, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewTreeFromFileSystem(ctx, newPath, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return diffFileSystemTrees(ctx, a, b)
+}
+
+// diffFileSystemTrees is DiffFileSystem for the scanned trees, whose roots
+// are paired by position.
+func diffFileSystemTrees(ctx context.Context, a, b *Tree[FileInfo]) (*Tree[DiffEntry[FileInfo]], error) {
+	if len(a.Nodes()) != len(b.Nodes()) {
+		return nil, fmt.Errorf("%w: comparing %d roots with %d", ErrTreeConstruction, len(a.Nodes()), len(b.Nodes()))
+	}
+
+	// The roots are the same directory under different names
+	for i, root := range a.Nodes() {
+		root.SetName(b.Nodes()[i].Name())
+	}
+
+	// Both trees are keyed by the same function, so look the keys up by node
+	keys := make(map[*Node[FileInfo]]string)
+	for _, tree := range []*Tree[FileInfo]{a, b} {
+		for _, root := range tree.Nodes() {
+			for info, err := range dfsSeq(ctx, []*Node[FileInfo]{root}, true, nil) {
+				if err != nil {
+					return nil, err
+				}
+				rel, err := filepath.Rel(root.Data().Path, info.Node.Data().Path)
+				if err != nil {
+					rel = info.Node.Data().Path
+				}
+				keys[info.Node] = filepath.ToSlash(rel)
+			}
+		}
+	}
+	key := func(n *Node[FileInfo]) string { return keys[n] }
+	return diffTrees(ctx, a.Nodes(), b.Nodes(), equalFileInfo, key)
+}
+
+// equalFileInfo reports whether two file system entries look unchanged.
+func equalFileInfo(a, b FileInfo) bool {
+	if a.FileInfo == nil || b.FileInfo == nil {
+		return a.FileInfo == nil && b.FileInfo == nil
+	}
+	if a.IsDir() != b.IsDir() {
+		return false
+	}
+	if a.IsDir() {
+		return true // Directory changes show up as changes of their entries
+	}
+	return a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// diffTrees implements Diff with nodes matched by key. Callers must hold the
+// locks of the trees the roots belong to, or own the nodes.
+func diffTrees[T any](ctx context.Context, aRoots, bRoots []*Node[T], eq func(T, T) bool, key func(*Node[T]) string) (*Tree[DiffEntry[T]], error) {
+	if eq == nil {
+		eq = func(x, y T) bool { return reflect.DeepEqual(x, y) }
+	}
+	parentKey := func(n *Node[T]) string {
+		if p := n.Parent(); p != nil {
+			return key(p)
+		}
+		return ""
+	}
+
+	oldNodes := make(map[string]*Node[T])
+	for info, err := range dfsSeq(ctx, aRoots, true, nil) {
+		if err != nil {
+			return nil, err
+		}
+		oldNodes[key(info.Node)] = info.Node
+	}
+	newNodes := make(map[string]*Node[T])
+	for info, err := range dfsSeq(ctx, bRoots, true, nil) {
+		if err != nil {
+			return nil, err
+		}
+		newNodes[key(info.Node)] = info.Node
+	}
+
+	// merge builds the merged nodes for the new siblings and the old siblings
+	// at the same place, keeping removed nodes next to their old neighbours
+	var merge func(olds, news []*Node[T]) ([]*Node[DiffEntry[T]], error)
+	merge = func(olds, news []*Node[T]) ([]*Node[DiffEntry[T]], error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var out []*Node[DiffEntry[T]]
+		for _, nb := range news {
+			k := key(nb)
+			entry := DiffEntry[T]{Kind: DiffAdded, New: nb}
+			if na, ok := oldNodes[k]; ok {
+				entry.Old = na
+				entry.Modified = na.Name() != nb.Name() || !eq(*na.Data(), *nb.Data())
+				switch {
+				case parentKey(na) != parentKey(nb):
+					entry.Kind = DiffMoved
+				case entry.Modified:
+					entry.Kind = DiffModified
+				default:
+					entry.Kind = DiffUnchanged
+				}
+			}
+			var oldChildren []*Node[T]
+			if entry.Old != nil {
+				oldChildren = entry.Old.Children()
+			}
+			children, err := merge(oldChildren, nb.Children())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, newDiffNode(k, nb.Name(), entry, children))
+		}
+
+		for i, na := range olds {
+			k := key(na)
+			if _, ok := newNodes[k]; ok {
+				continue
+			}
+			children, err := merge(na.Children(), nil)
+			if err != nil {
+				return nil, err
+			}
+			node := newDiffNode(k, na.Name(), DiffEntry[T]{Kind: DiffRemoved, Old: na}, children)
+
+			// Insert after the closest preceding old sibling that is shown
+			at := 0
+			for j := i - 1; j >= 0; j-- {
+				prev := key(olds[j])
+				if pos := slices.IndexFunc(out, func(n *Node[DiffEntry[T]]) bool { return n.ID() == prev }); pos >= 0 {
+					at = pos + 1
+					break
+				}
+			}
+			out = slices.Insert(out, at, node)
+		}
+		return out, nil
+	}
+
+	roots, err := merge(aRoots, bRoots)
+	if err != nil {
+		return nil, err
+	}
+	return NewTree(roots, WithProvider[DiffEntry[T]](NewDiffProvider[T]())), nil
+}
+
+// newDiffNode creates a merged node. It records whether anything in its
+// subtree changed and expands it if so.
+func newDiffNode[T any](id, name string, entry DiffEntry[T], children []*Node[DiffEntry[T]]) *Node[DiffEntry[T]] {
+	entry.Changes = entry.Kind != DiffUnchanged
+	for _, child := range children {
+		entry.Changes = entry.Changes || child.Data().Changes
+	}
+	node := NewNode(id, name, entry)
+	node.SetChildren(children)
+	node.SetExpanded(entry.Changes && len(children) > 0)
+	return node
+}
+
+// PredDiffKind returns a predicate that checks the kind of a node in the
+// result of Diff.
+func PredDiffKind[T any](kind DiffKind) func(*Node[DiffEntry[T]]) bool {
+	return func(n *Node[DiffEntry[T]]) bool {
+		return n.Data().Kind == kind
+	}
+}
+
+// NewDiffProvider returns a provider for the result of Diff that marks each
+// node with its kind ("+", "-", "~", "→") and colors it: green for added,
+// struck-through red for removed, orange for modified and blue for moved
+// nodes. Renamed nodes show "old → new", moved nodes where they came from.
+// Options are evaluated before the built-in rules.
+func NewDiffProvider[T any](opts ...ProviderOption[DiffEntry[T]]) *DefaultNodeProvider[DiffEntry[T]] {
+	focused := func(color string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color(color)).Bold(true)
+	}
+	rules := []ProviderOption[DiffEntry[T]]{
+		WithIconRule(PredDiffKind[T](DiffAdded), "+"),
+		WithIconRule(PredDiffKind[T](DiffRemoved), "-"),
+		WithIconRule(PredDiffKind[T](DiffModified), "~"),
+		WithIconRule(PredDiffKind[T](DiffMoved), "→"),
+		WithDefaultIcon[DiffEntry[T]](" "),
+
+		WithStyleRule(PredDiffKind[T](DiffAdded),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("34")), focused("34")),
+		WithStyleRule(PredDiffKind[T](DiffRemoved),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("160")).Strikethrough(true), focused("160").Strikethrough(true)),
+		WithStyleRule(PredDiffKind[T](DiffModified),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("214")), focused("214")),
+		WithStyleRule(PredDiffKind[T](DiffMoved),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("39")), focused("39")),
+
+		WithNameFormatter(formatDiffEntry[T]),
+	}
+	return NewDefaultNodeProvider(append(opts, rules...)...)
+}
+
+// formatDiffEntry labels renamed and moved nodes with their old name or
+// location. The name is the new name, after the old one.
+func formatDiffEntry[T any](n *Node[DiffEntry[T]]) (string, int, bool) {
+	entry := n.Data()
+	label, offset := n.Name(), 0
+	if entry.Old != nil && entry.New != nil && entry.Old.Name() != entry.New.Name() {
+		label = entry.Old.Name() + " → " + entry.New.Name()
+		offset = len(label) - len(entry.New.Name())
+	}
+	if entry.Kind == DiffMoved {
+		from := "top level"
+		if p := entry.Old.Parent(); p != nil {
+			from = namePath(p)
+		}
+		label = fmt.Sprintf("%s (moved from %s)", label, from)
+	}
+	return label, offset, true
+}
+
+// namePath returns the names from the root down to node joined by "/".
+func namePath[T any](node *Node[T]) string {
+	var names []string
+	for n := node; n != nil; n = n.Parent() {
+		names = append(names, n.Name())
+	}
+	slices.Reverse(names)
+	return strings.Join(names, "/")
+}
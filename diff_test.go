@@ -0,0 +1,314 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// diffLines lists the nodes of a diff result as "id:kind", indented by depth.
+func diffLines(t *testing.T, tree *Tree[DiffEntry[string]]) []string {
+	t.Helper()
+	var lines []string
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		indent := ""
+		for range info.Depth {
+			indent += "  "
+		}
+		lines = append(lines, indent+info.Node.ID()+":"+info.Node.Data().Kind.String())
+	}
+	return lines
+}
+
+// createDiffTree builds a tree of string nodes from a parent map given in
+// order as {id, parent, data}; an empty parent makes a root. Names equal IDs.
+func createDiffTree(items [][3]string) *Tree[string] {
+	nodes := make(map[string]*Node[string])
+	var roots []*Node[string]
+	for _, item := range items {
+		id, parent, data := item[0], item[1], item[2]
+		node := NewNode(id, id, data)
+		nodes[id] = node
+		if parent == "" {
+			roots = append(roots, node)
+			continue
+		}
+		p := nodes[parent]
+		p.SetChildren(append(p.Children(), node))
+	}
+	return NewTree(roots)
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	old := createDiffTree([][3]string{
+		{"root", "", ""},
+		{"a", "root", "1"},
+		{"a1", "a", "x"},
+		{"b", "root", "2"},
+		{"c", "root", "3"},
+		{"d", "root", "4"},
+		{"d1", "d", "y"},
+		{"e", "root", "5"},
+		{"e1", "e", "z"},
+	})
+	updated := createDiffTree([][3]string{
+		{"root", "", ""},
+		{"a", "root", "1"},
+		{"a1", "a", "x"},
+		{"c", "root", "changed"},
+		{"d", "root", "4"},
+		{"e", "root", "5"},
+		{"d1", "e", "y"},
+		{"e1", "e", "z"},
+		{"f", "root", "6"},
+	})
+
+	got, err := Diff(ctx, old, updated, nil)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	want := []string{
+		"root:unchanged",
+		"  a:unchanged",
+		"    a1:unchanged",
+		"  b:removed",
+		"  c:modified",
+		"  d:unchanged",
+		"  e:unchanged",
+		"    d1:moved",
+		"    e1:unchanged",
+		"  f:added",
+	}
+	if diff := cmp.Diff(want, diffLines(t, got)); diff != "" {
+		t.Errorf("Diff() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Unchanged subtrees start collapsed, subtrees with changes expanded
+	expanded := map[string]bool{}
+	for info, err := range got.All(ctx) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		if info.Node.HasChildren() {
+			expanded[info.Node.ID()] = info.Node.IsExpanded()
+		}
+	}
+	wantExpanded := map[string]bool{"root": true, "a": false, "e": true}
+	if diff := cmp.Diff(wantExpanded, expanded); diff != "" {
+		t.Errorf("expanded mismatch (-want +got):\n%s", diff)
+	}
+
+	d1, err := got.FindByID(ctx, "d1")
+	if err != nil {
+		t.Fatalf("FindByID(d1) error = %v", err)
+	}
+	if label, want := got.Provider().Format(d1), "d1 (moved from root/d)"; label != want {
+		t.Errorf("Format(d1) = %q, want %q", label, want)
+	}
+}
+
+func TestDiff_Kinds(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		old  [][3]string
+		new  [][3]string
+		eq   func(string, string) bool
+		want []string
+	}{
+		{
+			name: "removed_keeps_position",
+			old:  [][3]string{{"a", "", ""}, {"b", "", ""}, {"c", "", ""}},
+			new:  [][3]string{{"a", "", ""}, {"c", "", ""}},
+			want: []string{"a:unchanged", "b:removed", "c:unchanged"},
+		},
+		{
+			name: "removed_first",
+			old:  [][3]string{{"a", "", ""}, {"b", "", ""}},
+			new:  [][3]string{{"b", "", ""}},
+			want: []string{"a:removed", "b:unchanged"},
+		},
+		{
+			name: "removed_subtree",
+			old:  [][3]string{{"a", "", ""}, {"a1", "a", ""}},
+			new:  [][3]string{},
+			want: []string{"a:removed", "  a1:removed"},
+		},
+		{
+			name: "added_subtree",
+			old:  [][3]string{},
+			new:  [][3]string{{"a", "", ""}, {"a1", "a", ""}},
+			want: []string{"a:added", "  a1:added"},
+		},
+		{
+			name: "moved_to_top_level",
+			old:  [][3]string{{"a", "", ""}, {"a1", "a", ""}},
+			new:  [][3]string{{"a", "", ""}, {"a1", "", ""}},
+			want: []string{"a:unchanged", "a1:moved"},
+		},
+		{
+			name: "custom_eq",
+			old:  [][3]string{{"a", "", "x"}},
+			new:  [][3]string{{"a", "", "X"}},
+			eq:   func(a, b string) bool { return len(a) == len(b) },
+			want: []string{"a:unchanged"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Diff(ctx, createDiffTree(test.old), createDiffTree(test.new), test.eq)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, diffLines(t, got)); diff != "" {
+				t.Errorf("Diff() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiff_Renamed(t *testing.T) {
+	ctx := context.Background()
+	old := NewTree([]*Node[string]{NewNode("a", "old.txt", "")})
+	updated := NewTree([]*Node[string]{NewNode("a", "new.txt", "")})
+
+	got, err := Diff(ctx, old, updated, nil)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	node := got.Nodes()[0]
+	if kind := node.Data().Kind; kind != DiffModified {
+		t.Errorf("Kind = %v, want %v", kind, DiffModified)
+	}
+	if label, want := got.Provider().Format(node), "old.txt → new.txt"; label != want {
+		t.Errorf("Format() = %q, want %q", label, want)
+	}
+}
+
+func TestDiff_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	items := [][3]string{{"a", "", ""}, {"a1", "a", ""}, {"a2", "a", ""}, {"b", "", ""}}
+	x, y := createDiffTree(items), createDiffTree(items)
+
+	// Diffs in both directions run while both trees change
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				a, b := x, y
+				if i%2 == 1 {
+					a, b = y, x
+				}
+				if _, err := Diff(ctx, a, b, nil); err != nil {
+					t.Errorf("Diff() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for _, tree := range []*Tree[string]{x, y} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				parent := []string{"a", "b"}[i%2]
+				if err := tree.MoveNode(ctx, "a1", parent, 0); err != nil {
+					t.Errorf("MoveNode() error = %v", err)
+					return
+				}
+				if err := tree.SetNodeData(ctx, "a2", parent); err != nil {
+					t.Errorf("SetNodeData() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDiff_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree := createDiffTree([][3]string{{"a", "", ""}})
+	if _, err := Diff(ctx, tree, tree, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Diff() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDiffFileSystem(t *testing.T) {
+	ctx := context.Background()
+	oldDir := createDiskUsageDir(t, map[string]int{
+		"same.txt":     10,
+		"grown.txt":    10,
+		"gone.txt":     10,
+		"sub/keep.txt": 10,
+	})
+	newDir := createDiskUsageDir(t, map[string]int{
+		"same.txt":     10,
+		"grown.txt":    20,
+		"new.txt":      10,
+		"sub/keep.txt": 10,
+	})
+	// Give unchanged files the same modification time in both directories
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, dir := range []string{oldDir, newDir} {
+		for _, name := range []string{"same.txt", "grown.txt", "sub/keep.txt"} {
+			if err := os.Chtimes(filepath.Join(dir, name), mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	got, err := DiffFileSystem(ctx, oldDir, newDir)
+	if err != nil {
+		t.Fatalf("DiffFileSystem() error = %v", err)
+	}
+	kinds := map[string]DiffKind{}
+	for info, err := range got.All(ctx) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		kinds[info.Node.ID()] = info.Node.Data().Kind
+	}
+	want := map[string]DiffKind{
+		".":            DiffUnchanged,
+		"same.txt":     DiffUnchanged,
+		"grown.txt":    DiffModified,
+		"gone.txt":     DiffRemoved,
+		"new.txt":      DiffAdded,
+		"sub":          DiffUnchanged,
+		"sub/keep.txt": DiffUnchanged,
+	}
+	if diff := cmp.Diff(want, kinds); diff != "" {
+		t.Errorf("DiffFileSystem() kinds mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffFileSystem_RootMismatch(t *testing.T) {
+	ctx := context.Background()
+	tree, err := NewTreeFromFileSystem(ctx, createDiskUsageDir(t, map[string]int{"a.txt": 1}), false)
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	empty := NewTree[FileInfo](nil)
+
+	for _, pair := range [][2]*Tree[FileInfo]{{tree, empty}, {empty, tree}} {
+		if _, err := diffFileSystemTrees(ctx, pair[0], pair[1]); !errors.Is(err, ErrTreeConstruction) {
+			t.Errorf("diffFileSystemTrees(%d roots, %d roots) error = %v, want %v", len(pair[0].Nodes()), len(pair[1].Nodes()), err, ErrTreeConstruction)
+		}
+	}
+}
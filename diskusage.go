@@ -0,0 +1,151 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// A disk usage view shows file system trees the way ncdu does: every entry
+// with its cumulative size, its share of the parent directory and a bar, the
+// largest entries first. NewDiskUsageTree sets one up; the pieces it uses can
+// also be combined by hand.
+
+// DeepSizeKey is the FileInfo.Extra key under which NewTreeFromFileSystem
+// stores the total size of the files below a directory whose contents were
+// cut off by WithMaxDepth; see WithDeepSizes.
+const DeepSizeKey = "deepSize"
+
+// diskUsageBarWidth is the number of cells inside the bar drawn by
+// WithDiskUsageFormatter.
+const diskUsageBarWidth = 10
+
+// WithDeepSizes makes NewTreeFromFileSystem total the sizes of the files
+// below directories cut off by WithMaxDepth, so disk usage is complete even
+// though the deeper entries get no nodes. The total is stored under
+// DeepSizeKey; see DeepSize. Symbolic links are not followed, the filter
+// function is not consulted, and unreadable directories are skipped.
+func WithDeepSizes() Option[FileInfo] {
+	return func(c *MasterConfig[FileInfo]) {
+		c.deepSizes = true
+	}
+}
+
+// DeepSize returns the size of the files below node that have no nodes
+// because of WithMaxDepth, or 0 if there are none or WithDeepSizes wasn't
+// used.
+func DeepSize(node *Node[FileInfo]) int64 {
+	size, _ := node.Data().Extra[DeepSizeKey].(int64)
+	return size
+}
+
+// setDeepSize stores the size of the files below the directory of node.
+func setDeepSize(ctx context.Context, node *Node[FileInfo]) error {
+	var size int64
+	err := filepath.WalkDir(node.Data().Path, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir // Count what we can read
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if size > 0 {
+		info := node.Data()
+		if info.Extra == nil {
+			info.Extra = make(map[string]any)
+		}
+		info.Extra[DeepSizeKey] = size
+	}
+	return nil
+}
+
+// DiskUsageLeaf is the leaf function of AggregateDiskUsage: the size of a
+// file, or the size of what WithDeepSizes counted below a directory.
+func DiskUsageLeaf(node *Node[FileInfo]) int64 {
+	return fileSize(node) + DeepSize(node)
+}
+
+// AggregateDiskUsage aggregates the cumulative size of every entry in a file
+// system tree. Returns context errors unwrapped.
+func AggregateDiskUsage(ctx context.Context, tree *Tree[FileInfo]) (*Aggregation[FileInfo, int64], error) {
+	return Aggregate(ctx, tree, DiskUsageLeaf, func(a, b int64) int64 { return a + b })
+}
+
+// ByDiskUsage sorts entries by cumulative size, largest first, and entries
+// of equal size by name.
+func ByDiskUsage(usage *Aggregation[FileInfo, int64]) LessFn[FileInfo] {
+	return ThenBy(
+		func(a, b *Node[FileInfo]) bool { return usage.ValueLocked(a) > usage.ValueLocked(b) },
+		ByName[FileInfo](),
+	)
+}
+
+// WithDiskUsageFormatter is a provider Option that prefixes every entry with
+// its cumulative size, its share of the parent directory and a bar, as in
+// "  3.4 MB  42.0% [####      ] src/". Roots count as 100%.
+func WithDiskUsageFormatter(usage *Aggregation[FileInfo, int64]) ProviderOption[FileInfo] {
+	return WithNameFormatter(func(n *Node[FileInfo]) (string, int, bool) {
+		size := usage.ValueLocked(n)
+		share := 1.0
+		if parent := n.Parent(); parent != nil {
+			share = 0
+			if total := usage.ValueLocked(parent); total > 0 {
+				share = float64(size) / float64(total)
+			}
+		}
+
+		name := n.Name()
+		if isDir(n) {
+			name += "/"
+		}
+		prefix := fmt.Sprintf("%9s %5.1f%% %s ", formatBytes(size), share*100, diskUsageBar(share))
+		return prefix + name, len(prefix), true
+	})
+}
+
+// diskUsageBar draws share (0 to 1) as a bar of diskUsageBarWidth cells.
+func diskUsageBar(share float64) string {
+	filled := int(share*diskUsageBarWidth + 0.5)
+	filled = min(max(filled, 0), diskUsageBarWidth)
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(" ", diskUsageBarWidth-filled) + "]"
+}
+
+// NewDiskUsageTree builds a disk usage view of the directory at path: sizes
+// are counted all the way down even with WithMaxDepth, every entry shows its
+// size, share and bar, and the largest entries come first. The returned
+// aggregation holds the sizes, which stay up to date as nodes are removed,
+// for example by WithTuiDelete. Accepts the options of NewTreeFromFileSystem;
+// a provider given with WithProvider is replaced. Returns context errors
+// unwrapped, or ErrFileSystem for filesystem errors.
+func NewDiskUsageTree(ctx context.Context, path string, opts ...Option[FileInfo]) (*Tree[FileInfo], *Aggregation[FileInfo, int64], error) {
+	tree, err := NewTreeFromFileSystem(ctx, path, false, append(opts, WithDeepSizes())...)
+	if err != nil {
+		return nil, nil, err
+	}
+	usage, err := AggregateDiskUsage(ctx, tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree.SetProvider(NewFileNodeProvider(WithDiskUsageFormatter(usage)))
+	if err := tree.Sort(ctx, ByDiskUsage(usage)); err != nil {
+		return nil, nil, err
+	}
+	tree.ClearHistory() // Sorting is part of building, not an edit
+	return tree, usage, nil
+}
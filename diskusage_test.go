@@ -0,0 +1,156 @@
+package treeview
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// createDiskUsageDir creates files of the given sizes below a temporary
+// directory and returns its path.
+func createDiskUsageDir(t *testing.T, files map[string]int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestWithDeepSizes(t *testing.T) {
+	ctx := context.Background()
+	dir := createDiskUsageDir(t, map[string]int{
+		"top.txt":          10,
+		"sub/a.txt":        100,
+		"sub/deep/b.txt":   1000,
+		"sub/deep/x/c.txt": 10000,
+	})
+
+	tests := []struct {
+		name     string
+		opts     []Option[FileInfo]
+		wantDeep int64
+		wantRoot int64
+	}{
+		{
+			name:     "without",
+			opts:     []Option[FileInfo]{WithMaxDepth[FileInfo](1)},
+			wantDeep: 0,
+			wantRoot: 10,
+		},
+		{
+			name:     "with",
+			opts:     []Option[FileInfo]{WithMaxDepth[FileInfo](1), WithDeepSizes()},
+			wantDeep: 11100,
+			wantRoot: 11110,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree, err := NewTreeFromFileSystem(ctx, dir, false, test.opts...)
+			if err != nil {
+				t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+			}
+			sub, err := tree.FindByID(ctx, filepath.Join(dir, "sub"))
+			if err != nil {
+				t.Fatalf("FindByID(sub) error = %v", err)
+			}
+			if sub.HasChildren() {
+				t.Errorf("sub has children, want none below the depth limit")
+			}
+			if got := DeepSize(sub); got != test.wantDeep {
+				t.Errorf("DeepSize(sub) = %d, want %d", got, test.wantDeep)
+			}
+
+			usage, err := AggregateDiskUsage(ctx, tree)
+			if err != nil {
+				t.Fatalf("AggregateDiskUsage() error = %v", err)
+			}
+			if got := usage.Value(tree.Nodes()[0]); got != test.wantRoot {
+				t.Errorf("AggregateDiskUsage() root = %d, want %d", got, test.wantRoot)
+			}
+		})
+	}
+}
+
+func TestNewDiskUsageTree(t *testing.T) {
+	ctx := context.Background()
+	dir := createDiskUsageDir(t, map[string]int{
+		"small.txt":   100,
+		"big/a.bin":   600,
+		"big/b.bin":   200,
+		"medium.txt":  300,
+		"empty/.keep": 0,
+	})
+
+	tree, usage, err := NewDiskUsageTree(ctx, dir)
+	if err != nil {
+		t.Fatalf("NewDiskUsageTree() error = %v", err)
+	}
+	if tree.CanUndo() {
+		t.Errorf("NewDiskUsageTree() CanUndo() = true, want a clean history")
+	}
+
+	var got []string
+	for info, err := range tree.All(ctx) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		rel, _ := filepath.Rel(dir, info.Node.ID())
+		got = append(got, rel)
+	}
+	want := []string{".", "big", "big/a.bin", "big/b.bin", "medium.txt", "small.txt", "empty", "empty/.keep"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NewDiskUsageTree() order mismatch (-want +got):\n%s", diff)
+	}
+
+	big, _ := tree.FindByID(ctx, filepath.Join(dir, "big"))
+	if label, want := tree.Provider().Format(big), "    800 B  66.7% [#######   ] big/"; label != want {
+		t.Errorf("Format(big) = %q, want %q", label, want)
+	}
+	small, _ := tree.FindByID(ctx, filepath.Join(dir, "small.txt"))
+	if label, want := tree.Provider().Format(small), "    100 B   8.3% [#         ] small.txt"; label != want {
+		t.Errorf("Format(small.txt) = %q, want %q", label, want)
+	}
+	if label := tree.Provider().Format(tree.Nodes()[0]); !strings.HasPrefix(label, "   1.2 KB 100.0% [##########] ") {
+		t.Errorf("Format(root) = %q, want the total at 100%%", label)
+	}
+
+	// Removing an entry updates the sizes above it
+	if _, err := tree.RemoveNode(ctx, filepath.Join(dir, "big", "a.bin")); err != nil {
+		t.Fatalf("RemoveNode() error = %v", err)
+	}
+	if got := usage.Value(big); got != 200 {
+		t.Errorf("usage of big after removal = %d, want 200", got)
+	}
+}
+
+func TestDiskUsageBar(t *testing.T) {
+	tests := []struct {
+		share float64
+		want  string
+	}{
+		{0, "[          ]"},
+		{0.04, "[          ]"},
+		{0.05, "[#         ]"},
+		{0.5, "[#####     ]"},
+		{1, "[##########]"},
+		{1.5, "[##########]"},
+	}
+	for _, test := range tests {
+		if got := diskUsageBar(test.share); got != test.want {
+			t.Errorf("diskUsageBar(%v) = %q, want %q", test.share, got, test.want)
+		}
+	}
+}
@@ -14,6 +14,14 @@ var (
 	// has been exceeded during a build or file-system scan.
 	ErrTraversalLimit = errors.New("traversal limit exceeded")
 
+	// ErrDuplicateID is returned when a node is inserted with an ID that is
+	// already used in the tree.
+	ErrDuplicateID = errors.New("duplicate node ID")
+
+	// ErrAttachedNode is returned when a node that already has a parent is
+	// inserted into a tree.
+	ErrAttachedNode = errors.New("node already has a parent")
+
 	// ErrNodeNotFound is returned by lookup helpers when the requested node
 	// does not exist in the tree.
 	ErrNodeNotFound = errors.New("node not found in tree")
@@ -22,6 +30,10 @@ var (
 	// in parent-child relationships.
 	ErrCyclicReference = errors.New("cyclic reference detected in tree")
 
+	// ErrOrphanedNode is returned when building a tree from flat data finds
+	// an item whose parent ID doesn't match any item (see WithOrphanPolicy).
+	ErrOrphanedNode = errors.New("parent of node not found")
+
 	// ErrTreeConstruction is returned when tree building fails at a high level.
 	ErrTreeConstruction = errors.New("tree construction failed")
 
@@ -33,6 +45,19 @@ var (
 
 	// ErrDirectoryScan is returned when directory scanning fails.
 	ErrDirectoryScan = errors.New("directory scan failed")
+
+	// ErrNotFileSystemTree is returned by WatchFileSystem for trees that were
+	// not built by NewTreeFromFileSystem.
+	ErrNotFileSystemTree = errors.New("tree was not built from the file system")
+
+	// ErrInvalidMove is returned when a node cannot be moved to the requested
+	// position, either because it would become its own descendant or because
+	// the tree's move policy rejected it.
+	ErrInvalidMove = errors.New("invalid node move")
+
+	// ErrInvalidQuery is returned when a search query cannot be parsed. The
+	// concrete error is a *QueryError carrying the position of the problem.
+	ErrInvalidQuery = errors.New("invalid query")
 )
 
 // pathError creates an error that includes path context.
@@ -48,3 +73,8 @@ func pathError(sentinel error, path string, cause error) error {
 func cyclicReferenceError(nodeID, parentID string) error {
 	return fmt.Errorf("%w: node %q -> parent %q", ErrCyclicReference, nodeID, parentID)
 }
+
+// orphanedNodeError creates an error for a node whose parent is missing.
+func orphanedNodeError(nodeID, parentID string) error {
+	return fmt.Errorf("%w: node %q -> parent %q", ErrOrphanedNode, nodeID, parentID)
+}
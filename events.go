@@ -0,0 +1,304 @@
+package treeview
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Subscribers learn about changes to a tree without polling it. Every change
+// made through Tree methods, including undo, redo and a FileSystemWatcher, is
+// queued for each subscriber and delivered from a goroutine of its own, never
+// while the tree lock is held, so subscribers may call back into the tree.
+// Changes made directly on Node values are not observed.
+//
+// Each subscriber has a bounded queue (see WithEventBuffer), so a slow
+// subscriber never holds up the tree. Waiting events are coalesced:
+//   - An event of the same kind as the newest waiting one is merged into it.
+//   - When the queue is full, an event is merged into the newest waiting
+//     event of its kind.
+//   - When the queue is full and no event of that kind waits, everything
+//     waiting is dropped and replaced by a single EventResync.
+//
+// Merged events carry the IDs of both, so no node is lost, only the order in
+// which things happened.
+
+// defaultEventBuffer is the number of events a subscriber can fall behind by
+// before they are coalesced.
+const defaultEventBuffer = 64
+
+// TreeEventKind is the kind of change a TreeEvent reports.
+type TreeEventKind int
+
+const (
+	// EventFocus reports a changed focus. IDs holds every focused node,
+	// primary first, and is empty when the focus was cleared.
+	EventFocus TreeEventKind = iota
+	// EventExpansion reports nodes that were expanded or collapsed.
+	EventExpansion
+	// EventVisibility reports nodes that were shown or hidden. IDs is nil
+	// when a filter or hoist was applied or cleared, which affects the whole
+	// tree.
+	EventVisibility
+	// EventData reports nodes whose payload or name changed.
+	EventData
+	// EventStructure reports nodes that were inserted, removed or moved, and
+	// nodes whose children were reordered. IDs is nil when the roots were
+	// reordered or replaced.
+	EventStructure
+	// EventResync reports that events were dropped because the subscriber
+	// fell behind. Re-read whatever you keep of the tree.
+	EventResync
+)
+
+// String returns the lower-case name of the kind.
+func (k TreeEventKind) String() string {
+	switch k {
+	case EventFocus:
+		return "focus"
+	case EventExpansion:
+		return "expansion"
+	case EventVisibility:
+		return "visibility"
+	case EventData:
+		return "data"
+	case EventStructure:
+		return "structure"
+	case EventResync:
+		return "resync"
+	default:
+		return "unknown"
+	}
+}
+
+// TreeEvent is a change delivered to subscribers; see Tree.Subscribe.
+type TreeEvent struct {
+	Kind TreeEventKind
+	IDs  []string // The nodes concerned; see the kinds for nil
+}
+
+// merge folds next, a later event of the same kind, into e. Focus events
+// report the current focus, so the later one wins; others collect the IDs of
+// both, nil meaning the whole tree.
+func (e TreeEvent) merge(next TreeEvent) TreeEvent {
+	if e.Kind == EventFocus || e.Kind == EventResync {
+		return next
+	}
+	if e.IDs == nil || next.IDs == nil {
+		e.IDs = nil
+		return e
+	}
+	// The IDs may be shared with other subscribers
+	ids := slices.Clip(e.IDs)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, id := range next.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	e.IDs = ids
+	return e
+}
+
+// SubscribeOption configures a subscription.
+type SubscribeOption func(*subscription)
+
+// WithEventBuffer sets how many events may wait for a subscriber before they
+// are coalesced. The default is 64.
+func WithEventBuffer(n int) SubscribeOption {
+	return func(s *subscription) {
+		if n > 0 {
+			s.limit = n
+		}
+	}
+}
+
+// eventHub holds the subscriptions of a tree. Its lock is taken after t.mu.
+type eventHub struct {
+	mu   sync.Mutex
+	subs []*subscription
+}
+
+// subscription is the queue of one subscriber.
+type subscription struct {
+	mu    sync.Mutex
+	queue []TreeEvent
+	limit int
+	wake  chan struct{}
+}
+
+// Subscribe returns a channel delivering the tree's changes until ctx is
+// done, when the channel is closed. Events the receiver falls behind on are
+// coalesced as described above.
+func (t *Tree[T]) Subscribe(ctx context.Context, opts ...SubscribeOption) <-chan TreeEvent {
+	out := make(chan TreeEvent)
+	t.subscribe(ctx, opts, func(ev TreeEvent) {
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+		}
+	}, func() { close(out) })
+	return out
+}
+
+// SubscribeFunc calls fn for every change of the tree until ctx is done.
+// Calls come one at a time from a goroutine of the subscription; events that
+// arrive while fn runs are coalesced as described above.
+func (t *Tree[T]) SubscribeFunc(ctx context.Context, fn func(TreeEvent), opts ...SubscribeOption) {
+	t.subscribe(ctx, opts, fn, func() {})
+}
+
+// subscribe registers a subscription and starts delivering its events.
+func (t *Tree[T]) subscribe(ctx context.Context, opts []SubscribeOption, deliver func(TreeEvent), done func()) {
+	s := &subscription{limit: defaultEventBuffer, wake: make(chan struct{}, 1)}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+
+	hub := &t.events
+	hub.mu.Lock()
+	hub.subs = append(slices.Clip(hub.subs), s)
+	hub.mu.Unlock()
+
+	go func() {
+		defer done()
+		defer func() {
+			hub.mu.Lock()
+			defer hub.mu.Unlock()
+			hub.subs = slices.DeleteFunc(slices.Clone(hub.subs), func(other *subscription) bool { return other == s })
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			}
+			for ev, ok := s.pop(); ok; ev, ok = s.pop() {
+				if ctx.Err() != nil {
+					return
+				}
+				deliver(ev)
+			}
+		}
+	}()
+}
+
+// push queues ev, coalescing as described above, and wakes the subscriber.
+func (s *subscription) push(ev TreeEvent) {
+	s.mu.Lock()
+	last := len(s.queue) - 1
+	switch {
+	case last >= 0 && s.queue[last].Kind == ev.Kind:
+		s.queue[last] = s.queue[last].merge(ev)
+	case len(s.queue) < s.limit:
+		s.queue = append(s.queue, ev)
+	default:
+		i := len(s.queue) - 1
+		for i >= 0 && s.queue[i].Kind != ev.Kind {
+			i--
+		}
+		if i >= 0 {
+			s.queue[i] = s.queue[i].merge(ev)
+		} else {
+			s.queue = append(s.queue[:0], TreeEvent{Kind: EventResync})
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pop takes the oldest waiting event.
+func (s *subscription) pop() (TreeEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return TreeEvent{}, false
+	}
+	ev := s.queue[0]
+	s.queue = s.queue[1:]
+	return ev, true
+}
+
+// observed reports whether anyone subscribed, so callers can skip collecting
+// IDs for nobody.
+func (t *Tree[T]) observed() bool {
+	t.events.mu.Lock()
+	defer t.events.mu.Unlock()
+	return len(t.events.subs) > 0
+}
+
+// emit queues an event for every subscriber. It may be called with or
+// without t.mu held.
+func (t *Tree[T]) emit(kind TreeEventKind, ids []string) {
+	t.events.mu.Lock()
+	subs := t.events.subs
+	t.events.mu.Unlock()
+	for _, s := range subs {
+		s.push(TreeEvent{Kind: kind, IDs: ids})
+	}
+}
+
+// emitFocus reports the current focus. Callers must hold t.mu.
+func (t *Tree[T]) emitFocus() {
+	if t.observed() {
+		t.emit(EventFocus, nodeIDs(t.focusedNodes))
+	}
+}
+
+// nodeStateChanges sets the expanded and visible flags of nodes and collects
+// the IDs of those that actually changed, for one event per kind.
+type nodeStateChanges[T any] struct {
+	expanded, visible []string
+	nodes             []*Node[T]
+}
+
+// expand sets the expanded flag of node.
+func (c *nodeStateChanges[T]) expand(node *Node[T], expanded bool) {
+	if node.IsExpanded() != expanded {
+		node.SetExpanded(expanded)
+		c.expanded = append(c.expanded, node.ID())
+		c.nodes = append(c.nodes, node)
+	}
+}
+
+// show sets the visible flag of node.
+func (c *nodeStateChanges[T]) show(node *Node[T], visible bool) {
+	if node.IsVisible() != visible {
+		node.SetVisible(visible)
+		c.visible = append(c.visible, node.ID())
+		c.nodes = append(c.nodes, node)
+	}
+}
+
+// commitStateChanges drops the snapshot copies of the changed nodes and
+// reports the changes. Callers must hold t.mu.
+func (t *Tree[T]) commitStateChanges(c nodeStateChanges[T]) {
+	for _, node := range c.nodes {
+		t.touch(node)
+	}
+	if len(c.expanded) > 0 {
+		t.expansionVersion++
+		t.emit(EventExpansion, c.expanded)
+	}
+	if len(c.visible) > 0 {
+		t.emit(EventVisibility, c.visible)
+	}
+}
+
+// expansionVersionNow returns a counter that increases on every expansion
+// change.
+func (t *Tree[T]) expansionVersionNow() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.expansionVersion
+}
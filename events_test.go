@@ -0,0 +1,290 @@
+package treeview
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// createEventTree returns a tree of "a" (children "a1", "a2", expanded), "b"
+// and "c" with "a" focused.
+func createEventTree() *Tree[string] {
+	tree := createDiffTree([][3]string{
+		{"c", "", ""},
+		{"a", "", ""},
+		{"a1", "a", ""},
+		{"a2", "a", ""},
+		{"b", "", ""},
+	})
+	a, _ := tree.FindByID(context.Background(), "a")
+	a.Expand()
+	_, _ = tree.SetFocusedID(context.Background(), "a")
+	return tree
+}
+
+// queueEvents registers a subscription without a delivering goroutine, so
+// tests can look at exactly what was queued.
+func queueEvents(tree *Tree[string], limit int) *subscription {
+	s := &subscription{limit: limit, wake: make(chan struct{}, 1)}
+	tree.events.subs = append(tree.events.subs, s)
+	return s
+}
+
+func TestTreeEvents(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(*Tree[string]) error
+		want   []TreeEvent
+	}{
+		{
+			name: "focus",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.SetFocusedID(ctx, "b")
+				return err
+			},
+			want: []TreeEvent{{Kind: EventFocus, IDs: []string{"b"}}},
+		},
+		{
+			name: "focus_cleared",
+			change: func(tree *Tree[string]) error {
+				tree.ClearAllFocus()
+				return nil
+			},
+			want: []TreeEvent{{Kind: EventFocus, IDs: []string{}}},
+		},
+		{
+			name: "collapse",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.SetExpanded(ctx, "a", false)
+				return err
+			},
+			want: []TreeEvent{{Kind: EventExpansion, IDs: []string{"a"}}},
+		},
+		{
+			name: "expand_all_reports_changed_nodes",
+			change: func(tree *Tree[string]) error {
+				return tree.ExpandAll(ctx)
+			},
+			want: []TreeEvent{{Kind: EventExpansion, IDs: []string{"c", "a1", "a2", "b"}}},
+		},
+		{
+			name: "hide_all",
+			change: func(tree *Tree[string]) error {
+				return tree.HideAll(ctx)
+			},
+			want: []TreeEvent{{Kind: EventVisibility, IDs: []string{"c", "a", "a1", "a2", "b"}}},
+		},
+		{
+			name: "filter",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.Filter(ctx, "a2")
+				return err
+			},
+			want: []TreeEvent{
+				{Kind: EventVisibility},
+				{Kind: EventFocus, IDs: []string{"a2"}},
+			},
+		},
+		{
+			name: "data",
+			change: func(tree *Tree[string]) error {
+				return tree.SetNodeData(ctx, "b", "new")
+			},
+			want: []TreeEvent{{Kind: EventData, IDs: []string{"b"}}},
+		},
+		{
+			name: "rename",
+			change: func(tree *Tree[string]) error {
+				return tree.Rename(ctx, "b", "bee")
+			},
+			want: []TreeEvent{{Kind: EventData, IDs: []string{"b"}}},
+		},
+		{
+			name: "insert",
+			change: func(tree *Tree[string]) error {
+				return tree.InsertNode(ctx, "b", 0, NewNode("b1", "b1", ""))
+			},
+			want: []TreeEvent{{Kind: EventStructure, IDs: []string{"b1"}}},
+		},
+		{
+			name: "move",
+			change: func(tree *Tree[string]) error {
+				return tree.MoveNode(ctx, "a1", "b", 0)
+			},
+			want: []TreeEvent{{Kind: EventStructure, IDs: []string{"a1"}}},
+		},
+		{
+			name: "remove_focused",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.RemoveNode(ctx, "a")
+				return err
+			},
+			want: []TreeEvent{
+				{Kind: EventStructure, IDs: []string{"a"}},
+				{Kind: EventFocus, IDs: []string{}},
+			},
+		},
+		{
+			name: "sort_roots",
+			change: func(tree *Tree[string]) error {
+				return tree.Sort(ctx, ByName[string]())
+			},
+			want: []TreeEvent{{Kind: EventStructure}},
+		},
+		{
+			name: "sort_subtree",
+			change: func(tree *Tree[string]) error {
+				return tree.SortSubtree(ctx, "a", Reverse(ByName[string]()))
+			},
+			want: []TreeEvent{{Kind: EventStructure, IDs: []string{"a"}}},
+		},
+		{
+			name: "undo",
+			change: func(tree *Tree[string]) error {
+				if err := tree.SetNodeData(ctx, "b", "new"); err != nil {
+					return err
+				}
+				if _, err := tree.SetExpanded(ctx, "a", false); err != nil {
+					return err
+				}
+				_, err := tree.Undo(ctx)
+				return err
+			},
+			want: []TreeEvent{
+				{Kind: EventData, IDs: []string{"b"}},
+				{Kind: EventExpansion, IDs: []string{"a"}},
+			},
+		},
+		{
+			name: "no_change",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.SetFocusedID(ctx, "a")
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createEventTree()
+			s := queueEvents(tree, defaultEventBuffer)
+			if err := test.change(tree); err != nil {
+				t.Fatalf("change error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, s.queue); diff != "" {
+				t.Errorf("events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSubscriptionCoalescing(t *testing.T) {
+	focus := func(ids ...string) TreeEvent { return TreeEvent{Kind: EventFocus, IDs: ids} }
+	data := func(ids ...string) TreeEvent { return TreeEvent{Kind: EventData, IDs: ids} }
+	structure := func(ids ...string) TreeEvent { return TreeEvent{Kind: EventStructure, IDs: ids} }
+
+	tests := []struct {
+		name   string
+		limit  int
+		events []TreeEvent
+		want   []TreeEvent
+	}{
+		{
+			name:   "same_kind_merges",
+			limit:  4,
+			events: []TreeEvent{data("a"), data("b", "a")},
+			want:   []TreeEvent{data("a", "b")},
+		},
+		{
+			name:   "focus_keeps_latest",
+			limit:  4,
+			events: []TreeEvent{focus("a"), focus("b")},
+			want:   []TreeEvent{focus("b")},
+		},
+		{
+			name:   "whole_tree_absorbs",
+			limit:  4,
+			events: []TreeEvent{structure("a"), structure()},
+			want:   []TreeEvent{structure()},
+		},
+		{
+			name:   "full_merges_into_same_kind",
+			limit:  2,
+			events: []TreeEvent{data("a"), focus("x"), data("b")},
+			want:   []TreeEvent{data("a", "b"), focus("x")},
+		},
+		{
+			name:   "full_without_same_kind_resyncs",
+			limit:  2,
+			events: []TreeEvent{data("a"), focus("x"), structure("s"), data("b")},
+			want:   []TreeEvent{{Kind: EventResync}, data("b")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &subscription{limit: test.limit, wake: make(chan struct{}, 1)}
+			for _, ev := range test.events {
+				s.push(ev)
+			}
+			if diff := cmp.Diff(test.want, s.queue); diff != "" {
+				t.Errorf("queue mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTree_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tree := createEventTree()
+	events := tree.Subscribe(ctx)
+
+	if _, err := tree.SetFocusedID(ctx, "b"); err != nil {
+		t.Fatalf("SetFocusedID() error = %v", err)
+	}
+	select {
+	case ev := <-events:
+		if diff := cmp.Diff(TreeEvent{Kind: EventFocus, IDs: []string{"b"}}, ev); diff != "" {
+			t.Errorf("event mismatch (-want +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no event delivered")
+	}
+
+	cancel()
+	for range events {
+	}
+	if tree.observed() {
+		t.Errorf("subscription still registered after cancel")
+	}
+}
+
+func TestTree_SubscribeFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tree := createEventTree()
+
+	// The callback can read the tree: events are not delivered under its lock
+	focused := make(chan string, 1)
+	tree.SubscribeFunc(ctx, func(ev TreeEvent) {
+		if ev.Kind == EventFocus {
+			focused <- tree.GetFocusedID()
+		}
+	})
+
+	if _, err := tree.SetFocusedID(ctx, "c"); err != nil {
+		t.Fatalf("SetFocusedID() error = %v", err)
+	}
+	select {
+	case id := <-focused:
+		if id != "c" {
+			t.Errorf("GetFocusedID() in callback = %q, want %q", id, "c")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("callback not called")
+	}
+}
@@ -43,6 +43,9 @@ type FileBrowserModel struct {
 	height       int
 	provider     *treeview.DefaultNodeProvider[treeview.FileInfo]
 
+	// Keeps the tree in step with changes on disk
+	watcher *treeview.FileSystemWatcher
+
 	// Rotating viewport state for file types
 	typeRotationOffset int
 	typeRotationTicker *time.Ticker
@@ -80,17 +83,31 @@ func (m *FileBrowserModel) newTuiTreeModel(tree *treeview.Tree[treeview.FileInfo
 		treeWidth = m.width
 	}
 
-	// Create custom key map to avoid Enter key conflict
-	keyMap := treeview.DefaultKeyMap()
-	keyMap.SearchStart = []string{"/"}
-
-	return treeview.NewTuiTreeModel(
-		tree,
+	opts := []treeview.TuiTreeModelOption[treeview.FileInfo]{
 		treeview.WithTuiWidth[treeview.FileInfo](treeWidth),
-		treeview.WithTuiHeight[treeview.FileInfo](m.height-3),
-		treeview.WithTuiKeyMap[treeview.FileInfo](keyMap),
+		treeview.WithTuiHeight[treeview.FileInfo](m.height - 3),
 		treeview.WithTuiDisableNavBar[treeview.FileInfo](true),
-	)
+	}
+	if m.watcher != nil {
+		opts = append(opts, treeview.WithTuiWatcher(m.watcher))
+	}
+	return treeview.NewTuiTreeModel(tree, opts...)
+}
+
+// watch replaces the watcher with one for tree and returns the command that
+// waits for its first change.
+func (m *FileBrowserModel) watch(tree *treeview.Tree[treeview.FileInfo]) tea.Cmd {
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
+	w, err := treeview.WatchFileSystem(context.Background(), tree)
+	if err != nil {
+		log.Printf("Failed to watch directory: %v", err)
+		return nil
+	}
+	m.watcher = w
+	return w.Wait()
 }
 
 // loadDirectory loads a directory tree from the filesystem
@@ -241,11 +258,15 @@ func (m *FileBrowserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case directoryLoadedMsg:
+		watchCmd := m.watch(msg.tree)
 		m.treeModel = m.newTuiTreeModel(msg.tree)
+		return m, watchCmd
 
 	case directoryChangedMsg:
 		m.currentPath = msg.path
+		watchCmd := m.watch(msg.tree)
 		m.treeModel = m.newTuiTreeModel(msg.tree)
+		return m, watchCmd
 
 	case errorMsg:
 		log.Printf("Error: %v", msg.err)
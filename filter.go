@@ -0,0 +1,198 @@
+package treeview
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// A filter hides everything except the matches of a search and their
+// ancestors, optionally also showing the subtrees below the matches (see
+// WithFilterDescendants). Unlike SearchAndExpand it never touches the nodes'
+// expanded or visible flags: visibility is an overlay consulted by AllVisible,
+// so clearing the filter brings back the tree exactly as it was.
+
+// filterView is the visibility overlay of an active filter. It is built once
+// per filter and never modified afterwards, so iterators can read it without
+// holding the tree lock.
+type filterView[T any] struct {
+	shown       map[*Node[T]]bool // Matches and their ancestors
+	matched     map[*Node[T]]bool
+	descendants bool
+}
+
+// Filter restricts the visible nodes to those matching term plus their
+// ancestors, using the tree's configured matcher. Ancestors are shown even
+// when collapsed. All matches are focused. An empty term clears the filter.
+// Returns context errors unwrapped.
+func (t *Tree[T]) Filter(ctx context.Context, term string) ([]*Node[T], error) {
+	return t.FilterWith(ctx, term, nil)
+}
+
+// FilterWith is Filter using match instead of the tree's configured matcher.
+// A nil match uses the configured matcher and search index. Returns context
+// errors unwrapped.
+func (t *Tree[T]) FilterWith(ctx context.Context, term string, match MatchFn[T]) ([]*Node[T], error) {
+	if term == "" {
+		t.ClearFilter()
+		return nil, nil
+	}
+
+	results, err := t.searchMatches(ctx, term, match)
+	if err != nil {
+		return nil, err
+	}
+	return t.filterMatches(results), nil
+}
+
+// filterMatches installs a filter showing the nodes of results and focuses
+// them, as Filter does.
+func (t *Tree[T]) filterMatches(results []SearchResult[T]) []*Node[T] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	view := &filterView[T]{
+		shown:       make(map[*Node[T]]bool),
+		matched:     make(map[*Node[T]]bool, len(results)),
+		descendants: t.filterDescendants,
+	}
+	matches := make([]*Node[T], 0, len(results))
+	ranges := make(map[string]Match, len(results))
+	for _, result := range results {
+		node := result.Node
+		matches = append(matches, node)
+		ranges[node.ID()] = result.Match
+		view.matched[node] = true
+		for n := node; n != nil && !view.shown[n]; n = n.Parent() {
+			view.shown[n] = true
+		}
+	}
+	t.filter = view
+	t.setMatches(ranges, matches)
+	t.emit(EventVisibility, nil)
+	t.focusNodes(matches)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches
+}
+
+// ClearFilter removes the filter overlay and its match highlights. Focus and
+// expansion are left as they are.
+func (t *Tree[T]) ClearFilter() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.filter != nil {
+		t.emit(EventVisibility, nil)
+	}
+	t.filter = nil
+	t.setMatches(nil, nil)
+}
+
+// IsFiltering reports whether a filter is active.
+func (t *Tree[T]) IsFiltering() bool {
+	return t.activeFilter() != nil
+}
+
+// activeFilter returns the current overlay or nil.
+func (t *Tree[T]) activeFilter() *filterView[T] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.filter
+}
+
+// focusNodes replaces the focus with nodes. Callers must hold t.mu.
+func (t *Tree[T]) focusNodes(nodes []*Node[T]) {
+	t.focusedNodes = make([]*Node[T], len(nodes))
+	copy(t.focusedNodes, nodes)
+	t.focusedIDs = make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		t.focusedIDs[node.ID()] = true
+	}
+	t.emitFocus()
+}
+
+// filterSeq yields the nodes shown by view in depth-first order. IsLast is
+// computed among the shown siblings so branch glyphs line up. Below a match
+// (when descendants are included) the normal expansion rules apply. Node
+// state is read under guard as in dfsSeq.
+func filterSeq[T any](ctx context.Context, roots []*Node[T], view *filterView[T], guard *sync.RWMutex) iter.Seq2[NodeInfo[T], error] {
+	return func(yield func(NodeInfo[T], error) bool) {
+		var walk func(nodes []*Node[T], depth int, underMatch bool) bool
+		walk = func(nodes []*Node[T], depth int, underMatch bool) bool {
+			shown := make([]*Node[T], 0, len(nodes))
+			for _, node := range nodes {
+				if underMatch || view.shown[node] {
+					shown = append(shown, node)
+				}
+			}
+
+			for i, node := range shown {
+				if err := ctx.Err(); err != nil {
+					yield(NodeInfo[T]{}, err)
+					return false
+				}
+				if !yield(NodeInfo[T]{Node: node, Depth: depth, IsLast: i == len(shown)-1}, nil) {
+					return false
+				}
+
+				readLock(guard)
+				below := view.descendants && (underMatch || view.matched[node]) && node.IsExpanded()
+				var children []*Node[T]
+				if node.HasChildren() && (below || view.shown[node]) {
+					children = node.children
+				}
+				readUnlock(guard)
+				if children != nil && !walk(children, depth+1, below) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(roots, 0, false)
+	}
+}
+
+// expansionState records the expanded flag of every node with children.
+func (t *Tree[T]) expansionState(ctx context.Context) (map[*Node[T]]bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	state := make(map[*Node[T]]bool)
+	for info, err := range dfsSeq(ctx, t.nodes, true, nil) {
+		if err != nil {
+			return nil, err
+		}
+		if info.Node.HasChildren() {
+			state[info.Node] = info.Node.IsExpanded()
+		}
+	}
+	return state, nil
+}
+
+// restoreView puts back expansion flags recorded by expansionState and the
+// given focus. Nodes removed in the meantime are simply dropped from the
+// focus. Like search expansion, this is not recorded in the undo history.
+func (t *Tree[T]) restoreView(ctx context.Context, expanded map[*Node[T]]bool, focused []*Node[T]) error {
+	present := make(map[*Node[T]]bool)
+	for info, err := range t.All(ctx) {
+		if err != nil {
+			return err
+		}
+		present[info.Node] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var changes nodeStateChanges[T]
+	for node, isExpanded := range expanded {
+		changes.expand(node, isExpanded)
+	}
+	t.commitStateChanges(changes)
+	kept := make([]*Node[T], 0, len(focused))
+	for _, node := range focused {
+		if present[node] {
+			kept = append(kept, node)
+		}
+	}
+	t.focusNodes(kept)
+	return nil
+}
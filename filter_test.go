@@ -0,0 +1,197 @@
+package treeview
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// createFilterTree returns
+//
+//	root (expanded)
+//	├── src (collapsed)
+//	│   ├── util
+//	│   └── pkg (collapsed)
+//	│       ├── target.go
+//	│       └── other.go
+//	└── docs (expanded)
+//	    └── readme
+func createFilterTree(opts ...Option[string]) *Tree[string] {
+	root := NewNode("root", "root", "")
+	src := NewNode("src", "src", "")
+	pkg := NewNode("pkg", "pkg", "")
+	docs := NewNode("docs", "docs", "")
+	pkg.SetChildren([]*Node[string]{NewNode("target", "target.go", ""), NewNode("other", "other.go", "")})
+	src.SetChildren([]*Node[string]{NewNode("util", "util", ""), pkg})
+	docs.SetChildren([]*Node[string]{NewNode("readme", "readme", "")})
+	root.SetChildren([]*Node[string]{src, docs})
+	root.Expand()
+	docs.Expand()
+	return NewTree([]*Node[string]{root}, opts...)
+}
+
+// visibleLines returns "id:depth:last" for every visible node.
+func visibleLines(t *testing.T, tree *Tree[string]) []string {
+	t.Helper()
+	var lines []string
+	for info, err := range tree.AllVisible(context.Background()) {
+		if err != nil {
+			t.Fatalf("AllVisible() error = %v", err)
+		}
+		last := ""
+		if info.IsLast {
+			last = ":last"
+		}
+		lines = append(lines, info.Node.ID()+":"+string(rune('0'+info.Depth))+last)
+	}
+	return lines
+}
+
+// expandedIDs returns the IDs of all expanded nodes.
+func expandedIDs(t *testing.T, tree *Tree[string]) []string {
+	t.Helper()
+	var ids []string
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		if info.Node.IsExpanded() {
+			ids = append(ids, info.Node.ID())
+		}
+	}
+	return ids
+}
+
+func TestTree_Filter(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option[string]
+		term        string
+		wantMatches []string
+		wantLines   []string
+	}{
+		{
+			name:        "ancestors_of_collapsed_match",
+			term:        "target",
+			wantMatches: []string{"target"},
+			wantLines:   []string{"root:0:last", "src:1:last", "pkg:2:last", "target:3:last"},
+		},
+		{
+			name:        "several_matches",
+			term:        ".go",
+			wantMatches: []string{"target", "other"},
+			wantLines:   []string{"root:0:last", "src:1:last", "pkg:2:last", "target:3", "other:3:last"},
+		},
+		{
+			name:        "descendants_follow_expansion",
+			opts:        []Option[string]{WithFilterDescendants[string]()},
+			term:        "s",
+			wantMatches: []string{"src", "docs"},
+			wantLines:   []string{"root:0:last", "src:1", "docs:1:last", "readme:2:last"},
+		},
+		{
+			name:      "no_matches_hides_everything",
+			term:      "zzz",
+			wantLines: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createFilterTree(test.opts...)
+			before := expandedIDs(t, tree)
+
+			matches, err := tree.Filter(context.Background(), test.term)
+			if err != nil {
+				t.Fatalf("Filter() error = %v", err)
+			}
+			var got []string
+			for _, node := range matches {
+				got = append(got, node.ID())
+			}
+			if diff := cmp.Diff(test.wantMatches, got); diff != "" {
+				t.Errorf("Filter() matches mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantLines, visibleLines(t, tree)); diff != "" {
+				t.Errorf("AllVisible() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantMatches, tree.GetAllFocusedIDs(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("focus mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(before, expandedIDs(t, tree)); diff != "" {
+				t.Errorf("Filter() changed expansion (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTree_ClearFilter(t *testing.T) {
+	tree := createFilterTree()
+	want := visibleLines(t, tree)
+
+	if _, err := tree.Filter(context.Background(), "target"); err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if !tree.IsFiltering() {
+		t.Fatal("IsFiltering() = false after Filter()")
+	}
+	if got := tree.matchRanges("target"); got == nil {
+		t.Error("matchRanges(target) = nil, want highlight while filtering")
+	}
+
+	if _, err := tree.Filter(context.Background(), ""); err != nil {
+		t.Fatalf("Filter(\"\") error = %v", err)
+	}
+	if tree.IsFiltering() {
+		t.Error("IsFiltering() = true after empty term")
+	}
+	if diff := cmp.Diff(want, visibleLines(t, tree)); diff != "" {
+		t.Errorf("AllVisible() after clearing mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTuiFilterMode_CancelRestoresView(t *testing.T) {
+	ctx := context.Background()
+	tree := createFilterTree()
+	model := NewTuiTreeModel(tree, WithTuiFilterMode[string](), WithTuiSearchDebounce[string](0))
+	if _, err := tree.SetFocusedID(ctx, "readme"); err != nil {
+		t.Fatalf("SetFocusedID() error = %v", err)
+	}
+	wantExpanded := expandedIDs(t, tree)
+	wantLines := visibleLines(t, tree)
+
+	model.BeginSearch()
+	for _, r := range "other" {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if got, want := tree.GetFocusedID(), "other"; got != want {
+		t.Errorf("focused while filtering = %q, want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{"root:0:last", "src:1:last", "pkg:2:last", "other:3:last"}, visibleLines(t, tree)); diff != "" {
+		t.Errorf("filtered view mismatch (-want +got):\n%s", diff)
+	}
+
+	// Expansion changes made while filtering are undone by cancelling too
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.NavigateUp()
+	model.Toggle()
+
+	model.BeginSearch()
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	if tree.IsFiltering() {
+		t.Error("IsFiltering() = true after cancel")
+	}
+	if got, want := tree.GetFocusedID(), "readme"; got != want {
+		t.Errorf("focused after cancel = %q, want %q", got, want)
+	}
+	if diff := cmp.Diff(wantExpanded, expandedIDs(t, tree)); diff != "" {
+		t.Errorf("expansion after cancel mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantLines, visibleLines(t, tree)); diff != "" {
+		t.Errorf("AllVisible() after cancel mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,165 @@
+package treeview
+
+import (
+	"context"
+	"slices"
+)
+
+// defaultHistoryLimit is the number of undo steps kept unless WithHistoryLimit
+// says otherwise.
+const defaultHistoryLimit = 100
+
+// change is a single recorded mutation together with its inverse. Both
+// functions are executed while the tree's write lock is held.
+type change[T any] struct {
+	undo func()
+	redo func()
+	// nodes are the nodes the change moves, edits or whose children it
+	// reorders; nil stands for the roots.
+	nodes []*Node[T]
+}
+
+// history keeps bounded undo and redo stacks. Each stack entry is a group of
+// changes that are undone and redone together, so a compound operation such
+// as moving several nodes is a single step for the user.
+type history[T any] struct {
+	limit int
+	undo  [][]change[T]
+	redo  [][]change[T]
+
+	// open collects changes while a Group call is running.
+	open  []change[T]
+	depth int
+}
+
+// record adds a change to the history. nodes are the nodes it moves or edits
+// and the parents whose children it changes. A new change invalidates
+// everything that could have been redone. Callers must hold the tree's write
+// lock.
+func (h *history[T]) record(undo, redo func(), nodes ...*Node[T]) {
+	if h.limit <= 0 {
+		return // History is disabled
+	}
+	h.redo = nil
+	c := change[T]{undo: undo, redo: redo, nodes: nodes}
+	if h.depth > 0 {
+		h.open = append(h.open, c)
+		return
+	}
+	h.push([]change[T]{c})
+}
+
+// push appends a group to the undo stack, dropping the oldest group once the
+// limit is exceeded.
+func (h *history[T]) push(group []change[T]) {
+	h.undo = append(h.undo, group)
+	if over := len(h.undo) - h.limit; over > 0 {
+		h.undo = append([][]change[T](nil), h.undo[over:]...)
+	}
+}
+
+// forget drops the undo and redo steps with a change involving one of the
+// given nodes, which were changed behind the history's back: replaying them
+// could bring back removed nodes or stale payloads. Steps that only involve
+// other nodes are kept. Callers must hold the tree's write lock.
+func (h *history[T]) forget(nodes map[*Node[T]]bool) {
+	involved := func(group []change[T]) bool {
+		return slices.ContainsFunc(group, func(c change[T]) bool {
+			return slices.ContainsFunc(c.nodes, func(n *Node[T]) bool { return nodes[n] })
+		})
+	}
+	h.undo = slices.DeleteFunc(h.undo, involved)
+	h.redo = slices.DeleteFunc(h.redo, involved)
+	if involved(h.open) {
+		h.open = nil
+	}
+}
+
+// Undo reverts the most recent group of changes. The bool result reports
+// whether anything was undone. Returns context errors unwrapped.
+func (t *Tree[T]) Undo(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := &t.history
+	if len(h.undo) == 0 {
+		return false, nil
+	}
+
+	group := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	for i := len(group) - 1; i >= 0; i-- {
+		group[i].undo()
+	}
+	h.redo = append(h.redo, group)
+	return true, nil
+}
+
+// Redo re-applies the most recently undone group of changes. The bool result
+// reports whether anything was redone. Returns context errors unwrapped.
+func (t *Tree[T]) Redo(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := &t.history
+	if len(h.redo) == 0 {
+		return false, nil
+	}
+
+	group := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	for _, c := range group {
+		c.redo()
+	}
+	h.push(group)
+	return true, nil
+}
+
+// CanUndo reports whether there is anything to undo.
+func (t *Tree[T]) CanUndo() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.history.undo) > 0
+}
+
+// CanRedo reports whether there is anything to redo.
+func (t *Tree[T]) CanRedo() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.history.redo) > 0
+}
+
+// ClearHistory forgets all undo and redo steps.
+func (t *Tree[T]) ClearHistory() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.history.undo = nil
+	t.history.redo = nil
+}
+
+// Group runs fn and records every mutation it makes as a single undo step.
+// Groups may be nested; only the outermost one creates a step. Mutations made
+// by other goroutines while fn runs end up in the same group.
+func (t *Tree[T]) Group(fn func() error) error {
+	t.mu.Lock()
+	t.history.depth++
+	t.mu.Unlock()
+
+	err := fn()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := &t.history
+	h.depth--
+	if h.depth == 0 && len(h.open) > 0 {
+		h.push(h.open)
+		h.open = nil
+	}
+	return err
+}
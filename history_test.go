@@ -0,0 +1,318 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newHistoryTree returns the simple test tree with the undo history enabled.
+func newHistoryTree(t *testing.T, limit int) *Tree[string] {
+	t.Helper()
+	tree := createSimpleTree(t)
+	tree.history.limit = limit
+	return tree
+}
+
+func TestTree_UndoRedo_Operations(t *testing.T) {
+	ctx := context.Background()
+	original := []string{"1(1.1(1.1.1),1.2)", "2(2.1)"}
+
+	tests := []struct {
+		name  string
+		apply func(tree *Tree[string]) error
+		check func(t *testing.T, tree *Tree[string], applied bool)
+	}{
+		{
+			name: "expand",
+			apply: func(tree *Tree[string]) error {
+				_, err := tree.SetExpanded(ctx, "2", true)
+				return err
+			},
+			check: func(t *testing.T, tree *Tree[string], applied bool) {
+				node, _ := tree.FindByID(ctx, "2")
+				if node.IsExpanded() != applied {
+					t.Errorf("node 2 expanded = %v, want %v", node.IsExpanded(), applied)
+				}
+			},
+		},
+		{
+			name: "rename",
+			apply: func(tree *Tree[string]) error {
+				return tree.Rename(ctx, "1.2", "renamed")
+			},
+			check: func(t *testing.T, tree *Tree[string], applied bool) {
+				node, _ := tree.FindByID(ctx, "1.2")
+				want := "Node 1.2"
+				if applied {
+					want = "renamed"
+				}
+				if node.Name() != want {
+					t.Errorf("node 1.2 name = %q, want %q", node.Name(), want)
+				}
+			},
+		},
+		{
+			name: "set_data",
+			apply: func(tree *Tree[string]) error {
+				return tree.SetNodeData(ctx, "2.1", "changed")
+			},
+			check: func(t *testing.T, tree *Tree[string], applied bool) {
+				node, _ := tree.FindByID(ctx, "2.1")
+				want := "data2.1"
+				if applied {
+					want = "changed"
+				}
+				if *node.Data() != want {
+					t.Errorf("node 2.1 data = %q, want %q", *node.Data(), want)
+				}
+			},
+		},
+		{
+			name: "move",
+			apply: func(tree *Tree[string]) error {
+				return tree.MoveNode(ctx, "1.1", "2", 0)
+			},
+			check: func(t *testing.T, tree *Tree[string], applied bool) {
+				want := original
+				if applied {
+					want = []string{"1(1.2)", "2(1.1(1.1.1),2.1)"}
+				}
+				if diff := cmp.Diff(want, treeShape(tree.Nodes())); diff != "" {
+					t.Errorf("shape mismatch (-want +got):\n%s", diff)
+				}
+			},
+		},
+		{
+			name: "insert",
+			apply: func(tree *Tree[string]) error {
+				return tree.InsertNode(ctx, "1", 1, NewNode("new", "new", "new"))
+			},
+			check: func(t *testing.T, tree *Tree[string], applied bool) {
+				want := original
+				if applied {
+					want = []string{"1(1.1(1.1.1),new,1.2)", "2(2.1)"}
+				}
+				if diff := cmp.Diff(want, treeShape(tree.Nodes())); diff != "" {
+					t.Errorf("shape mismatch (-want +got):\n%s", diff)
+				}
+			},
+		},
+		{
+			name: "remove",
+			apply: func(tree *Tree[string]) error {
+				_, err := tree.RemoveNode(ctx, "1.1")
+				return err
+			},
+			check: func(t *testing.T, tree *Tree[string], applied bool) {
+				want := original
+				if applied {
+					want = []string{"1(1.2)", "2(2.1)"}
+				}
+				if diff := cmp.Diff(want, treeShape(tree.Nodes())); diff != "" {
+					t.Errorf("shape mismatch (-want +got):\n%s", diff)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := newHistoryTree(t, defaultHistoryLimit)
+
+			if err := test.apply(tree); err != nil {
+				t.Fatalf("apply error = %v", err)
+			}
+			test.check(t, tree, true)
+
+			if ok, err := tree.Undo(ctx); !ok || err != nil {
+				t.Fatalf("Undo() = %v, %v, want true, nil", ok, err)
+			}
+			test.check(t, tree, false)
+
+			if ok, err := tree.Redo(ctx); !ok || err != nil {
+				t.Fatalf("Redo() = %v, %v, want true, nil", ok, err)
+			}
+			test.check(t, tree, true)
+		})
+	}
+}
+
+func TestTree_UndoRedo_Empty(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, defaultHistoryLimit)
+
+	if ok, err := tree.Undo(ctx); ok || err != nil {
+		t.Errorf("Undo() on empty history = %v, %v, want false, nil", ok, err)
+	}
+	if ok, err := tree.Redo(ctx); ok || err != nil {
+		t.Errorf("Redo() on empty history = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestTree_History_Limit(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, 2)
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := tree.Rename(ctx, "1", name); err != nil {
+			t.Fatalf("Rename(%q) error = %v", name, err)
+		}
+	}
+
+	for tree.CanUndo() {
+		if _, err := tree.Undo(ctx); err != nil {
+			t.Fatalf("Undo() error = %v", err)
+		}
+	}
+	node, _ := tree.FindByID(ctx, "1")
+	if got := node.Name(); got != "a" {
+		t.Errorf("name after undoing a 2-step history = %q, want %q", got, "a")
+	}
+}
+
+func TestTree_History_Disabled(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, 0)
+
+	if err := tree.Rename(ctx, "1", "x"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if tree.CanUndo() {
+		t.Errorf("CanUndo() with history disabled = true, want false")
+	}
+}
+
+func TestTree_History_NewChangeClearsRedo(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, defaultHistoryLimit)
+
+	_ = tree.Rename(ctx, "1", "x")
+	_, _ = tree.Undo(ctx)
+	if !tree.CanRedo() {
+		t.Fatalf("CanRedo() after Undo = false, want true")
+	}
+	_ = tree.Rename(ctx, "1", "y")
+	if tree.CanRedo() {
+		t.Errorf("CanRedo() after a new change = true, want false")
+	}
+}
+
+func TestTree_Group(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, defaultHistoryLimit)
+	errStop := errors.New("stop")
+
+	err := tree.Group(func() error {
+		_ = tree.Rename(ctx, "1", "x")
+		return tree.Group(func() error {
+			_ = tree.Rename(ctx, "2", "y")
+			return errStop
+		})
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Group() error = %v, want %v", err, errStop)
+	}
+
+	if ok, _ := tree.Undo(ctx); !ok {
+		t.Fatalf("Undo() = false, want true")
+	}
+	if tree.CanUndo() {
+		t.Errorf("CanUndo() after undoing the group = true, want false")
+	}
+	for id, want := range map[string]string{"1": "Node 1", "2": "Node 2"} {
+		node, _ := tree.FindByID(ctx, id)
+		if node.Name() != want {
+			t.Errorf("node %q name = %q, want %q", id, node.Name(), want)
+		}
+	}
+}
+
+func TestTree_InsertNode_Errors(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, defaultHistoryLimit)
+
+	tests := []struct {
+		name     string
+		parentID string
+		node     *Node[string]
+		wantErr  error
+	}{
+		{name: "empty_id", node: NewNode("", "x", "x"), wantErr: ErrEmptyID},
+		{name: "duplicate_id", node: NewNode("1.1", "x", "x"), wantErr: ErrDuplicateID},
+		{name: "missing_parent", parentID: "missing", node: NewNode("x", "x", "x"), wantErr: ErrNodeNotFound},
+		{name: "duplicate_child_id", node: withChildren(NewNode("x", "x", "x"), NewNode("2", "dup", "dup")), wantErr: ErrDuplicateID},
+		{name: "duplicate_within_subtree", node: withChildren(NewNode("x", "x", "x"), NewNode("y", "y", "y"), NewNode("y", "y", "y")), wantErr: ErrDuplicateID},
+		{name: "empty_child_id", node: withChildren(NewNode("x", "x", "x"), NewNode("", "y", "y")), wantErr: ErrEmptyID},
+		{name: "attached", node: withChildren(NewNode("x", "x", "x"), NewNode("y", "y", "y")).Children()[0], wantErr: ErrAttachedNode},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := tree.InsertNode(ctx, test.parentID, 0, test.node)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("InsertNode() error = %v, want %v", err, test.wantErr)
+			}
+			if _, err := tree.FindByID(ctx, "x"); err == nil {
+				t.Errorf("InsertNode() inserted %q despite the error", "x")
+			}
+		})
+	}
+}
+
+func TestTree_InsertNode_ConcurrentDuplicates(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, defaultHistoryLimit)
+
+	// Only one of the inserts claiming the same ID may succeed
+	const inserts = 8
+	errs := make([]error, inserts)
+	var wg sync.WaitGroup
+	for i := range inserts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = tree.InsertNode(ctx, "1", 0, NewNode("new", "new", "new"))
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrDuplicateID):
+			t.Errorf("InsertNode() error = %v, want %v", err, ErrDuplicateID)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d inserts succeeded, want 1", succeeded)
+	}
+}
+
+// withChildren adds children to node and returns it.
+func withChildren[T any](node *Node[T], children ...*Node[T]) *Node[T] {
+	for _, child := range children {
+		node.AddChild(child)
+	}
+	return node
+}
+
+func TestTree_RemoveNode_DropsFocus(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, defaultHistoryLimit)
+	if err := tree.SetAllFocusedIDs(ctx, []string{"1.1.1", "2"}); err != nil {
+		t.Fatalf("SetAllFocusedIDs() error = %v", err)
+	}
+
+	if _, err := tree.RemoveNode(ctx, "1.1"); err != nil {
+		t.Fatalf("RemoveNode() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"2"}, tree.GetAllFocusedIDs()); diff != "" {
+		t.Errorf("GetAllFocusedIDs() after RemoveNode mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,291 @@
+package treeview
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Hoisting shows one subtree as if it were the whole tree, for deep
+// hierarchies whose indentation would otherwise eat the screen width. Like a
+// filter it is an overlay consulted by AllVisible: All, FindByID and the
+// mutation methods still see every node, and unhoisting brings back the full
+// view.
+
+// Hoist makes the node with the given ID the only root of the visible tree:
+// AllVisible, Render and Move start from it at depth 0. The node and its
+// ancestors are expanded and shown, so it stays in view when stepping back out,
+// and it is focused if the primary focus lies outside its subtree. Stepping out
+// or hoisting elsewhere collapses and hides the nodes hoisting opened again,
+// unless the new hoist or the focused node still needs them, so showing the
+// whole tree restores the expansion it had before. An active filter still
+// applies below it. An empty ID shows the whole tree again. Returns
+// ErrNodeNotFound if the ID doesn't exist, or context errors unwrapped.
+func (t *Tree[T]) Hoist(ctx context.Context, id string) error {
+	if id == "" {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.setHoist(nil)
+		return nil
+	}
+
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return ErrNodeNotFound // Removed since it was looked up
+	}
+	t.setHoist(node)
+	if len(t.focusedNodes) == 0 || !isWithin(t.focusedNodes[0], node) {
+		t.focusNodes([]*Node[T]{node})
+	}
+	return nil
+}
+
+// Unhoist steps one level out of the hoisted subtree: the parent of the
+// hoisted node is hoisted instead, or the whole tree is shown again when the
+// node is a root. Reports whether a node was hoisted.
+func (t *Tree[T]) Unhoist() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hoist == nil {
+		return false
+	}
+	t.setHoist(t.hoist.Parent())
+	return true
+}
+
+// HoistedNode returns the hoisted node, or nil if the whole tree is shown.
+func (t *Tree[T]) HoistedNode() *Node[T] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.hoist
+}
+
+// HoistPath returns the breadcrumb trail of the hoisted node: its ancestors
+// from the root down, followed by the node itself. It is empty when nothing
+// is hoisted.
+func (t *Tree[T]) HoistPath() []*Node[T] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var path []*Node[T]
+	for n := t.hoist; n != nil; n = n.Parent() {
+		path = append(path, n)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// hoistOpening is a node that hoisting expanded or showed, with the flags it
+// changed, so they can be put back once no hoist needs them.
+type hoistOpening[T any] struct {
+	node            *Node[T]
+	expanded, shown bool
+}
+
+// setHoist replaces the hoisted node, expanding and showing a new one and its
+// ancestors. Nodes an earlier hoist expanded or showed are collapsed or
+// hidden again once the new hoist no longer needs them, except ancestors of
+// the focused node, which stay open so the focus remains in view. A nil node
+// shows the whole tree. Callers must hold t.mu.
+func (t *Tree[T]) setHoist(node *Node[T]) {
+	keep := make(map[*Node[T]]bool)
+	for n := node; n != nil; n = n.Parent() {
+		keep[n] = true
+	}
+	if len(t.focusedNodes) > 0 {
+		for n := t.focusedNodes[0].Parent(); n != nil; n = n.Parent() {
+			keep[n] = true
+		}
+	}
+
+	var changes nodeStateChanges[T]
+	opened := t.hoistOpened[:0:0]
+	for _, o := range t.hoistOpened {
+		if keep[o.node] {
+			opened = append(opened, o)
+			continue
+		}
+		if !t.contains(o.node) {
+			continue
+		}
+		if o.expanded {
+			changes.expand(o.node, false)
+		}
+		if o.shown {
+			changes.show(o.node, false)
+		}
+	}
+
+	for n := node; n != nil; n = n.Parent() {
+		o := hoistOpening[T]{node: n}
+		if n.HasChildren() && !n.IsExpanded() {
+			changes.expand(n, true)
+			o.expanded = true
+		}
+		if !n.IsVisible() {
+			changes.show(n, true)
+			o.shown = true
+		}
+		if o.expanded || o.shown {
+			opened = append(opened, o)
+		}
+	}
+	t.commitStateChanges(changes)
+
+	// Once the whole tree is shown again, what is still open is the user's
+	t.hoistOpened = opened
+	if node == nil {
+		t.hoistOpened = nil
+	}
+	if node != t.hoist {
+		t.hoist = node
+		t.emit(EventVisibility, nil)
+	}
+}
+
+// isWithin reports whether node is ancestor itself or lies below it.
+func isWithin[T any](node, ancestor *Node[T]) bool {
+	for n := node; n != nil; n = n.Parent() {
+		if n == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Hoist hoists the node with the given ID as Tree.Hoist does and makes room
+// for the breadcrumb header above the tree.
+func (m *TuiTreeModel[T]) Hoist(ctx context.Context, id string) error {
+	defer m.updateViewportDimensions()
+	return m.Tree.Hoist(ctx, id)
+}
+
+// Unhoist steps one level out of the hoisted subtree as Tree.Unhoist does and
+// removes the breadcrumb header once the whole tree is shown.
+func (m *TuiTreeModel[T]) Unhoist() bool {
+	defer m.updateViewportDimensions()
+	return m.Tree.Unhoist()
+}
+
+// HoistFocused hoists the focused node, so the tree below it uses the full
+// width of the view.
+func (m *TuiTreeModel[T]) HoistFocused() {
+	if id := m.GetFocusedID(); id != "" {
+		m.execWithNavigationTimeout(func(ctx context.Context) error {
+			return m.Hoist(ctx, id)
+		})
+	}
+}
+
+// Breadcrumbs returns the labels of the breadcrumb header: "⌂" for the whole
+// tree, then the names along HoistPath. It is empty when nothing is hoisted.
+func (m *TuiTreeModel[T]) Breadcrumbs() []string {
+	path := m.HoistPath()
+	if len(path) == 0 {
+		return nil
+	}
+	crumbs := []string{"⌂"}
+	for _, node := range path {
+		crumbs = append(crumbs, node.Name())
+	}
+	return crumbs
+}
+
+// JumpToBreadcrumb hoists the ancestor at position i of Breadcrumbs, or shows
+// the whole tree for position 0. Out of range positions are ignored.
+func (m *TuiTreeModel[T]) JumpToBreadcrumb(i int) {
+	path := m.HoistPath()
+	if i < 0 || i > len(path) {
+		return
+	}
+	id := ""
+	if i > 0 {
+		id = path[i-1].ID()
+	}
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		return m.Hoist(ctx, id)
+	})
+}
+
+// BeginBreadcrumbs lets the user pick an ancestor from the breadcrumb header
+// with KeyMap.BreadcrumbPrev and BreadcrumbNext, starting at the parent of
+// the hoisted node. Does nothing when nothing is hoisted.
+func (m *TuiTreeModel[T]) BeginBreadcrumbs() {
+	if crumbs := m.Breadcrumbs(); len(crumbs) > 0 {
+		m.pickingCrumb = true
+		m.crumb = len(crumbs) - 2
+	}
+}
+
+// CancelBreadcrumbs leaves the breadcrumb picker without hoisting anything.
+func (m *TuiTreeModel[T]) CancelBreadcrumbs() {
+	m.pickingCrumb = false
+}
+
+// PickingBreadcrumb reports whether the breadcrumb picker is open.
+func (m *TuiTreeModel[T]) PickingBreadcrumb() bool {
+	return m.pickingCrumb
+}
+
+// handleBreadcrumbKey handles a key press while the breadcrumb picker is
+// open; other keys are ignored.
+func (m *TuiTreeModel[T]) handleBreadcrumbKey(key string) {
+	switch {
+	case slices.Contains(m.keyMap.BreadcrumbPrev, key):
+		m.crumb = max(m.crumb-1, 0)
+	case slices.Contains(m.keyMap.BreadcrumbNext, key):
+		m.crumb = min(m.crumb+1, len(m.Breadcrumbs())-1)
+	case slices.Contains(m.keyMap.BreadcrumbAccept, key):
+		m.CancelBreadcrumbs()
+		m.JumpToBreadcrumb(m.crumb)
+	case slices.Contains(m.keyMap.BreadcrumbCancel, key):
+		m.CancelBreadcrumbs()
+	}
+}
+
+// handleMouse jumps to the breadcrumb that was clicked. The header is the
+// first line of the view; mouse reporting has to be enabled on the program,
+// for example with tea.WithMouseCellMotion.
+func (m *TuiTreeModel[T]) handleMouse(msg tea.MouseMsg) {
+	if msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft || msg.Y != 0 {
+		return
+	}
+	if m.renaming || m.Deleting() {
+		return
+	}
+	x := 0
+	for i, crumb := range m.breadcrumbLabels() {
+		width := visualWidth(crumb)
+		if msg.X >= x && msg.X < x+width {
+			m.CancelBreadcrumbs()
+			m.JumpToBreadcrumb(i)
+			return
+		}
+		x += width + visualWidth(breadcrumbSeparator)
+	}
+}
+
+// breadcrumbSeparator goes between the labels of the breadcrumb header.
+const breadcrumbSeparator = " › "
+
+// breadcrumbHeader is the trail shown above a hoisted tree.
+func (m *TuiTreeModel[T]) breadcrumbHeader() string {
+	return strings.Join(m.breadcrumbLabels(), breadcrumbSeparator)
+}
+
+// breadcrumbLabels returns Breadcrumbs as the header shows them, with the
+// crumb being picked in brackets.
+func (m *TuiTreeModel[T]) breadcrumbLabels() []string {
+	crumbs := m.Breadcrumbs()
+	if m.pickingCrumb && m.crumb < len(crumbs) {
+		crumbs[m.crumb] = "[" + crumbs[m.crumb] + "]"
+	}
+	return crumbs
+}
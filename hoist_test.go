@@ -0,0 +1,403 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-cmp/cmp"
+)
+
+// createHoistTree returns roots c, a[a1[x], a2] and b with nothing expanded
+// and c focused.
+func createHoistTree() *Tree[string] {
+	tree := createDiffTree([][3]string{
+		{"c", "", ""},
+		{"a", "", ""},
+		{"a1", "a", ""},
+		{"x", "a1", ""},
+		{"a2", "a", ""},
+		{"b", "", ""},
+	})
+	_, _ = tree.SetFocusedID(context.Background(), "c")
+	return tree
+}
+
+func TestTree_Hoist(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		change      func(*Tree[string]) error
+		wantVisible []string
+		wantPath    []string
+		wantFocus   string
+		wantErr     error
+	}{
+		{
+			name: "hoist",
+			change: func(tree *Tree[string]) error {
+				return tree.Hoist(ctx, "a")
+			},
+			wantVisible: []string{"a", "a1", "a2"},
+			wantPath:    []string{"a"},
+			wantFocus:   "a",
+		},
+		{
+			name: "keeps_focus_inside",
+			change: func(tree *Tree[string]) error {
+				_, _ = tree.SetFocusedID(ctx, "a2")
+				return tree.Hoist(ctx, "a")
+			},
+			wantVisible: []string{"a", "a1", "a2"},
+			wantPath:    []string{"a"},
+			wantFocus:   "a2",
+		},
+		{
+			name: "nested",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a")
+				return tree.Hoist(ctx, "a1")
+			},
+			wantVisible: []string{"a1", "x"},
+			wantPath:    []string{"a", "a1"},
+			wantFocus:   "a1",
+		},
+		{
+			name: "unhoist_to_parent",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a1")
+				if !tree.Unhoist() {
+					return errors.New("Unhoist() = false")
+				}
+				return nil
+			},
+			// a1 was only expanded for the hoist
+			wantVisible: []string{"a", "a1", "a2"},
+			wantPath:    []string{"a"},
+			wantFocus:   "a1",
+		},
+		{
+			name: "unhoist_root",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a")
+				tree.Unhoist()
+				if tree.Unhoist() {
+					return errors.New("Unhoist() of the whole tree = true")
+				}
+				return nil
+			},
+			wantVisible: []string{"c", "a", "b"},
+			wantFocus:   "a",
+		},
+		{
+			name: "clear",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a1")
+				return tree.Hoist(ctx, "")
+			},
+			// a stays expanded to keep the focused a1 in view
+			wantVisible: []string{"c", "a", "a1", "a2", "b"},
+			wantFocus:   "a1",
+		},
+		{
+			name: "clear_keeps_prior_expansion",
+			change: func(tree *Tree[string]) error {
+				if _, err := tree.SetExpanded(ctx, "a1", true); err != nil {
+					return err
+				}
+				_ = tree.Hoist(ctx, "a1")
+				_, _ = tree.SetFocusedID(ctx, "x")
+				_ = tree.Hoist(ctx, "b")
+				_, _ = tree.SetFocusedID(ctx, "c")
+				return tree.Hoist(ctx, "")
+			},
+			wantVisible: []string{"c", "a", "b"},
+			wantFocus:   "c",
+		},
+		{
+			name: "clear_after_user_expansion",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a")
+				if _, err := tree.SetExpanded(ctx, "a1", true); err != nil {
+					return err
+				}
+				_, _ = tree.SetFocusedID(ctx, "c")
+				return tree.Hoist(ctx, "")
+			},
+			// a1 was expanded by the user, a only by the hoist
+			wantVisible: []string{"c", "a", "b"},
+			wantFocus:   "c",
+		},
+		{
+			name: "remove_hoisted",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a1")
+				_, err := tree.RemoveNode(ctx, "a1")
+				return err
+			},
+			wantVisible: []string{"a", "a2"},
+			wantPath:    []string{"a"},
+		},
+		{
+			name: "move_hoisted",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a1")
+				return tree.MoveNode(ctx, "a1", "b", 0)
+			},
+			wantVisible: []string{"a1", "x"},
+			wantPath:    []string{"b", "a1"},
+			wantFocus:   "a1",
+		},
+		{
+			name: "filter_inside",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a")
+				_, err := tree.Filter(ctx, "a2")
+				return err
+			},
+			wantVisible: []string{"a", "a2"},
+			wantPath:    []string{"a"},
+			wantFocus:   "a2",
+		},
+		{
+			name: "hoist_filtered",
+			change: func(tree *Tree[string]) error {
+				if _, err := tree.Filter(ctx, "x"); err != nil {
+					return err
+				}
+				return tree.Hoist(ctx, "a1")
+			},
+			wantVisible: []string{"a1", "x"},
+			wantPath:    []string{"a", "a1"},
+			wantFocus:   "x",
+		},
+		{
+			name: "unhoist_filtered",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a")
+				if _, err := tree.Filter(ctx, "a2"); err != nil {
+					return err
+				}
+				tree.Unhoist()
+				tree.ClearFilter()
+				return nil
+			},
+			// The focused a2 keeps a expanded once the filter is gone
+			wantVisible: []string{"c", "a", "a1", "a2", "b"},
+			wantFocus:   "a2",
+		},
+		{
+			name: "unhoist_filtered_focus_outside",
+			change: func(tree *Tree[string]) error {
+				_ = tree.Hoist(ctx, "a")
+				if _, err := tree.Filter(ctx, "a"); err != nil {
+					return err
+				}
+				_, _ = tree.SetFocusedID(ctx, "a")
+				tree.Unhoist()
+				tree.ClearFilter()
+				return nil
+			},
+			wantVisible: []string{"c", "a", "b"},
+			wantFocus:   "a",
+		},
+		{
+			name: "not_found",
+			change: func(tree *Tree[string]) error {
+				return tree.Hoist(ctx, "missing")
+			},
+			wantVisible: []string{"c", "a", "b"},
+			wantFocus:   "c",
+			wantErr:     ErrNodeNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createHoistTree()
+			if err := test.change(tree); !errors.Is(err, test.wantErr) {
+				t.Fatalf("change error = %v, want %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.wantVisible, visibleIDs(t, tree.AllVisible)); diff != "" {
+				t.Errorf("AllVisible() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantPath, nodeIDs(tree.HoistPath()), cmpEmpty); diff != "" {
+				t.Errorf("HoistPath() mismatch (-want +got):\n%s", diff)
+			}
+			if got := tree.GetFocusedID(); got != test.wantFocus {
+				t.Errorf("GetFocusedID() = %q, want %q", got, test.wantFocus)
+			}
+		})
+	}
+}
+
+// cmpEmpty treats nil and empty slices as equal.
+var cmpEmpty = cmp.Comparer(func(a, b []string) bool {
+	return len(a) == 0 && len(b) == 0 || cmp.Equal(a, b)
+})
+
+func TestTree_Hoist_View(t *testing.T) {
+	ctx := context.Background()
+	tree := createHoistTree()
+	events := queueEvents(tree, defaultEventBuffer)
+	if err := tree.Hoist(ctx, "a1"); err != nil {
+		t.Fatalf("Hoist() error = %v", err)
+	}
+
+	// The hoisted node renders as a root, without branch glyphs
+	got, err := tree.Render(ctx)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if lines := strings.Split(got, "\n"); len(lines) != 2 || strings.Contains(lines[0], "──") {
+		t.Errorf("Render() = %q, want a1 as a root above x", got)
+	}
+	if !slices.ContainsFunc(events.queue, func(ev TreeEvent) bool { return ev.Kind == EventVisibility && ev.IDs == nil }) {
+		t.Errorf("Hoist() emitted no %v event", EventVisibility)
+	}
+
+	// Snapshots keep the hoist, everything else still sees the whole tree
+	snap := tree.Snapshot()
+	tree.Unhoist()
+	if diff := cmp.Diff([]string{"a1", "x"}, visibleIDs(t, snap.AllVisible)); diff != "" {
+		t.Errorf("Snapshot AllVisible() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"c", "a", "a1", "x", "a2", "b"}, visibleIDs(t, tree.All)); diff != "" {
+		t.Errorf("All() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTuiHoist(t *testing.T) {
+	tests := []struct {
+		name       string
+		focus      string
+		keys       []string
+		wantHeader string // First line of the view; "" when nothing is hoisted
+		wantHoist  []HoistChangedMsg
+	}{
+		{
+			name:       "hoist",
+			focus:      "a1",
+			keys:       []string{"z"},
+			wantHeader: "⌂ › a › a1",
+			wantHoist:  []HoistChangedMsg{{ID: "a1", Path: []string{"a", "a1"}}},
+		},
+		{
+			name:       "unhoist",
+			focus:      "a1",
+			keys:       []string{"z", "Z"},
+			wantHeader: "⌂ › a",
+			wantHoist: []HoistChangedMsg{
+				{ID: "a1", Path: []string{"a", "a1"}},
+				{ID: "a", Path: []string{"a"}},
+			},
+		},
+		{
+			name:      "unhoist_all",
+			focus:     "a",
+			keys:      []string{"z", "Z", "Z"},
+			wantHoist: []HoistChangedMsg{{ID: "a", Path: []string{"a"}}, {Path: []string{}}},
+		},
+		{
+			name:       "pick_parent",
+			focus:      "x",
+			keys:       []string{"z", "b", "enter"},
+			wantHeader: "⌂ › a › a1",
+			wantHoist: []HoistChangedMsg{
+				{ID: "x", Path: []string{"a", "a1", "x"}},
+				{ID: "a1", Path: []string{"a", "a1"}},
+			},
+		},
+		{
+			name:       "pick_root",
+			focus:      "x",
+			keys:       []string{"z", "b", "left", "left", "left", "enter"},
+			wantHeader: "",
+			wantHoist: []HoistChangedMsg{
+				{ID: "x", Path: []string{"a", "a1", "x"}},
+				{Path: []string{}},
+			},
+		},
+		{
+			name:       "picking",
+			focus:      "x",
+			keys:       []string{"z", "b", "left", "right", "right", "right"},
+			wantHeader: "⌂ › a › a1 › [x]",
+			wantHoist:  []HoistChangedMsg{{ID: "x", Path: []string{"a", "a1", "x"}}},
+		},
+		{
+			name:       "pick_cancel",
+			focus:      "x",
+			keys:       []string{"z", "b", "left", "esc"},
+			wantHeader: "⌂ › a › a1 › x",
+			wantHoist:  []HoistChangedMsg{{ID: "x", Path: []string{"a", "a1", "x"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createHoistTree()
+			_ = tree.ExpandAll(context.Background())
+			_, _ = tree.SetFocusedID(context.Background(), test.focus)
+			model := NewTuiTreeModel(tree, WithTuiDisableNavBar[string](true))
+
+			var hoists []HoistChangedMsg
+			for _, key := range test.keys {
+				for _, msg := range pressKey(model, key) {
+					if hoist, ok := msg.(HoistChangedMsg); ok {
+						hoists = append(hoists, hoist)
+					}
+				}
+			}
+			if diff := cmp.Diff(test.wantHoist, hoists); diff != "" {
+				t.Errorf("HoistChangedMsg mismatch (-want +got):\n%s", diff)
+			}
+
+			view := model.View()
+			header := ""
+			if model.HoistedNode() != nil {
+				header, _, _ = strings.Cut(view, "\n")
+			}
+			if header != test.wantHeader {
+				t.Errorf("header = %q, want %q", header, test.wantHeader)
+			}
+			if test.wantHeader == "" && strings.Contains(view, "⌂") {
+				t.Errorf("View() = %q, want no breadcrumbs", view)
+			}
+		})
+	}
+}
+
+func TestTuiHoist_Click(t *testing.T) {
+	tree := createHoistTree()
+	_ = tree.ExpandAll(context.Background())
+	_, _ = tree.SetFocusedID(context.Background(), "x")
+	model := NewTuiTreeModel(tree)
+	pressKey(model, "z")
+
+	height := model.viewport.Height
+	click := func(x, y int) {
+		_, _ = model.Update(tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	}
+	click(4, 1) // Below the header
+	click(5, 0) // Separator
+	if got := model.HoistedNode().ID(); got != "x" {
+		t.Fatalf("HoistedNode() = %s after clicks off the crumbs, want x", got)
+	}
+
+	click(4, 0) // "a" in "⌂ › a › a1 › x"
+	if got := model.HoistedNode().ID(); got != "a" {
+		t.Errorf("HoistedNode() = %s after clicking a, want a", got)
+	}
+	click(0, 0)
+	if got := model.HoistedNode(); got != nil {
+		t.Errorf("HoistedNode() = %s after clicking ⌂, want nil", got.ID())
+	}
+	if model.viewport.Height != height+2 {
+		t.Errorf("viewport height = %d, want %d once the header is gone", model.viewport.Height, height+2)
+	}
+}
@@ -0,0 +1,308 @@
+package treeview
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// A search index speeds up searching large trees (see WithSearchIndex). It
+// maps every trigram of a node's lower-cased ID, name and extracted text to
+// the nodes containing it, so a search only runs the matcher on nodes that
+// contain the term instead of on every node. Terms shorter than three runes
+// fall back to scanning the indexed text, which is still much cheaper than
+// calling the matcher.
+//
+// When a term extends the previous one (typing another character), the
+// candidates are narrowed from the previous candidates without touching the
+// index at all.
+//
+// The index is built on the first search and kept up to date by the tree's
+// mutation methods, including undo and redo. Changes made to nodes directly,
+// for example with Node.SetName, are not seen; call Tree.Reindex afterwards.
+
+// IndexTextFn extracts additional searchable text from a node, such as the
+// string form of its data. It may return "".
+type IndexTextFn[T any] func(node *Node[T]) string
+
+// searchIndex is a trigram index over the nodes of a tree. Its methods take
+// ix.mu; tree methods holding t.mu may call them, never the other way round.
+type searchIndex[T any] struct {
+	mu      sync.Mutex
+	text    IndexTextFn[T]
+	built   bool
+	version uint64 // Incremented by every change to the indexed nodes
+
+	docs  map[*Node[T]]string
+	grams map[string]map[*Node[T]]struct{}
+
+	// Candidates of the previous query, in tree order, for narrowing
+	last     []*Node[T]
+	lastTerm string
+	lastVer  uint64
+	hasLast  bool
+}
+
+// newSearchIndex returns an empty index that is built on first use.
+func newSearchIndex[T any](text IndexTextFn[T]) *searchIndex[T] {
+	return &searchIndex[T]{text: text}
+}
+
+// doc returns the lower-cased searchable text of node.
+func (ix *searchIndex[T]) doc(node *Node[T]) string {
+	parts := []string{node.ID(), node.Name()}
+	if ix.text != nil {
+		parts = append(parts, ix.text(node))
+	}
+	// The separator never occurs in a term, so trigrams don't span fields
+	return strings.ToLower(strings.Join(parts, "\x00"))
+}
+
+// trigrams returns the distinct three-rune substrings of s.
+func trigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 3 {
+		return nil
+	}
+	grams := make([]string, 0, len(runes)-2)
+	seen := make(map[string]struct{}, len(runes)-2)
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if _, ok := seen[gram]; !ok {
+			seen[gram] = struct{}{}
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
+
+// reset drops the index; it is rebuilt on the next query.
+func (ix *searchIndex[T]) reset() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.built = false
+	ix.docs = nil
+	ix.grams = nil
+	ix.version++
+}
+
+// build indexes every node below roots. Callers must hold ix.mu.
+func (ix *searchIndex[T]) build(ctx context.Context, roots []*Node[T]) error {
+	ix.docs = make(map[*Node[T]]string)
+	ix.grams = make(map[string]map[*Node[T]]struct{})
+	for info, err := range dfsSeq(ctx, roots, true, nil) {
+		if err != nil {
+			ix.docs, ix.grams = nil, nil
+			return err
+		}
+		ix.addLocked(info.Node)
+	}
+	ix.built = true
+	ix.version++
+	return nil
+}
+
+// addLocked (re)indexes node. Callers must hold ix.mu.
+func (ix *searchIndex[T]) addLocked(node *Node[T]) {
+	ix.removeLocked(node)
+	doc := ix.doc(node)
+	ix.docs[node] = doc
+	for _, gram := range trigrams(doc) {
+		postings := ix.grams[gram]
+		if postings == nil {
+			postings = make(map[*Node[T]]struct{})
+			ix.grams[gram] = postings
+		}
+		postings[node] = struct{}{}
+	}
+}
+
+// removeLocked drops node from the index. Callers must hold ix.mu.
+func (ix *searchIndex[T]) removeLocked(node *Node[T]) {
+	doc, ok := ix.docs[node]
+	if !ok {
+		return
+	}
+	delete(ix.docs, node)
+	for _, gram := range trigrams(doc) {
+		delete(ix.grams[gram], node)
+		if len(ix.grams[gram]) == 0 {
+			delete(ix.grams, gram)
+		}
+	}
+}
+
+// attach indexes the nodes of a subtree that isn't indexed yet. Nodes that
+// merely moved keep their entries, but the tree order changed.
+func (ix *searchIndex[T]) attach(node *Node[T]) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.version++
+	if !ix.built {
+		return
+	}
+	for info := range dfsSeq(context.Background(), []*Node[T]{node}, true, nil) {
+		if _, ok := ix.docs[info.Node]; !ok {
+			ix.addLocked(info.Node)
+		}
+	}
+}
+
+// detach drops the nodes of a removed subtree.
+func (ix *searchIndex[T]) detach(node *Node[T]) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.version++
+	if !ix.built {
+		return
+	}
+	for info := range dfsSeq(context.Background(), []*Node[T]{node}, true, nil) {
+		ix.removeLocked(info.Node)
+	}
+}
+
+// reordered records that the tree order changed.
+func (ix *searchIndex[T]) reordered() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.version++
+}
+
+// update reindexes a single node after its name or data changed.
+func (ix *searchIndex[T]) update(node *Node[T]) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.version++
+	if !ix.built {
+		return
+	}
+	if _, ok := ix.docs[node]; ok {
+		ix.addLocked(node)
+	}
+}
+
+// candidates returns, in tree order, the nodes below roots whose indexed text
+// contains term, ignoring case.
+func (ix *searchIndex[T]) candidates(ctx context.Context, roots []*Node[T], term string) ([]*Node[T], error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.built {
+		if err := ix.build(ctx, roots); err != nil {
+			return nil, err
+		}
+	}
+
+	term = strings.ToLower(term)
+	contains := func(node *Node[T]) bool {
+		return strings.Contains(ix.docs[node], term)
+	}
+
+	var result []*Node[T]
+	switch grams := trigrams(term); {
+	case ix.hasLast && ix.lastVer == ix.version && strings.Contains(term, ix.lastTerm):
+		// Whatever contains the new term also contained the previous one
+		result = make([]*Node[T], 0, len(ix.last))
+		for _, node := range ix.last {
+			if contains(node) {
+				result = append(result, node)
+			}
+		}
+
+	case len(grams) > 0:
+		set := ix.intersect(grams)
+		if len(set) == 0 {
+			break
+		}
+		result = make([]*Node[T], 0, len(set))
+		for info, err := range dfsSeq(ctx, roots, true, nil) {
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := set[info.Node]; ok && contains(info.Node) {
+				result = append(result, info.Node)
+				if len(result) == len(set) {
+					break
+				}
+			}
+		}
+
+	default:
+		for info, err := range dfsSeq(ctx, roots, true, nil) {
+			if err != nil {
+				return nil, err
+			}
+			if contains(info.Node) {
+				result = append(result, info.Node)
+			}
+		}
+	}
+
+	ix.last, ix.lastTerm, ix.lastVer, ix.hasLast = result, term, ix.version, true
+	return result, nil
+}
+
+// intersect returns the nodes listed under every gram. Callers must hold
+// ix.mu.
+func (ix *searchIndex[T]) intersect(grams []string) map[*Node[T]]struct{} {
+	postings := make([]map[*Node[T]]struct{}, 0, len(grams))
+	for _, gram := range grams {
+		p := ix.grams[gram]
+		if len(p) == 0 {
+			return nil
+		}
+		postings = append(postings, p)
+	}
+	// Start from the rarest gram to keep the working set small
+	slices.SortFunc(postings, func(a, b map[*Node[T]]struct{}) int {
+		return len(a) - len(b)
+	})
+
+	set := make(map[*Node[T]]struct{}, len(postings[0]))
+	for node := range postings[0] {
+		set[node] = struct{}{}
+	}
+	for _, p := range postings[1:] {
+		for node := range set {
+			if _, ok := p[node]; !ok {
+				delete(set, node)
+			}
+		}
+	}
+	return set
+}
+
+// Reindex rebuilds the search index, for example after nodes were changed
+// directly rather than through the tree's methods. It does nothing if the
+// tree has no index (see WithSearchIndex).
+func (t *Tree[T]) Reindex() {
+	if t.index != nil {
+		t.index.reset()
+	}
+}
+
+// indexAttach, indexDetach, indexUpdate and indexReordered keep the search index in sync
+// with a mutation. They do nothing if the tree has no index.
+func (t *Tree[T]) indexAttach(node *Node[T]) {
+	if t.index != nil {
+		t.index.attach(node)
+	}
+}
+
+func (t *Tree[T]) indexDetach(node *Node[T]) {
+	if t.index != nil {
+		t.index.detach(node)
+	}
+}
+
+func (t *Tree[T]) indexUpdate(node *Node[T]) {
+	if t.index != nil {
+		t.index.update(node)
+	}
+}
+
+func (t *Tree[T]) indexReordered() {
+	if t.index != nil {
+		t.index.reordered()
+	}
+}
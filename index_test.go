@@ -0,0 +1,260 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// createIndexedTree returns two copies of the same tree, one with a search
+// index over the node data and one without.
+func createIndexedTree() (indexed, plain *Tree[string]) {
+	build := func(opts ...Option[string]) *Tree[string] {
+		var roots []*Node[string]
+		for i := range 5 {
+			root := NewNode(fmt.Sprintf("dir%d", i), fmt.Sprintf("Folder %d", i), fmt.Sprintf("owner-%d", i%2))
+			for j := range 4 {
+				root.AddChild(NewNode(fmt.Sprintf("dir%d/f%d", i, j), fmt.Sprintf("report_%d%d.csv", i, j), "payload"))
+			}
+			roots = append(roots, root)
+		}
+		return NewTree(roots, opts...)
+	}
+	text := func(node *Node[string]) string { return *node.Data() }
+	return build(WithSearchIndex(text)), build()
+}
+
+// searchIDs runs Search and returns the IDs of the results.
+func searchIDs(t *testing.T, tree *Tree[string], term string) []string {
+	t.Helper()
+	nodes, err := tree.Search(context.Background(), term)
+	if err != nil {
+		t.Fatalf("Search(%q) error = %v", term, err)
+	}
+	return nodeIDs(nodes)
+}
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{name: "short", in: "ab", want: nil},
+		{name: "distinct_in_order", in: "abcd", want: []string{"abc", "bcd"}},
+		{name: "repeats_dropped", in: "aaaaa", want: []string{"aaa"}},
+		{name: "runes", in: "äöüäöü", want: []string{"äöü", "öüä", "üäö"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, trigrams(test.in)); diff != "" {
+				t.Errorf("trigrams(%q) mismatch (-want +got):\n%s", test.in, diff)
+			}
+		})
+	}
+}
+
+func TestSearchIndex_SameResults(t *testing.T) {
+	indexed, plain := createIndexedTree()
+	// Extending terms exercise narrowing, shorter ones the fallback scan
+	for _, term := range []string{"r", "re", "rep", "repo", "report_2", "REPORT_23", "zz", "folder", "owner-1", "dir3/", "payload", "csv"} {
+		if diff := cmp.Diff(searchIDs(t, plain, term), searchIDs(t, indexed, term)); diff != "" {
+			t.Errorf("Search(%q) mismatch (-unindexed +indexed):\n%s", term, diff)
+		}
+	}
+}
+
+// label is node data with a String method, as the default searcher reads it.
+type label string
+
+func (l label) String() string { return "label:" + string(l) }
+
+func TestSearchIndex_DefaultText(t *testing.T) {
+	build := func(opts ...Option[any]) *Tree[any] {
+		roots := []*Node[any]{
+			NewNode[any]("a", "alpha", "needle in the data"),
+			NewNode[any]("b", "beta", label("haystack")),
+			NewNode[any]("c", "gamma", 42),
+		}
+		return NewTree(roots, opts...)
+	}
+	indexed, plain := build(WithSearchIndex[any](nil)), build()
+	ids := func(tree *Tree[any], term string) []string {
+		nodes, err := tree.Search(context.Background(), term)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", term, err)
+		}
+		return nodeIDs(nodes)
+	}
+
+	// Without a text function the index still covers the data the default
+	// searcher checks, so enabling it doesn't change the results
+	for _, term := range []string{"needle", "haystack", "label:hay", "alpha", "gamma", "42", "zz"} {
+		want, got := ids(plain, term), ids(indexed, term)
+		if diff := cmp.Diff(want, got, cmpEmpty); diff != "" {
+			t.Errorf("Search(%q) mismatch (-unindexed +indexed):\n%s", term, diff)
+		}
+	}
+	if got := ids(indexed, "needle"); len(got) != 1 {
+		t.Errorf("Search(needle) = %v with the index, want [a]", got)
+	}
+}
+
+func TestSearchIndex_Mutations(t *testing.T) {
+	ctx := context.Background()
+	tree, _ := createIndexedTree()
+	if got := searchIDs(t, tree, "needle"); len(got) != 0 {
+		t.Fatalf("Search(needle) = %v before mutations, want none", got)
+	}
+
+	steps := []struct {
+		name   string
+		mutate func() error
+		want   []string
+	}{
+		{
+			name:   "insert",
+			mutate: func() error { return tree.InsertNode(ctx, "dir1", 0, NewNode("new", "needle.txt", "")) },
+			want:   []string{"new"},
+		},
+		{
+			name:   "rename",
+			mutate: func() error { return tree.Rename(ctx, "dir0/f1", "needle.csv") },
+			want:   []string{"dir0/f1", "new"},
+		},
+		{
+			name:   "set_data",
+			mutate: func() error { return tree.SetNodeData(ctx, "dir4", "needle owner") },
+			want:   []string{"dir0/f1", "new", "dir4"},
+		},
+		{
+			name:   "move_changes_order",
+			mutate: func() error { return tree.MoveNode(ctx, "new", "", 0) },
+			want:   []string{"new", "dir0/f1", "dir4"},
+		},
+		{
+			name: "remove",
+			mutate: func() error {
+				_, err := tree.RemoveNode(ctx, "dir0")
+				return err
+			},
+			want: []string{"new", "dir4"},
+		},
+		{
+			name: "undo_remove",
+			mutate: func() error {
+				_, err := tree.Undo(ctx)
+				return err
+			},
+			want: []string{"new", "dir0/f1", "dir4"},
+		},
+		{
+			name: "undo_set_data",
+			mutate: func() error {
+				if _, err := tree.Undo(ctx); err != nil { // Move
+					return err
+				}
+				_, err := tree.Undo(ctx)
+				return err
+			},
+			want: []string{"dir0/f1", "new"},
+		},
+		{
+			name: "direct_change_and_reindex",
+			mutate: func() error {
+				node, err := tree.FindByID(ctx, "dir2/f0")
+				if err != nil {
+					return err
+				}
+				node.SetName("needle")
+				tree.Reindex()
+				return nil
+			},
+			want: []string{"dir0/f1", "new", "dir2/f0"},
+		},
+	}
+
+	for _, step := range steps {
+		if err := step.mutate(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		if diff := cmp.Diff(step.want, searchIDs(t, tree, "needle")); diff != "" {
+			t.Errorf("%s: Search(needle) mismatch (-want +got):\n%s", step.name, diff)
+		}
+	}
+}
+
+func TestSearchIndex_Narrowing(t *testing.T) {
+	tree, _ := createIndexedTree()
+	if got := searchIDs(t, tree, "report_1"); len(got) != 4 {
+		t.Fatalf("Search(report_1) = %v, want 4 results", got)
+	}
+
+	// An extended term is only checked against the previous candidates
+	tree.index.last = tree.index.last[:1]
+	if diff := cmp.Diff([]string{"dir1/f0"}, searchIDs(t, tree, "report_1")); diff != "" {
+		t.Errorf("Search() with narrowed candidates mismatch (-want +got):\n%s", diff)
+	}
+
+	// A term that doesn't extend the previous one consults the index again
+	if got := searchIDs(t, tree, "report_"); len(got) != 20 {
+		t.Errorf("Search(report_) returned %d results, want 20", len(got))
+	}
+}
+
+func TestSearchIndex_OtherMatchersScanEverything(t *testing.T) {
+	tree, _ := createIndexedTree()
+	// A fuzzy match of "rpt" isn't a substring, so the index must not be used
+	results, err := tree.searchMatches(context.Background(), "rpt", FuzzyMatcher[string]())
+	if err != nil {
+		t.Fatalf("searchMatches() error = %v", err)
+	}
+	if len(results) != 20 {
+		t.Errorf("searchMatches() returned %d results, want 20", len(results))
+	}
+}
+
+func TestSearchIndex_ConfiguredMatchers(t *testing.T) {
+	tests := []struct {
+		name      string
+		opt       Option[string]
+		wantIndex bool
+		wantIDs   []string
+	}{
+		{
+			name:    "fuzzy",
+			opt:     WithFuzzySearch[string](),
+			wantIDs: []string{"foo_bar"},
+		},
+		{
+			name: "custom_matcher",
+			opt: WithMatcher(func(_ context.Context, node *Node[string], term string) (Match, bool) {
+				return Match{}, node.ID() == "foo_bar"
+			}),
+			wantIDs: []string{"foo_bar"},
+		},
+		{
+			name: "indexed_matcher",
+			opt: WithIndexedMatcher(func(ctx context.Context, node *Node[string], term string) (Match, bool) {
+				return defaultMatchFn(ctx, node, term)
+			}),
+			wantIndex: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			roots := []*Node[string]{NewNode("foo_bar", "foo_bar", ""), NewNode("baz", "baz", "")}
+			tree := NewTree(roots, WithSearchIndex[string](nil), test.opt)
+			if got := tree.index != nil; got != test.wantIndex {
+				t.Errorf("indexed = %v, want %v", got, test.wantIndex)
+			}
+			if diff := cmp.Diff(test.wantIDs, searchIDs(t, tree, "fbr"), cmpEmpty); diff != "" {
+				t.Errorf("Search(fbr) mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
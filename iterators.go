@@ -3,6 +3,7 @@ package treeview
 import (
 	"context"
 	"iter"
+	"sync"
 )
 
 // NodeInfo is returned by iters and contains metadata about a node during iteration.
@@ -12,28 +13,53 @@ type NodeInfo[T any] struct {
 	IsLast bool
 }
 
+// The tree iterators read node state under the tree's read lock, one step at
+// a time, and release it before every yield. The loop body may therefore call
+// any Tree method, including ones that change the tree; the iteration then
+// sees each node as it is when reached.
+
 // All returns an iterator that yields to every node using depth-first traversal.
 // Context errors are returned unwrapped.
 func (t *Tree[T]) All(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
-	return dfsSeq(ctx, t.Nodes(), true)
+	return dfsSeq(ctx, t.Nodes(), true, &t.mu)
 }
 
 // All (From Node) - Iterate from specific Node using depth-first traversal.
-// Context errors are returned unwrapped.
+// The node does not know its tree, so this takes no lock; use Tree.All while
+// other goroutines may change the tree. Context errors are returned unwrapped.
 func (n *Node[T]) All(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
-	return dfsSeq(ctx, []*Node[T]{n}, true)
+	return dfsSeq(ctx, []*Node[T]{n}, true, nil)
 }
 
 // AllVisible returns an iterator over visible nodes using depth-first traversal.
+// While a filter is active (see Tree.Filter) it yields the filtered view instead.
 // Context errors are returned unwrapped.
 func (t *Tree[T]) AllVisible(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
+	return t.visibleSeq(ctx, &t.mu)
+}
+
+// visibleSeq is AllVisible reading under guard; a nil guard is for callers
+// that hold t.mu.
+func (t *Tree[T]) visibleSeq(ctx context.Context, guard *sync.RWMutex) iter.Seq2[NodeInfo[T], error] {
+	readLock(guard)
+	roots, view := t.nodes, t.filter
+	if t.hoist != nil {
+		roots = []*Node[T]{t.hoist}
+	}
+	readUnlock(guard)
+	if view != nil {
+		return filterSeq(ctx, roots, view, guard)
+	}
 	return func(yield func(NodeInfo[T], error) bool) {
-		for info, err := range dfsSeq(ctx, t.Nodes(), false) {
+		for info, err := range dfsSeq(ctx, roots, false, guard) {
 			if err != nil {
 				yield(NodeInfo[T]{}, err)
 				return
 			}
-			if info.Node.IsVisible() {
+			readLock(guard)
+			visible := info.Node.IsVisible()
+			readUnlock(guard)
+			if visible {
 				if !yield(info, nil) {
 					return
 				}
@@ -60,20 +86,65 @@ func (t *Tree[T]) AllFocused(ctx context.Context) iter.Seq2[NodeInfo[T], error]
 	}
 }
 
+// AllSelected returns an iterator over all selected nodes in the tree.
+// Context errors are returned unwrapped.
+func (t *Tree[T]) AllSelected(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
+	return func(yield func(NodeInfo[T], error) bool) {
+		for info, err := range t.All(ctx) {
+			if err != nil {
+				yield(NodeInfo[T]{}, err)
+				return
+			}
+			if t.IsSelected(info.Node.ID()) {
+				if !yield(info, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // AllBottomUp returns an iterator that yields nodes in bottom-up order (leaves first, then parents).
 // Context errors are returned unwrapped.
 func (t *Tree[T]) AllBottomUp(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
-	return bottomUpSeq(ctx, t.Nodes(), true)
+	return bottomUpSeq(ctx, t.Nodes(), true, &t.mu)
 }
 
 // BreadthFirst returns an iterator over nodes using breadth first traversal.
 // Context errors are returned unwrapped.
 func (t *Tree[T]) BreadthFirst(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
-	return bfsSeq(ctx, t.Nodes(), true)
+	return bfsSeq(ctx, t.Nodes(), true, &t.mu)
+}
+
+// readLock read-locks guard unless it is nil.
+func readLock(guard *sync.RWMutex) {
+	if guard != nil {
+		guard.RLock()
+	}
+}
+
+// readUnlock undoes readLock.
+func readUnlock(guard *sync.RWMutex) {
+	if guard != nil {
+		guard.RUnlock()
+	}
+}
+
+// childrenToVisit returns the children a traversal descends into from node,
+// read under guard.
+func childrenToVisit[T any](node *Node[T], followUnexpanded bool, guard *sync.RWMutex) []*Node[T] {
+	readLock(guard)
+	defer readUnlock(guard)
+	if node.HasChildren() && (followUnexpanded || node.IsExpanded()) {
+		return node.children
+	}
+	return nil
 }
 
 // dfsSeq produces a depth-first iterator that yields NodeInfo values and context errors.
-func dfsSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool) iter.Seq2[NodeInfo[T], error] {
+// Node state is read under guard, which is nil when the caller holds the
+// tree lock or owns the nodes.
+func dfsSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool, guard *sync.RWMutex) iter.Seq2[NodeInfo[T], error] {
 	return func(yield func(NodeInfo[T], error) bool) {
 		// Push roots in reverse order so we pop left-to-right.
 		stack := make([]NodeInfo[T], 0, len(roots))
@@ -95,18 +166,17 @@ func dfsSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool)
 				return
 			}
 
-			if f.Node.HasChildren() && (followUnexpanded || f.Node.IsExpanded()) {
-				children := f.Node.Children()
-				for i := len(children) - 1; i >= 0; i-- {
-					stack = append(stack, NodeInfo[T]{Node: children[i], Depth: f.Depth + 1, IsLast: i == len(children)-1})
-				}
+			children := childrenToVisit(f.Node, followUnexpanded, guard)
+			for i := len(children) - 1; i >= 0; i-- {
+				stack = append(stack, NodeInfo[T]{Node: children[i], Depth: f.Depth + 1, IsLast: i == len(children)-1})
 			}
 		}
 	}
 }
 
 // bfsSeq produces a breadth-first iterator that yields NodeInfo values and context errors.
-func bfsSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool) iter.Seq2[NodeInfo[T], error] {
+// Node state is read under guard as in dfsSeq.
+func bfsSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool, guard *sync.RWMutex) iter.Seq2[NodeInfo[T], error] {
 	return func(yield func(NodeInfo[T], error) bool) {
 		queue := make([]NodeInfo[T], 0, len(roots))
 		for i, n := range roots {
@@ -126,19 +196,18 @@ func bfsSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool)
 				return
 			}
 
-			if cur.Node.HasChildren() && (followUnexpanded || cur.Node.IsExpanded()) {
-				children := cur.Node.Children()
-				for i, child := range children {
-					queue = append(queue, NodeInfo[T]{Node: child, Depth: cur.Depth + 1, IsLast: i == len(children)-1})
-				}
+			children := childrenToVisit(cur.Node, followUnexpanded, guard)
+			for i, child := range children {
+				queue = append(queue, NodeInfo[T]{Node: child, Depth: cur.Depth + 1, IsLast: i == len(children)-1})
 			}
 		}
 	}
 }
 
 // bottomUpSeq produces a bottom-up iterator that yields NodeInfo values and context errors.
-// It uses post-order traversal to visit children before their parents.
-func bottomUpSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool) iter.Seq2[NodeInfo[T], error] {
+// It uses post-order traversal to visit children before their parents. Node
+// state is read under guard as in dfsSeq.
+func bottomUpSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded bool, guard *sync.RWMutex) iter.Seq2[NodeInfo[T], error] {
 	return func(yield func(NodeInfo[T], error) bool) {
 		// Collect all nodes in post-order using a stack-based approach
 		var visitPostOrder func(*Node[T], int, bool) bool
@@ -150,12 +219,10 @@ func bottomUpSeq[T any](ctx context.Context, roots []*Node[T], followUnexpanded
 			}
 
 			// First visit children if they exist and should be followed
-			if node.HasChildren() && (followUnexpanded || node.IsExpanded()) {
-				children := node.Children()
-				for i, child := range children {
-					if !visitPostOrder(child, depth+1, i == len(children)-1) {
-						return false
-					}
+			children := childrenToVisit(node, followUnexpanded, guard)
+			for i, child := range children {
+				if !visitPostOrder(child, depth+1, i == len(children)-1) {
+					return false
 				}
 			}
 
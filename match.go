@@ -0,0 +1,192 @@
+package treeview
+
+import (
+	"context"
+	"unicode"
+)
+
+// MatchRange is a half-open range [Start, End) of rune offsets into a node's
+// Name that a search term matched.
+type MatchRange struct {
+	Start int
+	End   int
+}
+
+// Match describes how a node matched a search term. Higher scores are better
+// matches. Ranges index into the node's Name and drive highlighting; they may
+// be empty when the term matched another field such as the ID. Lines holds
+// the matching lines of a file found by ContentMatcher.
+type Match struct {
+	Score  int
+	Ranges []MatchRange
+	Lines  []ContentMatch
+}
+
+// SearchResult pairs a matching node with its match details.
+type SearchResult[T any] struct {
+	Node  *Node[T]
+	Match Match
+}
+
+// Scoring constants for FuzzyMatch. They follow the spirit of fzf: every
+// matched rune earns points, matches at word boundaries and runs of adjacent
+// matches earn bonuses, and gaps between matched runes cost points.
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusBoundary    = 8
+	fuzzyBonusCamel       = 7
+	fuzzyBonusConsecutive = 8
+	fuzzyBonusFirstRune   = 2 // Multiplier for a boundary bonus on the first pattern rune
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+	fuzzyDelimiters       = "/_-. \\"
+)
+
+// FuzzyMatch reports whether all runes of pattern appear in text in order and
+// scores the match. Matching uses smart case: it is case-insensitive unless
+// pattern contains an upper-case rune. When the pattern fits several ways the
+// best scoring one wins, so "fb" in "foo/bar/fb" matches the trailing "fb".
+func FuzzyMatch(pattern, text string) (Match, bool) {
+	if pattern == "" {
+		return Match{}, false
+	}
+	p := []rune(pattern)
+	t := []rune(text)
+
+	caseSensitive := false
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	eq := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	// Try every position where the first pattern rune occurs and keep the
+	// best scoring alignment. Labels are short, so the quadratic worst case
+	// doesn't matter in practice.
+	var best Match
+	found := false
+	for start := range t {
+		if !eq(t[start], p[0]) {
+			continue
+		}
+		m, ok := fuzzyAlign(p, t, start, eq)
+		if !ok {
+			break // No later start can complete the pattern either
+		}
+		if !found || m.Score > best.Score {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+// fuzzyAlign greedily matches p against t from position start and scores the
+// result. It returns false if the pattern can't be completed.
+func fuzzyAlign(p, t []rune, start int, eq func(a, b rune) bool) (Match, bool) {
+	var m Match
+	pi, prev := 0, -1
+	for ti := start; ti < len(t) && pi < len(p); ti++ {
+		if !eq(t[ti], p[pi]) {
+			continue
+		}
+
+		score := fuzzyScoreMatch
+		bonus := fuzzyBoundaryBonus(t, ti)
+		if pi == 0 {
+			bonus *= fuzzyBonusFirstRune
+		}
+		score += bonus
+		if prev >= 0 {
+			if gap := ti - prev - 1; gap == 0 {
+				score += fuzzyBonusConsecutive
+			} else {
+				score -= fuzzyPenaltyGapStart + (gap-1)*fuzzyPenaltyGapExtend
+			}
+		}
+		m.Score += score
+
+		if n := len(m.Ranges); n > 0 && m.Ranges[n-1].End == ti {
+			m.Ranges[n-1].End = ti + 1
+		} else {
+			m.Ranges = append(m.Ranges, MatchRange{Start: ti, End: ti + 1})
+		}
+		prev = ti
+		pi++
+	}
+	return m, pi == len(p)
+}
+
+// fuzzyBoundaryBonus returns the bonus for a match at position i of text:
+// the start of the text, the rune after a delimiter, or a camelCase hump.
+func fuzzyBoundaryBonus(text []rune, i int) int {
+	if i == 0 {
+		return fuzzyBonusBoundary
+	}
+	prev, cur := text[i-1], text[i]
+	for _, d := range fuzzyDelimiters {
+		if prev == d {
+			return fuzzyBonusBoundary
+		}
+	}
+	if unicode.IsLower(prev) && unicode.IsUpper(cur) {
+		return fuzzyBonusCamel
+	}
+	return 0
+}
+
+// FuzzyMatcher returns a MatchFn that fuzzy matches the term against the
+// node's Name and, failing that, its ID. Matches on the ID score lower and
+// carry no highlight ranges.
+func FuzzyMatcher[T any]() MatchFn[T] {
+	return func(_ context.Context, node *Node[T], term string) (Match, bool) {
+		if m, ok := FuzzyMatch(term, node.Name()); ok {
+			return m, true
+		}
+		if m, ok := FuzzyMatch(term, node.ID()); ok {
+			return Match{Score: m.Score / 2}, true
+		}
+		return Match{}, false
+	}
+}
+
+// substringRanges returns every non-overlapping case-insensitive occurrence
+// of term in text as rune ranges.
+func substringRanges(text, term string) []MatchRange {
+	t := []rune(text)
+	p := []rune(term)
+	if len(p) == 0 {
+		return nil
+	}
+
+	var ranges []MatchRange
+	for i := 0; i+len(p) <= len(t); {
+		j := 0
+		for j < len(p) && unicode.ToLower(t[i+j]) == unicode.ToLower(p[j]) {
+			j++
+		}
+		if j == len(p) {
+			ranges = append(ranges, MatchRange{Start: i, End: i + len(p)})
+			i += len(p)
+			continue
+		}
+		i++
+	}
+	return ranges
+}
+
+// defaultMatchFn wraps defaultSearchFn and adds highlight ranges for the
+// node's Name. Substring matches are not ranked, so all scores are zero and
+// results keep their tree order.
+func defaultMatchFn[T any](ctx context.Context, node *Node[T], term string) (Match, bool) {
+	if !defaultSearchFn(ctx, node, term) {
+		return Match{}, false
+	}
+	return Match{Ranges: substringRanges(node.Name(), term)}, true
+}
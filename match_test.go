@@ -0,0 +1,154 @@
+package treeview
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		text       string
+		wantOK     bool
+		wantRanges []MatchRange
+	}{
+		{name: "empty_pattern", pattern: "", text: "main.go", wantOK: false},
+		{name: "no_match", pattern: "xyz", text: "main.go", wantOK: false},
+		{name: "out_of_order", pattern: "og", text: "go", wantOK: false},
+		{
+			name:       "prefix",
+			pattern:    "mai",
+			text:       "main.go",
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 0, End: 3}},
+		},
+		{
+			name:       "scattered",
+			pattern:    "mgo",
+			text:       "main.go",
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 0, End: 1}, {Start: 5, End: 7}},
+		},
+		{
+			name:       "smart_case_insensitive",
+			pattern:    "readme",
+			text:       "README.md",
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 0, End: 6}},
+		},
+		{name: "smart_case_sensitive", pattern: "Readme", text: "README.md", wantOK: false},
+		{
+			name:       "best_alignment",
+			pattern:    "fb",
+			text:       "foo/bar/fb",
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 8, End: 10}},
+		},
+		{
+			name:       "unicode",
+			pattern:    "äö",
+			text:       "xäyö",
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 1, End: 2}, {Start: 3, End: 4}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := FuzzyMatch(test.pattern, test.text)
+			if ok != test.wantOK {
+				t.Fatalf("FuzzyMatch(%q, %q) ok = %v, want %v", test.pattern, test.text, ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.wantRanges, got.Ranges); diff != "" {
+				t.Errorf("FuzzyMatch(%q, %q) ranges mismatch (-want +got):\n%s", test.pattern, test.text, diff)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatch_Scoring(t *testing.T) {
+	tests := []struct {
+		name   string
+		better string
+		worse  string
+		term   string
+	}{
+		{name: "consecutive_beats_scattered", term: "tree", better: "treeview", worse: "t_r_e_e"},
+		{name: "boundary_beats_middle", term: "view", better: "tree_view", worse: "treeview"},
+		{name: "camel_case_beats_middle", term: "view", better: "treeView", worse: "treeview"},
+		{name: "short_gap_beats_long_gap", term: "ab", better: "a_b", worse: "axxxb"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			better, _ := FuzzyMatch(test.term, test.better)
+			worse, _ := FuzzyMatch(test.term, test.worse)
+			if better.Score <= worse.Score {
+				t.Errorf("score(%q) = %d, want more than score(%q) = %d",
+					test.better, better.Score, test.worse, worse.Score)
+			}
+		})
+	}
+}
+
+func TestSubstringRanges(t *testing.T) {
+	got := substringRanges("Node aNODE node", "node")
+	want := []MatchRange{{Start: 0, End: 4}, {Start: 6, End: 10}, {Start: 11, End: 15}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("substringRanges() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTree_SearchMatches_FuzzyOrdering(t *testing.T) {
+	ctx := context.Background()
+	nodes := []*Node[string]{
+		NewNode("a", "t_r_e_e", ""),
+		NewNode("b", "unrelated", ""),
+		NewNode("c", "treeview", ""),
+	}
+	tree := NewTree(nodes, WithFuzzySearch[string]())
+
+	results, err := tree.Search(ctx, "tree")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	want := []string{"c", "a"}
+	if diff := cmp.Diff(want, nodeIDs(results)); diff != "" {
+		t.Errorf("Search() order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTree_Search_PlainSearcherKeepsTreeOrder(t *testing.T) {
+	ctx := context.Background()
+	nodes := []*Node[string]{NewNode("b", "beta", ""), NewNode("a", "alpha", "")}
+	tree := NewTree(nodes, WithSearcher(func(context.Context, *Node[string], string) bool { return true }))
+
+	results, err := tree.SearchMatches(ctx, "x")
+	if err != nil {
+		t.Fatalf("SearchMatches() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Node.ID() != "b" || len(results[0].Match.Ranges) != 0 {
+		t.Errorf("SearchMatches() = %+v, want both nodes in tree order without ranges", results)
+	}
+}
+
+func TestTree_SearchAndExpand_RecordsRanges(t *testing.T) {
+	ctx := context.Background()
+	tree := NewTree([]*Node[string]{NewNode("1", "main.go", "")})
+
+	if _, err := tree.SearchAndExpand(ctx, "go"); err != nil {
+		t.Fatalf("SearchAndExpand() error = %v", err)
+	}
+	want := []MatchRange{{Start: 5, End: 7}}
+	if diff := cmp.Diff(want, tree.matchRanges("1")); diff != "" {
+		t.Errorf("matchRanges() mismatch (-want +got):\n%s", diff)
+	}
+
+	tree.ClearSearch()
+	if got := tree.matchRanges("1"); got != nil {
+		t.Errorf("matchRanges() after ClearSearch = %v, want nil", got)
+	}
+}
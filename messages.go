@@ -0,0 +1,261 @@
+package treeview
+
+import (
+	"context"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The messages below are produced by TuiTreeModel.Update as tea.Cmd results
+// whenever a key press, mouse click or search step changes the state of the
+// tree. Parent models can switch on them to update side panels or trigger
+// loads without polling the tree on every render.
+
+// FocusChangedMsg is sent when the primary focused node or the set of focused
+// nodes changes.
+type FocusChangedMsg struct {
+	// ID is the newly focused node, or "" if focus was cleared.
+	ID string
+	// PreviousID is the node that held the focus before the change.
+	PreviousID string
+	// IDs holds every focused node, primary first, when multi-focus is used.
+	IDs []string
+}
+
+// NodeExpandedMsg is sent when one or more nodes with children were expanded.
+type NodeExpandedMsg struct {
+	IDs []string
+}
+
+// NodeCollapsedMsg is sent when one or more nodes with children were collapsed.
+type NodeCollapsedMsg struct {
+	IDs []string
+}
+
+// SelectionChangedMsg is sent when the checkbox selection changes (see
+// Tree.SetSelected and WithCheckboxes). It is never sent for focus changes:
+// moving or extending the focus, including the multi-focus, is reported by
+// FocusChangedMsg, and the selection stays as it is. IDs holds every selected
+// node in tree order.
+type SelectionChangedMsg struct {
+	IDs []string
+}
+
+// SearchChangedMsg is sent when a changed search term or mode has been
+// evaluated, and again whenever a search continuing in the background finds
+// more matches or finishes. MatchIDs holds the IDs of all matching nodes,
+// best matches first when the matcher scores them and in tree order
+// otherwise. Searching is true while the search continues; the message sent
+// when it finishes has Searching false, and Partial true if it was stopped
+// by the search limit (see WithTuiSearchLimit).
+type SearchChangedMsg struct {
+	Term      string
+	Mode      SearchMode
+	MatchIDs  []string
+	Searching bool
+	Partial   bool
+}
+
+// NodeActivatedMsg is sent when the user activates the focused node with one
+// of the KeyMap.Activate keys.
+type NodeActivatedMsg struct {
+	ID string
+}
+
+// NodeRenamedMsg is sent after the user renamed a node inline.
+type NodeRenamedMsg struct {
+	ID      string
+	OldName string
+	NewName string
+}
+
+// NodeMovedMsg is sent after nodes were reordered, indented or outdented from
+// the keyboard.
+type NodeMovedMsg struct {
+	IDs []string
+}
+
+// NodesDeletedMsg is sent after the user confirmed a delete (see
+// WithTuiDelete). IDs holds the nodes that were deleted and removed from the
+// tree. Err is the error that stopped the delete early, if any; the nodes
+// after the failing one were not deleted.
+type NodesDeletedMsg struct {
+	IDs []string
+	Err error
+}
+
+// HoistChangedMsg is sent when a different node was hoisted or the whole
+// tree is shown again. ID is the hoisted node, or "" for the whole tree. Path
+// holds the IDs of the breadcrumb trail, from the root down to ID.
+type HoistChangedMsg struct {
+	ID   string
+	Path []string
+}
+
+// FileSystemChangedMsg is sent by a FileSystemWatcher after it applied changes
+// on disk to the tree. The lists hold the IDs (paths) of the nodes that were
+// added, removed, or given fresh FileInfo. Err reports a directory that could
+// not be read; the other changes were still applied. When batches are merged
+// an ID can show up in more than one list, in the order things happened.
+type FileSystemChangedMsg struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+	Err      error
+}
+
+// msgCmd wraps a message in a command so it can be returned from Update.
+func msgCmd(msg tea.Msg) tea.Cmd {
+	return func() tea.Msg { return msg }
+}
+
+// nodeIDs returns the IDs of the given nodes in order.
+func nodeIDs[T any](nodes []*Node[T]) []string {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID()
+	}
+	return ids
+}
+
+// tuiSnapshot records the parts of the model state that change messages are
+// derived from. It is taken before a message is handled and compared with
+// the state afterwards.
+type tuiSnapshot[T any] struct {
+	focusedIDs       []string
+	selectionVersion uint64
+	searchTerm       string
+	searchMode       SearchMode
+	matchIDs         []string
+	searching        bool
+	hoisted          *Node[T]
+}
+
+// snapshot captures the current focus, the selection version, the search and
+// the hoisted node. Expansion changes are compared with expansionFlags, which
+// is brought up to date here so changes made since the last message, by
+// other callers of the tree, aren't reported as this one's.
+func (m *TuiTreeModel[T]) snapshot() tuiSnapshot[T] {
+	if m.expansionFlags == nil || m.expansionVersionNow() != m.expansionVersion {
+		m.expansionChanges()
+	}
+	return tuiSnapshot[T]{
+		focusedIDs:       m.GetAllFocusedIDs(),
+		selectionVersion: m.selectionVersionNow(),
+		searchTerm:       m.searchedTerm,
+		searchMode:       m.searchMode,
+		matchIDs:         nodeIDs(m.searchMatches),
+		searching:        m.searching,
+		hoisted:          m.HoistedNode(),
+	}
+}
+
+// changeCmds compares the current state with before and returns a command
+// emitting one message per kind of change, or nil if nothing changed.
+func (m *TuiTreeModel[T]) changeCmds(before tuiSnapshot[T]) tea.Cmd {
+	var cmds []tea.Cmd
+
+	// Focus and multi-focus changes
+	after := m.GetAllFocusedIDs()
+	var prevID, curID string
+	if len(before.focusedIDs) > 0 {
+		prevID = before.focusedIDs[0]
+	}
+	if len(after) > 0 {
+		curID = after[0]
+	}
+	multi := len(after) > 1 || len(before.focusedIDs) > 1
+	if prevID != curID || (multi && !sameIDSet(before.focusedIDs, after)) {
+		focusMsg := FocusChangedMsg{ID: curID, PreviousID: prevID}
+		if multi {
+			focusMsg.IDs = after
+		}
+		cmds = append(cmds, msgCmd(focusMsg))
+	}
+
+	// Checkbox selection changes
+	if m.selectionVersionNow() != before.selectionVersion {
+		cmds = append(cmds, msgCmd(SelectionChangedMsg{IDs: m.SelectedIDs()}))
+	}
+
+	// Expansion changes, however they were made
+	if m.expansionVersionNow() != m.expansionVersion {
+		expandedIDs, collapsedIDs := m.expansionChanges()
+		if len(expandedIDs) > 0 {
+			cmds = append(cmds, msgCmd(NodeExpandedMsg{IDs: expandedIDs}))
+		}
+		if len(collapsedIDs) > 0 {
+			cmds = append(cmds, msgCmd(NodeCollapsedMsg{IDs: collapsedIDs}))
+		}
+	}
+
+	// Search changes, reported once the term has been evaluated and
+	// whenever a background search finds more or finishes
+	matchIDs := nodeIDs(m.searchMatches)
+	if m.searchedTerm != before.searchTerm || m.searchMode != before.searchMode ||
+		!slices.Equal(matchIDs, before.matchIDs) || m.searching != before.searching {
+		cmds = append(cmds, msgCmd(SearchChangedMsg{
+			Term:      m.searchedTerm,
+			Mode:      m.searchMode,
+			MatchIDs:  matchIDs,
+			Searching: m.searching,
+			Partial:   m.searchPartial,
+		}))
+	}
+
+	// Hoisting and unhoisting
+	if hoisted := m.HoistedNode(); hoisted != before.hoisted {
+		hoistMsg := HoistChangedMsg{Path: nodeIDs(m.HoistPath())}
+		if hoisted != nil {
+			hoistMsg.ID = hoisted.ID()
+		}
+		cmds = append(cmds, msgCmd(hoistMsg))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// expansionChanges compares the expanded flag of every node with children
+// with expansionFlags and records the current flags there. It returns the
+// IDs of the nodes that were expanded and collapsed, in tree order; nodes
+// that weren't recorded, such as ones inserted since, are left out.
+func (m *TuiTreeModel[T]) expansionChanges() (expanded, collapsed []string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state := make(map[*Node[T]]bool, len(m.expansionFlags))
+	for info := range dfsSeq(context.Background(), m.nodes, true, nil) {
+		node := info.Node
+		if !node.HasChildren() {
+			continue
+		}
+		now := node.IsExpanded()
+		state[node] = now
+		switch was, ok := m.expansionFlags[node]; {
+		case !ok || was == now:
+		case now:
+			expanded = append(expanded, node.ID())
+		default:
+			collapsed = append(collapsed, node.ID())
+		}
+	}
+	m.expansionFlags, m.expansionVersion = state, m.Tree.expansionVersion
+	return expanded, collapsed
+}
+
+// sameIDSet reports whether a and b contain the same IDs, ignoring order.
+func sameIDSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	for _, id := range b {
+		if !set[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,293 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// MoveNode detaches the node with the given ID and re-inserts it under the
+// node with parentID at position index among its new siblings. An empty
+// parentID moves the node to the root level. An index outside the range of
+// the new sibling list appends the node at the end.
+//
+// The move is rejected with ErrInvalidMove if the node would become its own
+// descendant or if the tree's move policy (see WithMovePolicy) refuses it.
+// Returns ErrNodeNotFound if either ID doesn't exist, or context errors
+// unwrapped.
+func (t *Tree[T]) MoveNode(ctx context.Context, id, parentID string, index int) error {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	var parent *Node[T]
+	if parentID != "" {
+		if parent, err = t.FindByID(ctx, parentID); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Either node may have been removed since it was looked up
+	if !t.contains(node) || parent != nil && !t.contains(parent) {
+		return ErrNodeNotFound
+	}
+	return t.moveNode(node, parent, index)
+}
+
+// moveNode validates and performs a move. Callers must hold t.mu.
+func (t *Tree[T]) moveNode(node, parent *Node[T], index int) error {
+	for ancestor := parent; ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor == node {
+			return fmt.Errorf("%w: %q cannot be moved into its own subtree", ErrInvalidMove, node.ID())
+		}
+	}
+	if t.movePolicy != nil {
+		if err := t.movePolicy(node, parent); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidMove, err)
+		}
+	}
+
+	oldParent := node.Parent()
+	oldIndex := t.relocateNode(node, parent, index)
+	t.history.record(
+		func() { t.relocateNode(node, oldParent, oldIndex) },
+		func() { t.relocateNode(node, parent, index) },
+		node, oldParent, parent,
+	)
+	return nil
+}
+
+// relocateNode moves node under parent at index without validation and
+// returns the index it previously held. Callers must hold t.mu.
+func (t *Tree[T]) relocateNode(node, parent *Node[T], index int) int {
+	oldParent := node.Parent()
+	oldIndex := t.detachNode(node)
+	t.attachNode(node, parent, index)
+
+	// Both the old and new ancestors may have changed check state
+	t.refreshAncestorSelection(oldParent)
+	t.refreshAncestorSelection(parent)
+	return oldIndex
+}
+
+// InsertNode adds node (with any children it already has) under the node with
+// parentID at position index. An empty parentID inserts a root node, and an
+// index outside the sibling list appends at the end. Returns ErrAttachedNode
+// if node already has a parent, ErrEmptyID or ErrDuplicateID for an unusable
+// ID anywhere in its subtree, ErrNodeNotFound if the parent doesn't exist, or
+// context errors unwrapped.
+func (t *Tree[T]) InsertNode(ctx context.Context, parentID string, index int, node *Node[T]) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if node.Parent() != nil {
+		return fmt.Errorf("%w: %q", ErrAttachedNode, node.ID())
+	}
+
+	// Validate under the same lock as the insert, so no other insert can
+	// claim an ID in between
+	ids := make(map[string]*Node[T])
+	for info, err := range dfsSeq(ctx, t.nodes, true, nil) {
+		if err != nil {
+			return err
+		}
+		ids[info.Node.ID()] = info.Node
+	}
+	var parent *Node[T]
+	if parentID != "" {
+		if parent = ids[parentID]; parent == nil {
+			return ErrNodeNotFound
+		}
+	}
+	for info, err := range dfsSeq(ctx, []*Node[T]{node}, true, nil) {
+		if err != nil {
+			return err
+		}
+		id := info.Node.ID()
+		if id == "" {
+			return ErrEmptyID
+		}
+		if _, ok := ids[id]; ok {
+			return fmt.Errorf("%w: %q", ErrDuplicateID, id)
+		}
+		ids[id] = info.Node
+	}
+
+	t.attachNode(node, parent, index)
+	t.refreshAncestorSelection(parent)
+	t.history.record(
+		func() { t.removeNode(node) },
+		func() { t.attachNode(node, parent, index); t.refreshAncestorSelection(parent) },
+		node, parent,
+	)
+	return nil
+}
+
+// RemoveNode detaches the node with the given ID and its subtree from the
+// tree and returns it. Removed nodes are dropped from the focus and the
+// checkbox selection; undoing the removal selects them again. Returns
+// ErrNodeNotFound if the ID doesn't exist, or context errors unwrapped.
+func (t *Tree[T]) RemoveNode(ctx context.Context, id string) (*Node[T], error) {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return nil, ErrNodeNotFound
+	}
+	parent := node.Parent()
+	selection := t.subtreeSelection(node)
+	index := t.removeNode(node)
+	t.history.record(
+		func() {
+			t.attachNode(node, parent, index)
+			t.restoreSelection(selection)
+			t.refreshAncestorSelection(parent)
+		},
+		func() { t.removeNode(node) },
+		node, parent,
+	)
+	return node, nil
+}
+
+// removeNode detaches node, drops its subtree from the focus and the
+// selection and returns the index it held. Callers must hold t.mu.
+func (t *Tree[T]) removeNode(node *Node[T]) int {
+	parent := node.Parent()
+	index := t.detachNode(node)
+	removed := make(map[*Node[T]]bool)
+	for info := range dfsSeq(context.Background(), []*Node[T]{node}, true, nil) {
+		removed[info.Node] = true
+	}
+	t.dropSelection(removed)
+	t.refreshAncestorSelection(parent)
+	t.indexDetach(node)
+	t.forgetAggregates(node)
+
+	// Removed nodes can no longer be focused
+	focused := t.focusedNodes[:0:0]
+	for _, n := range t.focusedNodes {
+		if removed[n] {
+			delete(t.focusedIDs, n.ID())
+			continue
+		}
+		focused = append(focused, n)
+	}
+	if len(focused) != len(t.focusedNodes) {
+		t.focusedNodes = focused
+		t.emitFocus()
+	}
+
+	// A removed hoist falls back to the closest remaining ancestor
+	if removed[t.hoist] {
+		t.setHoist(parent)
+	}
+	return index
+}
+
+// SetNodeData replaces the payload of the node with the given ID. Returns
+// ErrNodeNotFound if the ID doesn't exist, or context errors unwrapped.
+func (t *Tree[T]) SetNodeData(ctx context.Context, id string, data T) error {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return ErrNodeNotFound
+	}
+	old := node.data
+	t.replaceNodeData(node, data)
+	t.history.record(
+		func() { t.replaceNodeData(node, old) },
+		func() { t.replaceNodeData(node, data) },
+		node,
+	)
+	return nil
+}
+
+// replaceNodeData replaces the payload of node, which must belong to the
+// tree, and updates the search index, aggregates and snapshot copies. It
+// doesn't record history. Callers must hold t.mu.
+func (t *Tree[T]) replaceNodeData(node *Node[T], data T) {
+	node.SetData(data)
+	t.indexUpdate(node)
+	t.invalidateAggregates(node)
+	t.touch(node)
+	t.emit(EventData, []string{node.ID()})
+}
+
+// detachNode removes node from its parent's children or from the roots and
+// returns the position it held. Callers must hold t.mu.
+func (t *Tree[T]) detachNode(node *Node[T]) int {
+	parent := node.Parent()
+	t.invalidateAggregates(parent)
+	t.touch(parent)
+	t.emit(EventStructure, []string{node.ID()})
+	if parent == nil {
+		i := slices.Index(t.nodes, node)
+		if i >= 0 {
+			t.nodes = removeAt(t.nodes, i)
+		}
+		return i
+	}
+
+	i := slices.Index(parent.children, node)
+	if i >= 0 {
+		parent.children = removeAt(parent.children, i)
+	}
+	node.parent = nil
+	return i
+}
+
+// attachNode inserts node under parent (or at the root level when parent is
+// nil) at index. Callers must hold t.mu.
+func (t *Tree[T]) attachNode(node, parent *Node[T], index int) {
+	t.emit(EventStructure, []string{node.ID()})
+	if parent == nil {
+		t.nodes = insertAt(t.nodes, index, node)
+		node.parent = nil
+		t.indexAttach(node)
+		return
+	}
+	parent.children = insertAt(parent.children, index, node)
+	node.parent = parent
+	t.indexAttach(node)
+	t.invalidateAggregates(parent)
+	t.touch(parent)
+}
+
+// siblings returns the slice that holds node: its parent's children or the
+// tree roots.
+func (t *Tree[T]) siblings(node *Node[T]) []*Node[T] {
+	if parent := node.Parent(); parent != nil {
+		return parent.Children()
+	}
+	return t.Nodes()
+}
+
+// removeAt returns a new slice without the element at i. A fresh slice is
+// built so iterators holding the old one are not disturbed.
+func removeAt[T any](nodes []*Node[T], i int) []*Node[T] {
+	out := make([]*Node[T], 0, len(nodes)-1)
+	out = append(out, nodes[:i]...)
+	return append(out, nodes[i+1:]...)
+}
+
+// insertAt returns a new slice with node inserted at index. Indices outside
+// the slice append at the end.
+func insertAt[T any](nodes []*Node[T], index int, node *Node[T]) []*Node[T] {
+	if index < 0 || index > len(nodes) {
+		index = len(nodes)
+	}
+	out := make([]*Node[T], 0, len(nodes)+1)
+	out = append(out, nodes[:index]...)
+	out = append(out, node)
+	return append(out, nodes[index:]...)
+}
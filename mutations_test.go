@@ -0,0 +1,228 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// treeShape renders the structure of a tree as "id(child,child)" strings so
+// tests can compare whole trees at a glance.
+func treeShape[T any](nodes []*Node[T]) []string {
+	shape := make([]string, len(nodes))
+	for i, node := range nodes {
+		shape[i] = node.ID()
+		if node.HasChildren() {
+			shape[i] += "("
+			for j, child := range treeShape(node.Children()) {
+				if j > 0 {
+					shape[i] += ","
+				}
+				shape[i] += child
+			}
+			shape[i] += ")"
+		}
+	}
+	return shape
+}
+
+func TestTree_MoveNode(t *testing.T) {
+	errNoChildren := errors.New("2.1 cannot have children")
+
+	tests := []struct {
+		name      string
+		id        string
+		parentID  string
+		index     int
+		policy    MovePolicyFn[string]
+		wantShape []string
+		wantErr   error
+	}{
+		{
+			name:      "reorder_siblings",
+			id:        "1.2",
+			parentID:  "1",
+			index:     0,
+			wantShape: []string{"1(1.2,1.1(1.1.1))", "2(2.1)"},
+		},
+		{
+			name:      "reparent",
+			id:        "1.1",
+			parentID:  "2",
+			index:     -1,
+			wantShape: []string{"1(1.2)", "2(2.1,1.1(1.1.1))"},
+		},
+		{
+			name:      "move_to_root",
+			id:        "2.1",
+			parentID:  "",
+			index:     1,
+			wantShape: []string{"1(1.1(1.1.1),1.2)", "2.1", "2"},
+		},
+		{
+			name:      "root_into_other_root",
+			id:        "2",
+			parentID:  "1.2",
+			index:     0,
+			wantShape: []string{"1(1.1(1.1.1),1.2(2(2.1)))"},
+		},
+		{
+			name:      "into_own_subtree",
+			id:        "1",
+			parentID:  "1.1.1",
+			index:     0,
+			wantShape: []string{"1(1.1(1.1.1),1.2)", "2(2.1)"},
+			wantErr:   ErrInvalidMove,
+		},
+		{
+			name:     "policy_rejects",
+			id:       "1.2",
+			parentID: "2.1",
+			index:    0,
+			policy: func(_, newParent *Node[string]) error {
+				if newParent != nil && newParent.ID() == "2.1" {
+					return errNoChildren
+				}
+				return nil
+			},
+			wantShape: []string{"1(1.1(1.1.1),1.2)", "2(2.1)"},
+			wantErr:   errNoChildren,
+		},
+		{
+			name:      "missing_node",
+			id:        "missing",
+			parentID:  "1",
+			wantShape: []string{"1(1.1(1.1.1),1.2)", "2(2.1)"},
+			wantErr:   ErrNodeNotFound,
+		},
+		{
+			name:      "missing_parent",
+			id:        "1.1",
+			parentID:  "missing",
+			wantShape: []string{"1(1.1(1.1.1),1.2)", "2(2.1)"},
+			wantErr:   ErrNodeNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createSimpleTree(t)
+			tree.movePolicy = test.policy
+
+			err := tree.MoveNode(context.Background(), test.id, test.parentID, test.index)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("MoveNode(%q, %q, %d) error = %v, want %v", test.id, test.parentID, test.index, err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.wantShape, treeShape(tree.Nodes())); diff != "" {
+				t.Errorf("MoveNode(%q, %q, %d) shape mismatch (-want +got):\n%s", test.id, test.parentID, test.index, diff)
+			}
+		})
+	}
+}
+
+func TestTree_MoveNode_ParentPointers(t *testing.T) {
+	tree := createSimpleTree(t)
+	if err := tree.MoveNode(context.Background(), "1.1", "2", 0); err != nil {
+		t.Fatalf("MoveNode() error = %v", err)
+	}
+
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		for _, child := range info.Node.Children() {
+			if child.Parent() != info.Node {
+				t.Errorf("node %q parent = %v, want %q", child.ID(), child.Parent(), info.Node.ID())
+			}
+		}
+	}
+}
+
+func TestTree_MoveNode_RefreshesSelection(t *testing.T) {
+	ctx := context.Background()
+	tree := createSimpleTree(t)
+	if err := tree.SetSelected(ctx, "1.1", true); err != nil {
+		t.Fatalf("SetSelected() error = %v", err)
+	}
+	if got := tree.CheckState("1"); got != PartiallyChecked {
+		t.Fatalf("CheckState(1) = %v, want %v", got, PartiallyChecked)
+	}
+
+	if err := tree.MoveNode(ctx, "1.2", "2", -1); err != nil {
+		t.Fatalf("MoveNode() error = %v", err)
+	}
+	if got := tree.CheckState("1"); got != Checked {
+		t.Errorf("CheckState(1) after moving unselected child away = %v, want %v", got, Checked)
+	}
+}
+
+// removingCtx runs remove the first time its Err method is called, which the
+// tree iterators do between steps without holding the lock. It lets tests
+// remove a node while a method is still resolving its ID.
+type removingCtx struct {
+	context.Context
+	once   sync.Once
+	remove func()
+}
+
+func (c *removingCtx) Err() error {
+	c.once.Do(c.remove)
+	return c.Context.Err()
+}
+
+func TestTree_Mutations_RemovedWhileResolving(t *testing.T) {
+	// Each mutation looks up "a", which is removed before it takes the lock
+	tests := []struct {
+		name   string
+		mutate func(context.Context, *Tree[string]) error
+	}{
+		{"move", func(ctx context.Context, tree *Tree[string]) error { return tree.MoveNode(ctx, "a", "b", 0) }},
+		{"remove", func(ctx context.Context, tree *Tree[string]) error { _, err := tree.RemoveNode(ctx, "a"); return err }},
+		{"rename", func(ctx context.Context, tree *Tree[string]) error { return tree.Rename(ctx, "a", "renamed") }},
+		{"set_data", func(ctx context.Context, tree *Tree[string]) error { return tree.SetNodeData(ctx, "a", "data") }},
+		{"sort_subtree", func(ctx context.Context, tree *Tree[string]) error {
+			return tree.SortSubtree(ctx, "a", func(a, b *Node[string]) bool { return a.ID() < b.ID() })
+		}},
+		{"hoist", func(ctx context.Context, tree *Tree[string]) error { return tree.Hoist(ctx, "a") }},
+		{"set_selected", func(ctx context.Context, tree *Tree[string]) error { return tree.SetSelected(ctx, "a", true) }},
+		{"set_expanded", func(ctx context.Context, tree *Tree[string]) error {
+			_, err := tree.SetExpanded(ctx, "a", true)
+			return err
+		}},
+		{"set_focused", func(ctx context.Context, tree *Tree[string]) error {
+			_, err := tree.SetFocusedID(ctx, "a")
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createDiffTree([][3]string{{"a", "", ""}, {"a1", "a", ""}, {"b", "", ""}})
+			_, _ = tree.SetFocusedID(context.Background(), "b")
+			ctx := &removingCtx{Context: context.Background(), remove: func() {
+				if _, err := tree.RemoveNode(context.Background(), "a"); err != nil {
+					t.Errorf("RemoveNode() error = %v", err)
+				}
+			}}
+
+			if err := test.mutate(ctx, tree); !errors.Is(err, ErrNodeNotFound) {
+				t.Errorf("error = %v, want %v", err, ErrNodeNotFound)
+			}
+			if diff := cmp.Diff([]string{"b"}, treeShape(tree.Nodes())); diff != "" {
+				t.Errorf("tree mismatch (-want +got):\n%s", diff)
+			}
+			if hoisted := tree.HoistedNode(); hoisted != nil {
+				t.Errorf("HoistedNode() = %q, want nil", hoisted.ID())
+			}
+			if focused := tree.GetFocusedNode(); focused == nil || focused.ID() != "b" {
+				t.Errorf("focused node = %v, want b", focused)
+			}
+			if tree.IsSelected("a") {
+				t.Error("IsSelected(a) = true, want the removed node left alone")
+			}
+		})
+	}
+}
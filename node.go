@@ -7,7 +7,8 @@ import (
 // Node represents a single element in a tree. It stores an arbitrary payload
 // of type T along with helper metadata used by the renderer and traversal
 // helpers. A Node is not safe for concurrent mutation; callers must
-// synchronise if they modify Nodes from multiple goroutines.
+// synchronise if they modify Nodes from multiple goroutines. For nodes in a
+// Tree that means going through the Tree's methods, which hold its lock.
 //
 // ID and Name
 //
@@ -24,6 +25,13 @@ type Node[T any] struct {
 	parent   *Node[T]
 	expanded bool
 	visible  bool
+
+	// snap is the node's frozen copy in the latest snapshot, nil once the
+	// node or anything below it changed; see snapshot.go.
+	snap *Node[T]
+	// frozen marks the copies made for snapshots, which no tree method
+	// ever changes.
+	frozen bool
 }
 
 // NewNode constructs a Node with the supplied name and payload. Children are
@@ -55,6 +63,32 @@ func NewNodeClone[T any](original *Node[T]) *Node[T] {
 	return clone
 }
 
+// DeepClone returns a copy of the node and everything below it with the same
+// IDs, names, payloads and expanded and visible flags. The copy has no parent,
+// so it can be placed in another tree without affecting the original. Payloads
+// are copied by assignment; pass copyData to copy payloads that hold pointers,
+// slices or maps which must not be shared. copyData may be nil.
+func (n *Node[T]) DeepClone(copyData func(T) T) *Node[T] {
+	return cloneBelow(n, copyData, -1)
+}
+
+// cloneBelow copies node and up to levels levels of its descendants, all of
+// them if levels is negative.
+func cloneBelow[T any](node *Node[T], copyData func(T) T, levels int) *Node[T] {
+	clone := NewNodeClone(node)
+	if copyData != nil {
+		clone.data = copyData(node.data)
+	}
+	if levels != 0 && len(node.children) > 0 {
+		children := make([]*Node[T], len(node.children))
+		for i, child := range node.children {
+			children[i] = cloneBelow(child, copyData, levels-1)
+		}
+		clone.SetChildren(children)
+	}
+	return clone
+}
+
 // ID returns the stable identifier of the node.
 //
 // The identifier is unique within a single tree and is the first port of call
@@ -80,7 +114,10 @@ func (n *Node[T]) Data() *T {
 	return &n.data
 }
 
-// SetData replaces the payload stored inside the node.
+// SetData replaces the payload stored inside the node. It takes no lock, so
+// once the node belongs to a Tree other goroutines use, call
+// Tree.SetNodeData instead, which also keeps the search index, aggregates
+// and snapshots in sync.
 func (n *Node[T]) SetData(data T) {
 	n.data = data
 }
@@ -104,7 +141,8 @@ func (n *Node[T]) Toggle() {
 	n.expanded = !n.expanded
 }
 
-// SetExpanded sets the expanded state of the node.
+// SetExpanded sets the expanded state of the node. Use Tree.SetExpanded for
+// nodes of a tree other goroutines use.
 func (n *Node[T]) SetExpanded(expanded bool) {
 	n.expanded = expanded
 }
@@ -131,7 +169,8 @@ func (n *Node[T]) HasChildren() bool {
 	return len(n.children) > 0
 }
 
-// SetName updates the display label shown by renderers.
+// SetName updates the display label shown by renderers. Use Tree.Rename for
+// nodes of a tree other goroutines use.
 func (n *Node[T]) SetName(name string) {
 	n.name = name
 }
@@ -142,6 +181,8 @@ func (n *Node[T]) Children() []*Node[T] {
 }
 
 // SetChildren replaces the entire child slice and wires up the parent pointers.
+// It is meant for building subtrees; change the children of a node in a
+// shared tree with Tree.InsertNode, Tree.RemoveNode and Tree.MoveNode.
 func (n *Node[T]) SetChildren(children []*Node[T]) {
 	for _, child := range children {
 		child.parent = n
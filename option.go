@@ -9,6 +9,10 @@ import (
 // SearchFn returns true if the node matches the search term.
 type SearchFn[T any] func(ctx context.Context, node *Node[T], term string) bool
 
+// MatchFn is a richer SearchFn that also scores the match and reports which
+// runes of the node's Name matched. It returns false if the node does not match.
+type MatchFn[T any] func(ctx context.Context, node *Node[T], term string) (Match, bool)
+
 // FilterFn returns true if the item should be included in the tree.
 type FilterFn[T any] func(item T) bool
 
@@ -22,6 +26,10 @@ type ProgressCallback[T any] func(processed int, node *Node[T])
 // list. The offset is usually ±1 but can be any integer.
 type FocusPolicyFn[T any] func(ctx context.Context, visible []*Node[T], current *Node[T], offset int) (*Node[T], error)
 
+// MovePolicyFn decides whether node may be moved under newParent. newParent is
+// nil when the node would become a root. Returning an error rejects the move.
+type MovePolicyFn[T any] func(node, newParent *Node[T]) error
+
 // Option is the unified functional Option type used by all tree constructors.
 // It allows callers to provide build-time and run-time configurations in a
 // single, flat list.
@@ -36,9 +44,47 @@ func WithProvider[T any](p NodeProvider[T]) Option[T] {
 }
 
 // WithSearcher overwrites the algorithm used when the search feature is invoked.
+// Plain searchers don't rank or highlight matches; use WithMatcher for that.
 func WithSearcher[T any](fn SearchFn[T]) Option[T] {
 	return func(cfg *MasterConfig[T]) {
 		cfg.searcher = fn
+		cfg.matcher = nil
+		cfg.indexSafe = false
+	}
+}
+
+// WithMatcher installs a scoring search algorithm. Search results are ordered
+// by descending score and the matched runes are highlighted when rendering.
+// It takes precedence over an earlier WithSearcher.
+func WithMatcher[T any](fn MatchFn[T]) Option[T] {
+	return func(cfg *MasterConfig[T]) {
+		cfg.matcher = fn
+		cfg.indexSafe = false
+	}
+}
+
+// WithIndexedMatcher is WithMatcher for matchers that only match nodes whose
+// indexed text (see WithSearchIndex) contains the term, ignoring case, such
+// as a substring matcher with its own scoring. Unlike other matchers it lets
+// the search index narrow the nodes it is run on.
+func WithIndexedMatcher[T any](fn MatchFn[T]) Option[T] {
+	return func(cfg *MasterConfig[T]) {
+		cfg.matcher = fn
+		cfg.indexSafe = true
+	}
+}
+
+// WithFuzzySearch switches searching to the built-in fzf-style fuzzy matcher.
+func WithFuzzySearch[T any]() Option[T] {
+	return WithMatcher(FuzzyMatcher[T]())
+}
+
+// WithFilterDescendants makes Tree.Filter also show the subtrees below each
+// match, following their normal expansion state. By default only matches and
+// their ancestors are shown.
+func WithFilterDescendants[T any]() Option[T] {
+	return func(cfg *MasterConfig[T]) {
+		cfg.filterDescendants = true
 	}
 }
 
@@ -73,6 +119,22 @@ func WithFilterFunc[T any](filter FilterFn[T]) Option[T] {
 	}
 }
 
+// WithFilterPolicy sets what NewTreeFromFlatData does with items rejected by
+// the filter function that have children. Defaults to FilterKeepAncestors.
+func WithFilterPolicy[T any](policy FilterPolicy) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.filterPolicy = policy
+	}
+}
+
+// WithFilterReport registers a callback invoked once by NewTreeFromFlatData
+// with the number of items the filter function excluded from the tree.
+func WithFilterReport[T any](fn func(excluded int)) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.filterReport = fn
+	}
+}
+
 // WithMaxDepth limits how deep the walker descends into directories or other
 // data structures. Use a negative depth for no limit (default).
 func WithMaxDepth[T any](d int) Option[T] {
@@ -99,6 +161,23 @@ func WithProgressCallback[T any](cb ProgressCallback[T]) Option[T] {
 	}
 }
 
+// WithOrphanPolicy sets what NewTreeFromFlatData does with items whose
+// parent is missing. Defaults to OrphanError.
+func WithOrphanPolicy[T any](policy OrphanPolicy) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.orphanPolicy = policy
+	}
+}
+
+// WithOrphanReport registers a callback invoked by NewTreeFromFlatData for
+// every item whose parent is missing, whatever the orphan policy, for example
+// to log the items left out by OrphanDrop.
+func WithOrphanReport[T any](fn OrphanReportFn[T]) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.orphanReport = fn
+	}
+}
+
 // WithTruncate sets the maximum width for rendered lines. Lines longer than
 // this width will be truncated with an ellipsis. A width of 0 disables
 // truncation (default).
@@ -108,6 +187,72 @@ func WithTruncate[T any](width int) Option[T] {
 	}
 }
 
+// WithMovePolicy installs a hook that validates every structural move, for
+// example to stop files from receiving children.
+func WithMovePolicy[T any](fn MovePolicyFn[T]) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.movePolicy = fn
+	}
+}
+
+// WithSearchWorkers evaluates the matcher on n goroutines during a search,
+// which pays off for matchers doing I/O such as ContentMatcher. Results are
+// still returned in tree order before sorting by score. n ≤ 1 searches on the
+// calling goroutine, which is the default. The matcher must be safe for
+// concurrent use.
+func WithSearchWorkers[T any](n int) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.searchWorkers = n
+	}
+}
+
+// WithSearchIndex maintains a trigram index over the ID, name and text
+// extracted by text of every node. Searches with the configured matcher then
+// only evaluate nodes whose indexed text contains the term, which keeps
+// typing responsive in trees with hundreds of thousands of nodes. A nil text
+// indexes the node data the way the default searcher reads it: the data
+// itself if it is a string and its String method if it has one. Matchers
+// that look at other parts of the data need a text that extracts them.
+//
+// Only the default searcher and matchers installed with WithIndexedMatcher
+// use the index. Matchers that can match nodes not containing the term, such
+// as FuzzyMatcher or ContentMatcher, and any matcher set with WithMatcher or
+// WithSearcher leave the tree without an index.
+func WithSearchIndex[T any](text IndexTextFn[T]) Option[T] {
+	return func(c *MasterConfig[T]) {
+		if text == nil {
+			text = defaultIndexText[T]
+		}
+		c.indexed = true
+		c.indexText = text
+	}
+}
+
+// WithSort sorts the roots and every list of siblings by less when the tree
+// is built, which makes the order independent of the data source. Use
+// Tree.Sort to re-sort later.
+func WithSort[T any](less LessFn[T]) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.sortLess = less
+	}
+}
+
+// WithHistoryLimit sets how many undo steps the tree keeps. A limit ≤ 0
+// disables the undo history. Defaults to 100.
+func WithHistoryLimit[T any](limit int) Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.historyLimit = limit
+	}
+}
+
+// WithCheckboxes renders a checkbox in front of every node showing whether it
+// is checked, unchecked, or partially checked (some descendants selected).
+func WithCheckboxes[T any]() Option[T] {
+	return func(c *MasterConfig[T]) {
+		c.checkboxes = true
+	}
+}
+
 // MasterConfig is the structure that aggregates options from
 // different domains (build, filesystem, tree). It is used by the unified
 // constructors to collect and dispatch options to the appropriate internal
@@ -119,18 +264,35 @@ type MasterConfig[T any] struct {
 	expandFunc   ExpandFn[T]         // If the function returns true, the node is expanded immediately during the build process.
 	filterFunc   FilterFn[T]         // If the function returns true, the node is included in the tree.
 	progressCb   ProgressCallback[T] // Optional progress reporting during construction.
+	filterPolicy FilterPolicy        // What the flat builder does with rejected items that have children.
+	filterReport func(excluded int)  // Optional report of the number of items the filter excluded.
+	orphanPolicy OrphanPolicy        // What the flat builder does with items whose parent is missing.
+	orphanReport OrphanReportFn[T]   // Optional report of items whose parent is missing.
 
 	// Options passed to the final tree.
 	searcher      SearchFn[T]
+	matcher       MatchFn[T]
 	focusPol      FocusPolicyFn[T]
 	provider      NodeProvider[T]
-	truncateWidth int // Maximum width for rendered lines (0 = no truncation)
+	movePolicy    MovePolicyFn[T]
+	truncateWidth int  // Maximum width for rendered lines (0 = no truncation)
+	checkboxes    bool // Render selection checkboxes in front of each node
+	historyLimit  int  // Number of undo steps to keep (≤ 0 disables history)
+
+	filterDescendants bool // Filter shows the subtrees below matches
+	searchWorkers     int  // Goroutines evaluating the matcher (≤ 1 = none)
+	indexed           bool // Maintain a search index
+	indexSafe         bool // The matcher only matches nodes containing the term
+	indexText         IndexTextFn[T]
+	sortLess          LessFn[T] // Sort order applied when the tree is built
+	deepSizes         bool      // Count file sizes below the depth limit; see WithDeepSizes
 }
 
 // NewMasterConfig is a helper that creates a MasterConfig, applies defaults, and then user-provided options.
 func NewMasterConfig[T any](opts []Option[T], defaults ...Option[T]) *MasterConfig[T] {
 	cfg := &MasterConfig[T]{
 		searcher:     defaultSearchFn[T],
+		matcher:      defaultMatchFn[T],
 		focusPol:     defaultFocusPolicy[T],
 		provider:     NewDefaultNodeProvider[T](),
 		expandFunc:   nil,
@@ -138,6 +300,8 @@ func NewMasterConfig[T any](opts []Option[T], defaults ...Option[T]) *MasterConf
 		maxDepth:     -1,
 		traversalCap: 10000,
 		progressCb:   nil,
+		historyLimit: defaultHistoryLimit,
+		indexSafe:    true,
 	}
 
 	// Apply provided defaults first
@@ -209,18 +373,13 @@ func defaultSearchFn[T any](_ context.Context, node *Node[T], term string) bool
 
 	// First, prepare the search comparison values
 	// We'll check both the node's ID and Name definitely
+	// Then the data if it's a string or has a String() method.
+	str, stringer := dataStrings(node)
 	fields := []string{
 		strings.ToLower(node.ID()),
 		strings.ToLower(node.Name()),
-		"",
-		"",
-	}
-	// Then the data if it's a string or has a String() method.
-	if nodeData, ok := any(*node.Data()).(string); ok {
-		fields[2] = strings.ToLower(nodeData)
-	}
-	if nodeData, ok := any(*node.Data()).(interface{ String() string }); ok {
-		fields[3] = strings.ToLower(nodeData.String())
+		strings.ToLower(str),
+		strings.ToLower(stringer),
 	}
 
 	searchTerm := strings.ToLower(term)
@@ -235,6 +394,27 @@ func defaultSearchFn[T any](_ context.Context, node *Node[T], term string) bool
 	return false
 }
 
+// dataStrings returns the text the default searcher looks at in a node's
+// data: the data itself if it is a string, and the result of its String
+// method if it has one. Either may be "".
+func dataStrings[T any](node *Node[T]) (str, stringer string) {
+	if s, ok := any(*node.Data()).(string); ok {
+		str = s
+	}
+	if s, ok := any(*node.Data()).(interface{ String() string }); ok {
+		stringer = s.String()
+	}
+	return str, stringer
+}
+
+// defaultIndexText is the IndexTextFn used when WithSearchIndex is given
+// none: the data text the default searcher checks, so enabling the index
+// doesn't change which nodes match.
+func defaultIndexText[T any](node *Node[T]) string {
+	str, stringer := dataStrings(node)
+	return str + "\x00" + stringer
+}
+
 // defaultFocusPolicy implements a simple focus navigation strategy.
 // It moves through the visible nodes linearly, wrapping at boundaries.
 func defaultFocusPolicy[T any](_ context.Context, visible []*Node[T], current *Node[T], offset int) (*Node[T], error) {
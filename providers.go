@@ -24,6 +24,23 @@ type NodeProvider[T any] interface {
 	Style(node *Node[T], isFocused bool) lipgloss.Style
 }
 
+// MatchStyler is an optional NodeProvider extension that styles the runes of
+// a label matched by the current search. Providers that don't implement it get
+// their regular style with an underline.
+type MatchStyler[T any] interface {
+	MatchStyle(node *Node[T], isFocused bool) lipgloss.Style
+}
+
+// NameFormatter is an optional NodeProvider extension that tells the renderer
+// where the node's name sits in its label, so search highlights land on the
+// name even when text around it repeats the name. FormatName returns the
+// label Format would and the byte offset of the name in it, or -1 if the
+// label doesn't show the name. For other providers the name is looked up in
+// the label and only highlighted if it occurs exactly once.
+type NameFormatter[T any] interface {
+	FormatName(node *Node[T]) (label string, nameOffset int)
+}
+
 // DefaultNodeProvider is a batteries-included implementation of
 // NodeProvider that delivers a pleasant out-of-the-box look & feel.
 //
@@ -45,7 +62,9 @@ type NodeProvider[T any] interface {
 type DefaultNodeProvider[T any] struct {
 	defaultStyle lipgloss.Style
 	focusedStyle lipgloss.Style
-	formatters   []func(node *Node[T]) (string, bool)
+	matchStyle   lipgloss.Style
+	focusedMatch lipgloss.Style
+	formatters   []func(node *Node[T]) (string, int, bool)
 	iconRules    []iconRule[T]
 	styleRules   []styleRule[T]
 	DisableIcons bool
@@ -75,6 +94,11 @@ func NewDefaultNodeProvider[T any](opts ...ProviderOption[T]) *DefaultNodeProvid
 			Foreground(lipgloss.Color("0")).
 			Background(lipgloss.Color("39")).
 			Bold(true),
+		matchStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Underline(true),
+		focusedMatch: lipgloss.NewStyle().
+			Underline(true),
 	}
 
 	for _, opt := range opts {
@@ -101,16 +125,16 @@ func WithDefaultFolderRules[T any]() ProviderOption[T] {
 		)
 
 		// Formatter
-		p.formatters = append(p.formatters, func(n *Node[T]) (string, bool) {
+		p.formatters = append(p.formatters, func(n *Node[T]) (string, int, bool) {
 			if !PredIsDir[T]()(n) {
-				return "", false
+				return "", 0, false
 			}
 
 			name := n.Name()
 			if name == "" {
 				name = n.ID()
 			}
-			return name + "/", true
+			return name + "/", 0, true
 		})
 	}
 }
@@ -162,6 +186,19 @@ func WithStyleRule[T any](predicate func(*Node[T]) bool, style, focused lipgloss
 // WithFormatter adds a custom formatter for the node's label. The first
 // formatter that returns true will be used.
 func WithFormatter[T any](formatter func(node *Node[T]) (string, bool)) ProviderOption[T] {
+	return func(p *DefaultNodeProvider[T]) {
+		p.formatters = append(p.formatters, func(n *Node[T]) (string, int, bool) {
+			label, ok := formatter(n)
+			return label, nameIndex(label, n.Name()), ok
+		})
+	}
+}
+
+// WithNameFormatter is WithFormatter for formatters that also return the
+// byte offset of the node's name in the label, or -1 if the label doesn't
+// show it. Search matches are highlighted at that offset; use it when the
+// text around the name may contain the name as well.
+func WithNameFormatter[T any](formatter func(node *Node[T]) (label string, nameOffset int, ok bool)) ProviderOption[T] {
 	return func(p *DefaultNodeProvider[T]) {
 		p.formatters = append(p.formatters, formatter)
 	}
@@ -227,14 +264,37 @@ func (p *DefaultNodeProvider[T]) SetFocusedStyle(style lipgloss.Style) {
 	p.focusedStyle = style
 }
 
+// MatchStyle returns the style for label runes matched by the current search.
+// Properties it leaves unset are taken from the node's regular style.
+func (p *DefaultNodeProvider[T]) MatchStyle(_ *Node[T], isFocused bool) lipgloss.Style {
+	if isFocused {
+		return p.focusedMatch
+	}
+	return p.matchStyle
+}
+
+// SetMatchStyle changes the styles used to highlight search matches in
+// unfocused and focused nodes.
+func (p *DefaultNodeProvider[T]) SetMatchStyle(style, focused lipgloss.Style) {
+	p.matchStyle = style
+	p.focusedMatch = focused
+}
+
 // Format returns the human-readable label for a node.
 func (p *DefaultNodeProvider[T]) Format(node *Node[T]) string {
+	label, _ := p.FormatName(node)
+	return label
+}
+
+// FormatName returns the label for a node and the byte offset of the node's
+// name in it, or -1 if the label doesn't show the name.
+func (p *DefaultNodeProvider[T]) FormatName(node *Node[T]) (string, int) {
 	for _, formatter := range p.formatters {
-		if name, ok := formatter(node); ok {
-			return name
+		if label, offset, ok := formatter(node); ok {
+			return label, offset
 		}
 	}
-	return node.Name()
+	return node.Name(), 0
 }
 
 // WithFileExtensionRules is a provider Option that adds icon and style rules based on file extensions.
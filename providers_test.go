@@ -274,15 +274,15 @@ func TestDefaultNodeProvider_Format(t *testing.T) {
 		{
 			name: "first_matching_formatter_wins",
 			provider: &DefaultNodeProvider[fileData]{
-				formatters: []func(node *Node[fileData]) (string, bool){
-					func(n *Node[fileData]) (string, bool) {
+				formatters: []func(node *Node[fileData]) (string, int, bool){
+					func(n *Node[fileData]) (string, int, bool) {
 						if n.data.isDir {
-							return "Directory: " + n.Name(), true
+							return "Directory: " + n.Name(), len("Directory: "), true
 						}
-						return "", false
+						return "", 0, false
 					},
-					func(n *Node[fileData]) (string, bool) {
-						return "File: " + n.Name(), true
+					func(n *Node[fileData]) (string, int, bool) {
+						return "File: " + n.Name(), len("File: "), true
 					},
 				},
 			},
@@ -292,12 +292,12 @@ func TestDefaultNodeProvider_Format(t *testing.T) {
 		{
 			name: "no_formatter_matches_uses_name",
 			provider: &DefaultNodeProvider[fileData]{
-				formatters: []func(node *Node[fileData]) (string, bool){
-					func(n *Node[fileData]) (string, bool) {
+				formatters: []func(node *Node[fileData]) (string, int, bool){
+					func(n *Node[fileData]) (string, int, bool) {
 						if n.data.isDir {
-							return "Directory: " + n.Name(), true
+							return "Directory: " + n.Name(), len("Directory: "), true
 						}
-						return "", false
+						return "", 0, false
 					},
 				},
 			},
@@ -0,0 +1,623 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// A query combines field conditions with boolean operators, for example
+//
+//	ext:.go AND NOT hidden AND (name~"^test" OR depth>2)
+//
+// Conditions have the form field<op>value where op is one of : = != ~ > >= < <=.
+// A field without operator is a flag such as "hidden" or "dir", and a bare
+// word or quoted string matches node names containing it. AND, OR and NOT
+// (also written &&, || and !) are case-insensitive; juxtaposed conditions are
+// joined with AND, which binds tighter than OR. Values containing spaces or
+// parentheses must be quoted.
+
+// QueryOp is the comparison operator of a query condition.
+type QueryOp string
+
+const (
+	QueryFlag  QueryOp = ""   // Bare field such as "hidden"
+	QueryHas   QueryOp = ":"  // Field contains or has the value
+	QueryEq    QueryOp = "="  // Field equals the value
+	QueryNe    QueryOp = "!=" // Field does not equal the value
+	QueryMatch QueryOp = "~"  // Field matches the regular expression
+	QueryGt    QueryOp = ">"
+	QueryGe    QueryOp = ">="
+	QueryLt    QueryOp = "<"
+	QueryLe    QueryOp = "<="
+)
+
+// QueryFieldFn compiles a condition on one field into a predicate. It returns
+// an error if the operator isn't supported or the value can't be parsed; the
+// error text is reported with the value's position in the query.
+type QueryFieldFn[T any] func(op QueryOp, value string) (func(*Node[T]) bool, error)
+
+// QueryError describes why a query could not be parsed. Pos is the rune
+// offset in Query where the problem was found.
+type QueryError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+// Error implements the error interface.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%v: %s at position %d", ErrInvalidQuery, e.Msg, e.Pos)
+}
+
+// Unwrap makes QueryError match ErrInvalidQuery with errors.Is.
+func (e *QueryError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// QueryParser compiles query strings into node predicates. The zero value
+// knows no fields; use NewQueryParser for one with the built-in fields.
+type QueryParser[T any] struct {
+	fields map[string]QueryFieldFn[T]
+}
+
+// QueryOption configures a QueryParser.
+type QueryOption[T any] func(*QueryParser[T])
+
+// WithQueryField registers a resolver for a field, replacing any built-in
+// resolver of the same name. Field names are case-insensitive.
+func WithQueryField[T any](name string, fn QueryFieldFn[T]) QueryOption[T] {
+	return func(p *QueryParser[T]) {
+		if p.fields == nil {
+			p.fields = make(map[string]QueryFieldFn[T])
+		}
+		p.fields[strings.ToLower(name)] = fn
+	}
+}
+
+// NewQueryParser returns a parser that knows these fields:
+//
+//	name, id      text with ":" (contains), "=", "!=" (case-insensitive) and "~" (regex)
+//	ext           file extension, e.g. ext:.go or ext:go,md
+//	hidden, dir, file, expanded, collapsed
+//	              flags, optionally compared with true/false (dir:false)
+//	depth         nesting level of the node, roots are 0
+//	size          size of payloads with a Size() int64 method, e.g. size>10k
+func NewQueryParser[T any](opts ...QueryOption[T]) *QueryParser[T] {
+	p := &QueryParser[T]{fields: map[string]QueryFieldFn[T]{
+		"name":      textField(func(n *Node[T]) string { return n.Name() }),
+		"id":        textField(func(n *Node[T]) string { return n.ID() }),
+		"ext":       extField[T],
+		"hidden":    flagField(PredIsHidden[T]()),
+		"dir":       flagField(PredIsDir[T]()),
+		"file":      flagField(PredIsFile[T]()),
+		"expanded":  flagField(PredIsExpanded[T]()),
+		"collapsed": flagField(PredIsCollapsed[T]()),
+		"depth":     numberField(nodeDepth[T], parseCount),
+		"size":      numberField(nodeSize[T], parseSize),
+	}}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// ParseQuery compiles query with the built-in fields. Returns a *QueryError
+// wrapping ErrInvalidQuery if the query is malformed.
+func ParseQuery[T any](query string) (func(*Node[T]) bool, error) {
+	return NewQueryParser[T]().Parse(query)
+}
+
+// Parse compiles query into a predicate. Returns a *QueryError wrapping
+// ErrInvalidQuery if the query is malformed.
+func (p *QueryParser[T]) Parse(query string) (func(*Node[T]) bool, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	qp := &queryParse[T]{parser: p, query: query, tokens: tokens}
+	if qp.peek().kind == tokEOF {
+		return nil, qp.errorf(0, "empty query")
+	}
+
+	pred, err := qp.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := qp.peek(); tok.kind != tokEOF {
+		return nil, qp.errorf(tok.pos, "unexpected %s", tok)
+	}
+	return pred, nil
+}
+
+// Matcher returns a MatchFn that treats the search term as a query. The last
+// compiled query is cached, so searching the whole tree parses it only once.
+// Malformed queries match nothing; call Parse to report the error.
+func (p *QueryParser[T]) Matcher() MatchFn[T] {
+	var (
+		mu   sync.Mutex
+		last string
+		pred func(*Node[T]) bool
+	)
+	return func(_ context.Context, node *Node[T], term string) (Match, bool) {
+		mu.Lock()
+		if term != last || pred == nil {
+			pred, _ = p.Parse(term)
+			last = term
+		}
+		current := pred
+		mu.Unlock()
+
+		if current == nil {
+			return Match{}, false
+		}
+		return Match{}, current(node)
+	}
+}
+
+// PredicateMatcher adapts a node predicate to a MatchFn that ignores the
+// search term. It is handy with Tree.SearchAndExpandWith after compiling a
+// query once.
+func PredicateMatcher[T any](pred func(*Node[T]) bool) MatchFn[T] {
+	return func(_ context.Context, node *Node[T], _ string) (Match, bool) {
+		return Match{}, pred(node)
+	}
+}
+
+// queryTokenKind classifies lexer tokens.
+type queryTokenKind int
+
+const (
+	tokEOF queryTokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+// queryToken is a lexed token with its rune position in the query.
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+// String describes the token for error messages.
+func (t queryToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// isQueryOpRune reports whether r starts a comparison operator.
+func isQueryOpRune(r rune) bool {
+	return strings.ContainsRune(":=!~<>", r)
+}
+
+// lexQuery splits query into tokens. Words directly after an operator are
+// read up to the next space or closing parenthesis so values like ".go" or
+// "^test$" need no quoting.
+func lexQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	var tokens []queryToken
+	afterOp := false
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			afterOp = false
+			continue
+
+		case r == '(' || r == ')':
+			kind := tokLParen
+			if r == ')' {
+				kind = tokRParen
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: string(r), pos: start})
+			i++
+
+		case r == '"':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				c := runes[i]
+				if c == '\\' && i+1 < len(runes) {
+					sb.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				i++
+				if c == '"' {
+					closed = true
+					break
+				}
+				sb.WriteRune(c)
+			}
+			if !closed {
+				return nil, &QueryError{Query: query, Pos: start, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, queryToken{kind: tokString, text: sb.String(), pos: start})
+
+		case afterOp:
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != ')' {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: tokWord, text: string(runes[start:i]), pos: start})
+
+		case r == '&' || r == '|':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, &QueryError{Query: query, Pos: start, Msg: fmt.Sprintf("expected %q", string([]rune{r, r}))}
+			}
+			kind := tokAnd
+			if r == '|' {
+				kind = tokOr
+			}
+			i += 2
+			tokens = append(tokens, queryToken{kind: kind, text: string(runes[start:i]), pos: start})
+
+		case isQueryOpRune(r):
+			i++
+			if i < len(runes) && runes[i] == '=' && (r == '!' || r == '<' || r == '>') {
+				i++
+			}
+			text := string(runes[start:i])
+			if text == "!" {
+				tokens = append(tokens, queryToken{kind: tokNot, text: text, pos: start})
+				break
+			}
+			tokens = append(tokens, queryToken{kind: tokOp, text: text, pos: start})
+			afterOp = true
+			continue
+
+		default:
+			for i < len(runes) {
+				c := runes[i]
+				if unicode.IsSpace(c) || c == '(' || c == ')' || c == '"' || isQueryOpRune(c) {
+					break
+				}
+				i++
+			}
+			text := string(runes[start:i])
+			kind := tokWord
+			switch strings.ToUpper(text) {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: text, pos: start})
+		}
+		afterOp = false
+	}
+
+	return append(tokens, queryToken{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// queryParse is the state of a single recursive-descent parse.
+type queryParse[T any] struct {
+	parser *QueryParser[T]
+	query  string
+	tokens []queryToken
+	pos    int
+}
+
+func (qp *queryParse[T]) peek() queryToken {
+	return qp.tokens[qp.pos]
+}
+
+func (qp *queryParse[T]) next() queryToken {
+	tok := qp.tokens[qp.pos]
+	if tok.kind != tokEOF {
+		qp.pos++
+	}
+	return tok
+}
+
+func (qp *queryParse[T]) errorf(pos int, format string, args ...any) error {
+	return &QueryError{Query: qp.query, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr handles: and { OR and }
+func (qp *queryParse[T]) parseOr() (func(*Node[T]) bool, error) {
+	first, err := qp.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	preds := []func(*Node[T]) bool{first}
+	for qp.peek().kind == tokOr {
+		qp.next()
+		pred, err := qp.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+	if len(preds) == 1 {
+		return first, nil
+	}
+	return PredAny(preds...), nil
+}
+
+// parseAnd handles: unary { [AND] unary }
+func (qp *queryParse[T]) parseAnd() (func(*Node[T]) bool, error) {
+	first, err := qp.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	preds := []func(*Node[T]) bool{first}
+	for {
+		switch qp.peek().kind {
+		case tokAnd:
+			qp.next()
+		case tokWord, tokString, tokNot, tokLParen:
+			// Juxtaposed conditions are an implicit AND
+		default:
+			if len(preds) == 1 {
+				return first, nil
+			}
+			return PredAll(preds...), nil
+		}
+		pred, err := qp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+}
+
+// parseUnary handles: NOT unary | primary
+func (qp *queryParse[T]) parseUnary() (func(*Node[T]) bool, error) {
+	if qp.peek().kind == tokNot {
+		qp.next()
+		pred, err := qp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return PredNot(pred), nil
+	}
+	return qp.parsePrimary()
+}
+
+// parsePrimary handles: ( or ) | field op value | field | text
+func (qp *queryParse[T]) parsePrimary() (func(*Node[T]) bool, error) {
+	tok := qp.next()
+	switch tok.kind {
+	case tokLParen:
+		pred, err := qp.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if qp.peek().kind != tokRParen {
+			return nil, qp.errorf(tok.pos, "missing closing parenthesis")
+		}
+		qp.next()
+		return pred, nil
+
+	case tokString:
+		return PredContainsText[T](tok.text), nil
+
+	case tokWord:
+		if qp.peek().kind == tokOp {
+			return qp.parseCondition(tok)
+		}
+		if fn, ok := qp.parser.fields[strings.ToLower(tok.text)]; ok {
+			pred, err := fn(QueryFlag, "")
+			if err != nil {
+				return nil, qp.errorf(tok.pos, "%s: %v", tok.text, err)
+			}
+			return pred, nil
+		}
+		return PredContainsText[T](tok.text), nil
+	}
+
+	if tok.kind == tokEOF {
+		return nil, qp.errorf(tok.pos, "unexpected end of query")
+	}
+	return nil, qp.errorf(tok.pos, "unexpected %s", tok)
+}
+
+// parseCondition compiles field<op>value using the field's resolver.
+func (qp *queryParse[T]) parseCondition(field queryToken) (func(*Node[T]) bool, error) {
+	op := qp.next()
+	value := qp.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, qp.errorf(value.pos, "expected value after %s%s", field.text, op.text)
+	}
+
+	fn, ok := qp.parser.fields[strings.ToLower(field.text)]
+	if !ok {
+		return nil, qp.errorf(field.pos, "unknown field %q", field.text)
+	}
+	pred, err := fn(QueryOp(op.text), value.text)
+	if err != nil {
+		return nil, qp.errorf(value.pos, "%s%s: %v", field.text, op.text, err)
+	}
+	return pred, nil
+}
+
+// unsupportedOp is returned by field resolvers for operators they don't handle.
+func unsupportedOp(op QueryOp) error {
+	if op == QueryFlag {
+		return fmt.Errorf("operator required")
+	}
+	return fmt.Errorf("operator %q not supported", string(op))
+}
+
+// textField resolves conditions on a string attribute of the node.
+func textField[T any](get func(*Node[T]) string) QueryFieldFn[T] {
+	return func(op QueryOp, value string) (func(*Node[T]) bool, error) {
+		switch op {
+		case QueryHas:
+			lower := strings.ToLower(value)
+			return func(n *Node[T]) bool {
+				return strings.Contains(strings.ToLower(get(n)), lower)
+			}, nil
+		case QueryEq:
+			return func(n *Node[T]) bool { return strings.EqualFold(get(n), value) }, nil
+		case QueryNe:
+			return func(n *Node[T]) bool { return !strings.EqualFold(get(n), value) }, nil
+		case QueryMatch:
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			return func(n *Node[T]) bool { return re.MatchString(get(n)) }, nil
+		}
+		return nil, unsupportedOp(op)
+	}
+}
+
+// extField resolves ext conditions. Values may list several extensions
+// separated by commas, with or without the leading dot.
+func extField[T any](op QueryOp, value string) (func(*Node[T]) bool, error) {
+	var exts []string
+	for ext := range strings.SplitSeq(strings.ToLower(value), ",") {
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	if len(exts) == 0 {
+		return nil, fmt.Errorf("extension required")
+	}
+
+	switch op {
+	case QueryHas, QueryEq:
+		return PredHasExtension[T](exts...), nil
+	case QueryNe:
+		return PredNot(PredHasExtension[T](exts...)), nil
+	}
+	return nil, unsupportedOp(op)
+}
+
+// flagField resolves a boolean attribute used bare or compared with true/false.
+func flagField[T any](pred func(*Node[T]) bool) QueryFieldFn[T] {
+	return func(op QueryOp, value string) (func(*Node[T]) bool, error) {
+		switch op {
+		case QueryFlag:
+			return pred, nil
+		case QueryHas, QueryEq, QueryNe:
+			want, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("expected true or false, got %q", value)
+			}
+			if op == QueryNe {
+				want = !want
+			}
+			if want {
+				return pred, nil
+			}
+			return PredNot(pred), nil
+		}
+		return nil, unsupportedOp(op)
+	}
+}
+
+// numberField resolves numeric comparisons. get reports false for nodes that
+// have no value for the field; such nodes never match.
+func numberField[T any](get func(*Node[T]) (int64, bool), parse func(string) (int64, error)) QueryFieldFn[T] {
+	return func(op QueryOp, value string) (func(*Node[T]) bool, error) {
+		var cmp func(a, b int64) bool
+		switch op {
+		case QueryHas, QueryEq:
+			cmp = func(a, b int64) bool { return a == b }
+		case QueryNe:
+			cmp = func(a, b int64) bool { return a != b }
+		case QueryGt:
+			cmp = func(a, b int64) bool { return a > b }
+		case QueryGe:
+			cmp = func(a, b int64) bool { return a >= b }
+		case QueryLt:
+			cmp = func(a, b int64) bool { return a < b }
+		case QueryLe:
+			cmp = func(a, b int64) bool { return a <= b }
+		default:
+			return nil, unsupportedOp(op)
+		}
+
+		want, err := parse(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(n *Node[T]) bool {
+			got, ok := get(n)
+			return ok && cmp(got, want)
+		}, nil
+	}
+}
+
+// nodeDepth returns how many ancestors the node has.
+func nodeDepth[T any](n *Node[T]) (int64, bool) {
+	var depth int64
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		depth++
+	}
+	return depth, true
+}
+
+// nodeSize returns the payload size if the data has a Size() int64 method.
+func nodeSize[T any](n *Node[T]) (int64, bool) {
+	if sized, ok := any(*n.Data()).(interface{ Size() int64 }); ok {
+		return sized.Size(), true
+	}
+	return 0, false
+}
+
+// parseCount parses a plain integer.
+func parseCount(value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected a number, got %q", value)
+	}
+	return n, nil
+}
+
+// parseSize parses a byte count with an optional binary unit suffix such as
+// 512, 10k, 1.5M or 2GB.
+func parseSize(value string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToLower(value), "b")
+	mult := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		case 't':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a size such as 512, 10k or 1.5M, got %q", value)
+	}
+	return int64(n * float64(mult)), nil
+}
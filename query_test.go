@@ -0,0 +1,194 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// queryData is a payload with the optional methods used by query fields.
+type queryData struct {
+	dir  bool
+	size int64
+}
+
+func (d queryData) IsDir() bool { return d.dir }
+func (d queryData) Size() int64 { return d.size }
+
+// createQueryTree builds:
+//
+//	src/
+//	├── main.go        (100 B)
+//	├── main_test.go   (2 KiB)
+//	├── .hidden.go     (10 B)
+//	└── sub/
+//	    ├── test_util.go (5000 B)
+//	    └── README.md    (20 B)
+func createQueryTree() *Tree[queryData] {
+	file := func(name string, size int64) *Node[queryData] {
+		return NewNode(name, name, queryData{size: size})
+	}
+	sub := NewNode("sub", "sub", queryData{dir: true})
+	sub.AddChild(file("test_util.go", 5000))
+	sub.AddChild(file("README.md", 20))
+	src := NewNode("src", "src", queryData{dir: true})
+	src.AddChild(file("main.go", 100))
+	src.AddChild(file("main_test.go", 2048))
+	src.AddChild(file(".hidden.go", 10))
+	src.AddChild(sub)
+	return NewTree([]*Node[queryData]{src})
+}
+
+// queryMatches returns the IDs of the nodes accepted by pred in tree order.
+func queryMatches(t *testing.T, tree *Tree[queryData], pred func(*Node[queryData]) bool) []string {
+	t.Helper()
+	var ids []string
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		if pred(info.Node) {
+			ids = append(ids, info.Node.ID())
+		}
+	}
+	return ids
+}
+
+func TestQueryParser_Parse(t *testing.T) {
+	tree := createQueryTree()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "bare_word", query: "main", want: []string{"main.go", "main_test.go"}},
+		{name: "quoted_text", query: `"_test"`, want: []string{"main_test.go"}},
+		{name: "ext", query: "ext:.go", want: []string{"main.go", "main_test.go", ".hidden.go", "test_util.go"}},
+		{name: "ext_list_without_dots", query: "ext:md,GO", want: []string{"main.go", "main_test.go", ".hidden.go", "test_util.go", "README.md"}},
+		{name: "flag", query: "dir", want: []string{"src", "sub"}},
+		{name: "flag_compared", query: "dir:false AND NOT ext:.go", want: []string{"README.md"}},
+		{name: "not_hidden", query: "ext:.go AND NOT hidden", want: []string{"main.go", "main_test.go", "test_util.go"}},
+		{name: "bang_not", query: "ext:.go !hidden", want: []string{"main.go", "main_test.go", "test_util.go"}},
+		{name: "regex", query: `name~"^test"`, want: []string{"test_util.go"}},
+		{name: "unquoted_regex", query: `name~_test\.go$`, want: []string{"main_test.go"}},
+		{name: "depth", query: "depth>1", want: []string{"test_util.go", "README.md"}},
+		{
+			name:  "request_example",
+			query: `ext:.go AND NOT hidden AND (name~"^test" OR depth>2)`,
+			want:  []string{"test_util.go"},
+		},
+		{name: "or_binds_looser", query: "dir OR ext:md hidden", want: []string{"src", "sub"}},
+		{name: "symbolic_operators", query: "(dir || ext:md) && depth>=1", want: []string{"sub", "README.md"}},
+		{name: "name_equals_case_insensitive", query: "name=readme.md", want: []string{"README.md"}},
+		{name: "name_not_equals", query: "file name!=readme.md depth=2", want: []string{"test_util.go"}},
+		{name: "size_units", query: "size>=2k", want: []string{"main_test.go", "test_util.go"}},
+		{name: "size_less_than", query: "file size<50", want: []string{".hidden.go", "README.md"}},
+		{name: "keywords_case_insensitive", query: "dir and not name:sub", want: []string{"src"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pred, err := ParseQuery[queryData](test.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error = %v", test.query, err)
+			}
+			if diff := cmp.Diff(test.want, queryMatches(t, tree, pred)); diff != "" {
+				t.Errorf("ParseQuery(%q) matches mismatch (-want +got):\n%s", test.query, diff)
+			}
+		})
+	}
+}
+
+func TestQueryParser_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantPos int
+		wantMsg string
+	}{
+		{name: "empty", query: "   ", wantPos: 0, wantMsg: "empty query"},
+		{name: "unknown_field", query: "ext:.go AND color=red", wantPos: 12, wantMsg: `unknown field "color"`},
+		{name: "missing_value", query: "name~", wantPos: 5, wantMsg: "expected value"},
+		{name: "bad_regex", query: "name~(", wantPos: 5, wantMsg: "name~"},
+		{name: "bad_number", query: "depth>x", wantPos: 6, wantMsg: "expected a number"},
+		{name: "unsupported_operator", query: "ext>3", wantPos: 4, wantMsg: "not supported"},
+		{name: "flag_needs_no_value", query: "depth", wantPos: 0, wantMsg: "operator required"},
+		{name: "unclosed_paren", query: "dir AND (file", wantPos: 8, wantMsg: "missing closing parenthesis"},
+		{name: "stray_paren", query: "dir)", wantPos: 3, wantMsg: "unexpected"},
+		{name: "unterminated_string", query: `name:"abc`, wantPos: 5, wantMsg: "unterminated string"},
+		{name: "dangling_operator", query: "dir AND", wantPos: 7, wantMsg: "unexpected end of query"},
+		{name: "single_ampersand", query: "dir & file", wantPos: 4, wantMsg: `expected "&&"`},
+		{name: "positions_count_runes", query: "äö AND nope=1", wantPos: 7, wantMsg: "unknown field"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseQuery[queryData](test.query)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("ParseQuery(%q) error = %v, want %v", test.query, err, ErrInvalidQuery)
+			}
+			var qerr *QueryError
+			if !errors.As(err, &qerr) {
+				t.Fatalf("ParseQuery(%q) error = %T, want *QueryError", test.query, err)
+			}
+			if qerr.Pos != test.wantPos {
+				t.Errorf("ParseQuery(%q) error position = %d, want %d (%v)", test.query, qerr.Pos, test.wantPos, err)
+			}
+			if !strings.Contains(qerr.Msg, test.wantMsg) {
+				t.Errorf("ParseQuery(%q) error message = %q, want it to contain %q", test.query, qerr.Msg, test.wantMsg)
+			}
+		})
+	}
+}
+
+func TestQueryParser_CustomField(t *testing.T) {
+	tree := createQueryTree()
+	big := func(op QueryOp, value string) (func(*Node[queryData]) bool, error) {
+		if op != QueryFlag {
+			return nil, errors.New("big takes no value")
+		}
+		return func(n *Node[queryData]) bool { return n.Data().size > 1000 }, nil
+	}
+	parser := NewQueryParser(WithQueryField("BIG", big))
+
+	pred, err := parser.Parse("big AND name:test")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []string{"main_test.go", "test_util.go"}
+	if diff := cmp.Diff(want, queryMatches(t, tree, pred)); diff != "" {
+		t.Errorf("Parse() matches mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := parser.Parse("big:1"); err == nil || !strings.Contains(err.Error(), "big takes no value") {
+		t.Errorf("Parse(big:1) error = %v, want the resolver error", err)
+	}
+}
+
+func TestQueryParser_Matcher(t *testing.T) {
+	ctx := context.Background()
+	tree := createQueryTree()
+	tree.matcher = NewQueryParser[queryData]().Matcher()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "dir", want: []string{"src", "sub"}},
+		{query: "ext:md", want: []string{"README.md"}},
+		{query: "name~(", want: []string{}},
+	}
+	for _, test := range tests {
+		got, err := tree.Search(ctx, test.query)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", test.query, err)
+		}
+		if diff := cmp.Diff(test.want, nodeIDs(got)); diff != "" {
+			t.Errorf("Search(%q) mismatch (-want +got):\n%s", test.query, diff)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
 )
 
@@ -94,29 +95,113 @@ func truncateLine(line string, maxWidth int) string {
 
 // renderNode implements the NodeRenderer interface. It asks the NodeProvider
 // for icon, label, and style information, then returns the final string for a
-// single line including tree-branch glyphs.
+// single line including tree-branch glyphs. Runes of the node's name covered
+// by ranges are highlighted with the provider's match style.
 //
 // The function is fast and does not allocate beyond what the provider allocates.
-func renderNode[T any](provider NodeProvider[T], node *Node[T], prefix string, isFocused bool, maxWidth int) (string, error) {
+func renderNode[T any](provider NodeProvider[T], node *Node[T], prefix string, isFocused bool, maxWidth int, ranges []MatchRange) (string, error) {
 	// Get the icon from the provider and ensure consistent width
 	// This keeps the tree aligned even with different icon widths
 	icon := NormalizeIconWidth(provider.Icon(node))
 
-	// Get the human-readable text for this node
-	displayText := provider.Format(node)
+	// Get the human-readable text for this node, and where its name starts
+	// when matches have to be highlighted
+	var displayText string
+	nameOffset := -1
+	if len(ranges) > 0 {
+		displayText, nameOffset = formatName(provider, node)
+	} else {
+		displayText = provider.Format(node)
+	}
 
 	// Get the appropriate style based on focus state
 	style := provider.Style(node, isFocused)
 
 	// Combine all parts and apply the style
 	// Result: "│   └── 📁 folder-name/" (styled)
-	result := style.Render(prefix + icon + displayText)
+	var result string
+	if len(ranges) > 0 {
+		result = renderHighlighted(provider, node, style, isFocused, prefix+icon, displayText, nameOffset, ranges)
+	} else {
+		result = style.Render(prefix + icon + displayText)
+	}
 
 	// Apply truncation if maxWidth is set
 	return truncateLine(result, maxWidth), nil
 }
 
-// renderTree walks the tree, turns every visible node into a line.
+// formatName returns the label of node and the byte offset of its name in
+// the label, or -1 if the name can't be located there.
+func formatName[T any](provider NodeProvider[T], node *Node[T]) (string, int) {
+	if formatter, ok := provider.(NameFormatter[T]); ok {
+		return formatter.FormatName(node)
+	}
+	label := provider.Format(node)
+	return label, nameIndex(label, node.Name())
+}
+
+// nameIndex returns the byte offset of name in label if it occurs exactly
+// once. Otherwise it returns -1, as there is no telling which occurrence is
+// the name.
+func nameIndex(label, name string) int {
+	i := strings.Index(label, name)
+	if i < 0 || strings.Contains(label[i+1:], name) {
+		return -1
+	}
+	return i
+}
+
+// renderHighlighted renders lead+label like style.Render but draws the runes
+// of the node's name selected by ranges with the match style. nameOffset is
+// the byte offset of the name in the formatted label, so providers that
+// decorate the name (for example a trailing "/") keep their highlighting.
+// Labels without a known name offset and styles with padding, borders or a
+// fixed width, which can't be split into segments, are rendered without
+// highlighting.
+func renderHighlighted[T any](provider NodeProvider[T], node *Node[T], style lipgloss.Style, isFocused bool, lead, label string, nameOffset int, ranges []MatchRange) string {
+	if node.Name() == "" || nameOffset < 0 || nameOffset > len(label) || style.GetHorizontalFrameSize() > 0 || style.GetWidth() > 0 {
+		return style.Render(lead + label)
+	}
+	runeOffset := utf8.RuneCountInString(label[:nameOffset])
+
+	// Each segment is styled on its own so a highlight never resets the
+	// surrounding style part-way through the line.
+	var match lipgloss.Style
+	if styler, ok := provider.(MatchStyler[T]); ok {
+		match = styler.MatchStyle(node, isFocused)
+	} else {
+		match = style.Underline(true)
+	}
+	base := style.Inline(true)
+	match = match.Inherit(style).Inline(true)
+
+	sb := sbPool.Get().(*strings.Builder)
+	defer func() {
+		sb.Reset()
+		sbPool.Put(sb)
+	}()
+
+	runes := []rune(label)
+	pos := 0
+	plain := lead
+	for _, r := range ranges {
+		start := min(max(r.Start+runeOffset, pos), len(runes))
+		end := min(r.End+runeOffset, len(runes))
+		if start >= end {
+			continue
+		}
+		sb.WriteString(base.Render(plain + string(runes[pos:start])))
+		sb.WriteString(match.Render(string(runes[start:end])))
+		plain, pos = "", end
+	}
+	if rest := plain + string(runes[pos:]); rest != "" {
+		sb.WriteString(base.Render(rest))
+	}
+	return sb.String()
+}
+
+// renderTree walks the tree, turns every visible node into a line. Callers
+// must hold tree.mu for reading.
 func renderTree[T any](ctx context.Context, tree *Tree[T]) (string, int, error) {
 	// Get a string builder from the pool for efficiency
 	sb := sbPool.Get().(*strings.Builder)
@@ -141,7 +226,8 @@ func renderTree[T any](ctx context.Context, tree *Tree[T]) (string, int, error)
 	// The slice index corresponds to the depth level
 	var ancestorIsLastChild []bool
 
-	for info, err := range tree.AllVisible(ctx) {
+	provider := tree.provider
+	for info, err := range tree.visibleSeq(ctx, nil) {
 		if err != nil {
 			return "", 0, err
 		}
@@ -164,16 +250,19 @@ func renderTree[T any](ctx context.Context, tree *Tree[T]) (string, int, error)
 		if depth > 0 {
 			prefix = buildPrefix(ancestorIsLastChild[:depth], isLast)
 		}
+		if tree.checkboxes {
+			prefix += tree.checkbox(node)
+		}
 
 		// Check if this node should be highlighted as focused
-		isFocused := tree.IsFocused(node.ID())
+		isFocused := tree.focusedIDs[node.ID()]
 		if isFocused && focusedLineIndex == -1 {
 			// Set focused line index to the first focused node for viewport positioning
 			focusedLineIndex = lineIdx
 		}
 
 		// Render the actual node content
-		line, err := renderNode(tree.provider, node, prefix, isFocused, tree.truncateWidth)
+		line, err := renderNode(provider, node, prefix, isFocused, tree.truncateWidth, tree.matchRanges(node.ID()))
 		if err != nil {
 			return sb.String(), focusedLineIndex, err
 		}
@@ -194,9 +283,24 @@ func renderTree[T any](ctx context.Context, tree *Tree[T]) (string, int, error)
 	return sb.String(), focusedLineIndex, nil
 }
 
+// lineOverrideFn lets callers replace the rendered line of a node, for example
+// with an input field while the node is being renamed. The prefix holds the
+// tree branch glyphs for the node. Returning false keeps the normal line.
+type lineOverrideFn[T any] func(node *Node[T], prefix string) (string, bool)
+
 // renderTreeWithViewport combines tree rendering with viewport scrolling.
 // It automatically positions the viewport to keep the focused line visible.
 func renderTreeWithViewport[T any](ctx context.Context, tree *Tree[T], vp *viewport.Model) (string, error) {
+	return renderTreeWithViewportOverride(ctx, tree, vp, nil)
+}
+
+// renderTreeWithViewportOverride is renderTreeWithViewport with an optional
+// per-line override. The tree is read-locked for the whole pass so both scans
+// see the same tree.
+func renderTreeWithViewportOverride[T any](ctx context.Context, tree *Tree[T], vp *viewport.Model, override lineOverrideFn[T]) (string, error) {
+	tree.mu.RLock()
+	defer tree.mu.RUnlock()
+
 	// First, find the focused line position to determine if we need to adjust the viewport
 	focusedLineIndex := findFocusedLineIndex(ctx, tree)
 
@@ -214,7 +318,7 @@ func renderTreeWithViewport[T any](ctx context.Context, tree *Tree[T], vp *viewp
 	}
 
 	// Now render only the visible portion with the correct viewport offset
-	content, totalLines, err := renderViewportOnly(ctx, tree, vp)
+	content, totalLines, err := renderViewportOnly(ctx, tree, vp, override)
 
 	// Update viewport's understanding of total content for scrollbar
 	// We use empty lines to set the height without the memory cost of actual content
@@ -225,14 +329,15 @@ func renderTreeWithViewport[T any](ctx context.Context, tree *Tree[T], vp *viewp
 }
 
 // findFocusedLineIndex quickly scans through the tree to find the focused line's position.
-// This is a lightweight operation that doesn't render anything.
+// This is a lightweight operation that doesn't render anything. Callers must
+// hold tree.mu for reading.
 func findFocusedLineIndex[T any](ctx context.Context, tree *Tree[T]) int {
 	lineIdx := 0
-	for info, err := range tree.AllVisible(ctx) {
+	for info, err := range tree.visibleSeq(ctx, nil) {
 		if err != nil {
 			return -1
 		}
-		if tree.IsFocused(info.Node.ID()) {
+		if tree.focusedIDs[info.Node.ID()] {
 			return lineIdx
 		}
 		lineIdx++
@@ -249,7 +354,8 @@ func findFocusedLineIndex[T any](ctx context.Context, tree *Tree[T]) int {
 
 // renderViewportOnly efficiently renders only the visible lines in the viewport
 // in a single pass through the tree. Returns the rendered content, total line count, and any error.
-func renderViewportOnly[T any](ctx context.Context, tree *Tree[T], vp *viewport.Model) (string, int, error) {
+// Callers must hold tree.mu for reading.
+func renderViewportOnly[T any](ctx context.Context, tree *Tree[T], vp *viewport.Model, override lineOverrideFn[T]) (string, int, error) {
 	// Get a string builder from the pool for efficiency
 	sb := sbPool.Get().(*strings.Builder)
 	defer func() {
@@ -270,7 +376,8 @@ func renderViewportOnly[T any](ctx context.Context, tree *Tree[T], vp *viewport.
 	// line (│) or a space when building the tree prefix.
 	var ancestorIsLastChild []bool
 
-	for info, err := range tree.AllVisible(ctx) {
+	provider := tree.provider
+	for info, err := range tree.visibleSeq(ctx, nil) {
 		if err != nil {
 			return "", currentLine, err
 		}
@@ -297,14 +404,26 @@ func renderViewportOnly[T any](ctx context.Context, tree *Tree[T], vp *viewport.
 			if depth > 0 {
 				prefix = buildPrefix(ancestorIsLastChild[:depth], isLast)
 			}
+			if tree.checkboxes {
+				prefix += tree.checkbox(node)
+			}
+
+			// Let the caller replace this line entirely if it wants to
+			var line string
+			overridden := false
+			if override != nil {
+				line, overridden = override(node, prefix)
+			}
 
-			// Check if this node is focused
-			isFocused := tree.IsFocused(node.ID())
+			if !overridden {
+				// Check if this node is focused
+				isFocused := tree.focusedIDs[node.ID()]
 
-			// Render the actual node content
-			line, err := renderNode(tree.provider, node, prefix, isFocused, tree.truncateWidth)
-			if err != nil {
-				return sb.String(), currentLine, err
+				// Render the actual node content
+				line, err = renderNode(provider, node, prefix, isFocused, tree.truncateWidth, tree.matchRanges(node.ID()))
+				if err != nil {
+					return sb.String(), currentLine, err
+				}
 			}
 			renderBuffer = append(renderBuffer, line)
 		}
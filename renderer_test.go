@@ -3,12 +3,15 @@ package treeview
 import (
 	"context"
 	"errors"
+	"io"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/google/go-cmp/cmp"
+	"github.com/muesli/termenv"
 )
 
 // Mock data type for testing
@@ -162,7 +165,7 @@ func TestRenderNode(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got, err := renderNode(test.provider, test.node, test.prefix, test.isFocused, 0)
+			got, err := renderNode(test.provider, test.node, test.prefix, test.isFocused, 0, nil)
 
 			if (err != nil) != test.wantErr {
 				t.Errorf("renderNode() error = %v, wantErr %v", err, test.wantErr)
@@ -176,6 +179,132 @@ func TestRenderNode(t *testing.T) {
 	}
 }
 
+// underlinedRune matches a bold and underlined "m" as drawn for a highlight.
+var underlinedRune = regexp.MustCompile(`\x1b\[1(;4)+mm\x1b\[0m`)
+
+func TestRenderNode_Highlight(t *testing.T) {
+	// Force ANSI output so the styling can be inspected
+	renderer := lipgloss.NewRenderer(io.Discard)
+	renderer.SetColorProfile(termenv.ANSI)
+	base := renderer.NewStyle().Bold(true)
+	provider := &mockProvider{
+		formatFunc: func(n *Node[mockData]) string { return n.Name() + "/" },
+		styleFunc:  func(*Node[mockData], bool) lipgloss.Style { return base },
+	}
+	node := NewNode("id", "main.go", mockData{name: "main.go"})
+	ranges := []MatchRange{{Start: 0, End: 1}, {Start: 5, End: 7}}
+
+	tests := []struct {
+		name     string
+		maxWidth int
+		want     string
+	}{
+		{name: "full_line", maxWidth: 0, want: "├── 📁 main.go/"},
+		{name: "truncated", maxWidth: 12, want: "├── 📁 ma..."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := renderNode[mockData](provider, node, "├── ", false, test.maxWidth, ranges)
+			if err != nil {
+				t.Fatalf("renderNode() error = %v", err)
+			}
+			if plain := stripANSI(got); plain != test.want {
+				t.Errorf("renderNode() text = %q, want %q", plain, test.want)
+			}
+			if !underlinedRune.MatchString(got) {
+				t.Errorf("renderNode() = %q, want the first rune underlined on top of the bold base style", got)
+			}
+		})
+	}
+}
+
+// highlightedText matches a bold and underlined highlight and captures its
+// text.
+var highlightedText = regexp.MustCompile(`\x1b\[1(?:;4)+m([^\x1b]*)\x1b\[0m`)
+
+func TestRenderNode_HighlightNameOffset(t *testing.T) {
+	renderer := lipgloss.NewRenderer(io.Discard)
+	renderer.SetColorProfile(termenv.ANSI)
+	base := renderer.NewStyle().Bold(true)
+	mock := func(format func(*Node[mockData]) string) NodeProvider[mockData] {
+		return &mockProvider{
+			formatFunc: format,
+			styleFunc:  func(*Node[mockData], bool) lipgloss.Style { return base },
+		}
+	}
+	named := NewDefaultNodeProvider(WithNameFormatter(func(n *Node[mockData]) (string, int, bool) {
+		return "[" + n.Name() + "] " + n.Name(), len(n.Name()) + 3, true
+	}))
+	named.SetDefaultStyle(base)
+	named.SetMatchStyle(renderer.NewStyle().Underline(true), renderer.NewStyle().Underline(true))
+
+	tests := []struct {
+		name       string
+		provider   NodeProvider[mockData]
+		wantBefore string // Label text before the highlight, empty for none
+		wantText   string
+	}{
+		{
+			name:       "reported_offset",
+			provider:   named,
+			wantBefore: "[m] ",
+			wantText:   "m",
+		},
+		{
+			name:       "unique_name",
+			provider:   mock(func(n *Node[mockData]) string { return "x: " + n.Name() }),
+			wantBefore: "x: ",
+			wantText:   "m",
+		},
+		{
+			name:     "name_repeated_in_prefix",
+			provider: mock(func(n *Node[mockData]) string { return n.Name() + ": " + n.Name() }),
+		},
+	}
+
+	node := NewNode("id", "m", mockData{name: "m"})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := renderNode(test.provider, node, "", false, 0, []MatchRange{{Start: 0, End: 1}})
+			if err != nil {
+				t.Fatalf("renderNode() error = %v", err)
+			}
+			loc := highlightedText.FindStringSubmatchIndex(got)
+			if test.wantText == "" {
+				if loc != nil {
+					t.Errorf("renderNode() = %q, want no highlight", got)
+				}
+				return
+			}
+			if loc == nil {
+				t.Fatalf("renderNode() = %q, want a highlight", got)
+			}
+			if before := stripANSI(got[:loc[0]]); !strings.HasSuffix(before, test.wantBefore) {
+				t.Errorf("text before the highlight = %q, want it to end in %q", before, test.wantBefore)
+			}
+			if text := got[loc[2]:loc[3]]; text != test.wantText {
+				t.Errorf("highlighted text = %q, want %q", text, test.wantText)
+			}
+		})
+	}
+}
+
+func TestRenderNode_HighlightSkipsFramedStyles(t *testing.T) {
+	provider := &mockProvider{
+		styleFunc: func(*Node[mockData], bool) lipgloss.Style { return lipgloss.NewStyle().PaddingLeft(2) },
+	}
+	node := NewNode("id", "main.go", mockData{name: "main.go"})
+
+	got, err := renderNode[mockData](provider, node, "", false, 0, []MatchRange{{Start: 0, End: 4}})
+	if err != nil {
+		t.Fatalf("renderNode() error = %v", err)
+	}
+	if want := "  📁 main.go"; got != want {
+		t.Errorf("renderNode() = %q, want %q", got, want)
+	}
+}
+
 // Create fresh nodes for each test case to avoid state sharing
 func createTestTree() (*Node[mockData], *Node[mockData], *Node[mockData], *Node[mockData], *Node[mockData]) {
 	rootNode := NewNode("root", "root", mockData{name: "root"})
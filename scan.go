@@ -0,0 +1,342 @@
+package treeview
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// SearchScan is a search that can be suspended when its context ends and
+// resumed later with a new one, so a slow search over a large tree can be
+// spread over several frames instead of being cut short. Create one with
+// Tree.StartSearch.
+//
+// A scan walks the tree as it was when each node is reached; nodes added behind
+// the scan position are not seen until the next search, and nodes removed while
+// they were evaluated are dropped from the results. A scan is not safe for
+// concurrent use, but one goroutine may run it while others use the tree. A
+// sequential scan evaluates chunks of nodes under the tree's read lock,
+// releasing it in between, so its matcher must not call methods of the tree.
+// With WithSearchWorkers each batch is copied under the lock and the workers
+// evaluate the copies without it, so slow matchers such as ContentMatcher don't
+// hold up writers. A copy has the ID, name, payload and state of its node and
+// copies of its ancestors, as they were when the scan first reached them, as
+// parents; its children are the live nodes, which matchers must not read beyond
+// HasChildren.
+type SearchScan[T any] struct {
+	term    string
+	match   MatchFn[T]
+	workers int
+	tree    *Tree[T] // The live tree, or nil when scanning a snapshot
+
+	// ancestors holds the copies of ancestors given to the workers, by live
+	// node; see detach.
+	ancestors map[*Node[T]]*Node[T]
+
+	// frames is the depth-first position: the sibling lists being walked and
+	// the index of the next node in each. Index candidates are a single frame
+	// whose children are not visited.
+	frames   []scanFrame[T]
+	saved    []scanFrame[T]
+	children bool
+
+	results []SearchResult[T]
+	scanned int
+}
+
+// scanFrame is a sibling list and the position of the next node to visit.
+type scanFrame[T any] struct {
+	nodes []*Node[T]
+	next  int
+}
+
+// scanChunk is the number of nodes a sequential scan evaluates under one
+// read lock, and each worker evaluates between checks of the context when the
+// matcher runs concurrently (see WithSearchWorkers). A concurrent step must
+// leave time for one chunk or it makes no progress; a sequential one keeps
+// the nodes it finished.
+const scanChunk = 8
+
+// StartSearch prepares a resumable search for term. A nil match uses the
+// configured matcher and, if enabled, the search index, whose candidates are
+// looked up here. No node is evaluated until SearchScan.Continue is called.
+// Returns context errors unwrapped.
+func (t *Tree[T]) StartSearch(ctx context.Context, term string, match MatchFn[T]) (*SearchScan[T], error) {
+	s := t.newSearchScan(t.Nodes(), term, match, t)
+	if term == "" || match != nil || t.index == nil {
+		return s, nil
+	}
+
+	// Only the configured matcher may use the index; others, such as a regex
+	// or fuzzy matcher, can match nodes that don't contain the term
+	t.mu.RLock()
+	nodes, err := t.index.candidates(ctx, t.nodes, term)
+	t.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	s.frames = []scanFrame[T]{{nodes: nodes}}
+	s.children = false
+	return s, nil
+}
+
+// newSearchScan prepares a scan of everything below roots, reading node
+// state under the lock of live unless it is nil. A nil match uses the
+// configured matcher.
+func (t *Tree[T]) newSearchScan(roots []*Node[T], term string, match MatchFn[T], live *Tree[T]) *SearchScan[T] {
+	s := &SearchScan[T]{
+		term:     term,
+		match:    match,
+		workers:  max(t.searchWorkers, 1),
+		tree:     live,
+		frames:   []scanFrame[T]{{nodes: roots}},
+		children: true,
+	}
+	if match == nil {
+		s.match = t.match
+	}
+	if term == "" {
+		s.frames = nil
+	}
+	return s
+}
+
+// Continue evaluates nodes until the scan is finished or ctx ends. It returns
+// nil once the scan is finished, or the context error unwrapped if it stopped
+// early; calling it again with a fresh context picks up where it left off.
+// Nodes whose evaluation was interrupted are evaluated again, since the
+// matcher may have given up on them.
+func (s *SearchScan[T]) Continue(ctx context.Context) error {
+	batch := make([]*Node[T], 0, s.workers*scanChunk)
+	for !s.Done() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s.saved = append(s.saved[:0], s.frames...)
+		batch = batch[:0]
+		s.lock()
+		for len(batch) < cap(batch) {
+			node, ok := s.pop()
+			if !ok {
+				break
+			}
+			batch = append(batch, node)
+		}
+
+		if s.workers <= 1 {
+			found, n := s.evaluateLocked(ctx, batch)
+			if n < len(batch) {
+				// Put back the nodes not evaluated to the end
+				s.frames = append(s.frames[:0], s.saved...)
+				for range n {
+					s.pop()
+				}
+			}
+			s.unlock()
+			s.results = append(s.results, found...)
+			s.scanned += n
+			if n < len(batch) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		// The workers read copies, so the lock isn't held while they run
+		copies := s.detach(batch)
+		s.unlock()
+		found := s.evaluate(ctx, batch, copies)
+		if err := ctx.Err(); err != nil {
+			s.frames = append(s.frames[:0], s.saved...)
+			return err
+		}
+		s.results = append(s.results, s.attached(found)...)
+		s.scanned += len(batch)
+	}
+	return nil
+}
+
+// lock and unlock take and release the read lock of the live tree, if any.
+func (s *SearchScan[T]) lock() {
+	if s.tree != nil {
+		s.tree.mu.RLock()
+	}
+}
+
+func (s *SearchScan[T]) unlock() {
+	if s.tree != nil {
+		s.tree.mu.RUnlock()
+	}
+}
+
+// detach returns copies of the live nodes of batch for the workers to read
+// without the lock, or batch itself when scanning a snapshot. Ancestors are
+// copied once per scan, as they were when first reached. Callers must hold
+// the lock.
+func (s *SearchScan[T]) detach(batch []*Node[T]) []*Node[T] {
+	if s.tree == nil {
+		return batch
+	}
+	slab := make([]Node[T], len(batch))
+	copies := make([]*Node[T], len(batch))
+	for i, node := range batch {
+		slab[i] = detachedCopy(node)
+		slab[i].parent = s.ancestor(node.parent)
+		copies[i] = &slab[i]
+	}
+	return copies
+}
+
+// ancestor returns the shared copy of node, an ancestor of scanned nodes,
+// making it on first use. Callers must hold the lock.
+func (s *SearchScan[T]) ancestor(node *Node[T]) *Node[T] {
+	if node == nil {
+		return nil
+	}
+	if c, ok := s.ancestors[node]; ok {
+		return c
+	}
+	if s.ancestors == nil {
+		s.ancestors = make(map[*Node[T]]*Node[T])
+	}
+	c := detachedCopy(node)
+	c.parent = s.ancestor(node.parent)
+	s.ancestors[node] = &c
+	return &c
+}
+
+// detachedCopy copies the ID, name, payload, children and state of node,
+// without its parent.
+func detachedCopy[T any](node *Node[T]) Node[T] {
+	return Node[T]{
+		id:       node.id,
+		name:     node.name,
+		data:     node.data,
+		children: node.children,
+		expanded: node.expanded,
+		visible:  node.visible,
+	}
+}
+
+// attached drops the matches whose nodes were removed from the live tree
+// while they were evaluated.
+func (s *SearchScan[T]) attached(found []SearchResult[T]) []SearchResult[T] {
+	if s.tree == nil || len(found) == 0 {
+		return found
+	}
+	s.lock()
+	defer s.unlock()
+	return slices.DeleteFunc(found, func(r SearchResult[T]) bool {
+		return !s.tree.contains(r.Node)
+	})
+}
+
+// pop returns the next node in depth-first order.
+func (s *SearchScan[T]) pop() (*Node[T], bool) {
+	for len(s.frames) > 0 {
+		top := &s.frames[len(s.frames)-1]
+		if top.next >= len(top.nodes) {
+			s.frames = s.frames[:len(s.frames)-1]
+			continue
+		}
+		node := top.nodes[top.next]
+		top.next++
+		if s.children && node.HasChildren() {
+			s.frames = append(s.frames, scanFrame[T]{nodes: node.Children()})
+		}
+		return node, true
+	}
+	return nil, false
+}
+
+// evaluateLocked runs the matcher on the nodes of batch in order and returns
+// the matches and the number of nodes evaluated before ctx ended. The node
+// that saw ctx end is not counted, since the matcher may have given up on it.
+// Callers must hold the lock.
+func (s *SearchScan[T]) evaluateLocked(ctx context.Context, batch []*Node[T]) ([]SearchResult[T], int) {
+	var found []SearchResult[T]
+	for i, node := range batch {
+		m, ok := s.match(ctx, node, s.term)
+		if ctx.Err() != nil {
+			return found, i
+		}
+		if ok {
+			found = append(found, SearchResult[T]{Node: node, Match: m})
+		}
+	}
+	return found, len(batch)
+}
+
+// evaluate runs the matcher on the workers for copies, the copies of the
+// nodes of batch, and returns the matching nodes of batch in batch order.
+func (s *SearchScan[T]) evaluate(ctx context.Context, batch, copies []*Node[T]) []SearchResult[T] {
+	var found []SearchResult[T]
+	for i, o := range matchConcurrently(ctx, copies, s.term, s.match, s.workers) {
+		if o.ok {
+			found = append(found, SearchResult[T]{Node: batch[i], Match: o.match})
+		}
+	}
+	return found
+}
+
+// matchOutcome is the verdict of a matcher on one node.
+type matchOutcome struct {
+	match Match
+	ok    bool
+}
+
+// matchConcurrently evaluates match on nodes with a pool of workers
+// goroutines that take the nodes in order, and returns the outcomes by
+// position. Once ctx ends no further nodes are handed out; the outcomes of
+// those are left empty.
+func matchConcurrently[T any](ctx context.Context, nodes []*Node[T], term string, match MatchFn[T], workers int) []matchOutcome {
+	outcomes := make([]matchOutcome, len(nodes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(nodes)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				m, ok := match(ctx, nodes[i], term)
+				outcomes[i] = matchOutcome{match: m, ok: ok}
+			}
+		}()
+	}
+
+	for i := 0; i < len(nodes) && ctx.Err() == nil; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return outcomes
+}
+
+// Done reports whether every node has been evaluated.
+func (s *SearchScan[T]) Done() bool {
+	return len(s.frames) == 0
+}
+
+// Scanned returns the number of nodes evaluated so far.
+func (s *SearchScan[T]) Scanned() int {
+	return s.scanned
+}
+
+// Term returns the term being searched for.
+func (s *SearchScan[T]) Term() string {
+	return s.term
+}
+
+// Results returns the matches found so far, best scores first with ties in
+// tree order.
+func (s *SearchScan[T]) Results() []SearchResult[T] {
+	results := slices.Clone(s.results)
+	slices.SortStableFunc(results, func(a, b SearchResult[T]) int {
+		return cmp.Compare(b.Match.Score, a.Match.Score)
+	})
+	return results
+}
@@ -0,0 +1,161 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// slowMatcher matches names containing the term after sleeping for d. Like a
+// matcher reading files, it gives up without a match when ctx ends.
+func slowMatcher(d time.Duration) MatchFn[string] {
+	return func(ctx context.Context, node *Node[string], term string) (Match, bool) {
+		select {
+		case <-ctx.Done():
+			return Match{}, false
+		case <-time.After(d):
+		}
+		ranges := substringRanges(node.Name(), term)
+		return Match{Ranges: ranges}, ranges != nil
+	}
+}
+
+// createScanTree returns a tree of 4 roots with 5 children each; every third
+// node is named "hit".
+func createScanTree(opts ...Option[string]) *Tree[string] {
+	var roots []*Node[string]
+	n := 0
+	name := func() string {
+		n++
+		if n%3 == 0 {
+			return "hit"
+		}
+		return "miss"
+	}
+	for i := range 4 {
+		root := NewNode(fmt.Sprint(i), name(), "")
+		for j := range 5 {
+			root.AddChild(NewNode(fmt.Sprintf("%d.%d", i, j), name(), ""))
+		}
+		roots = append(roots, root)
+	}
+	return NewTree(roots, opts...)
+}
+
+func TestSearchScan_Resume(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("workers_%d", workers), func(t *testing.T) {
+			tree := createScanTree(WithMatcher(slowMatcher(time.Millisecond)), WithSearchWorkers[string](workers))
+			want, err := tree.Search(context.Background(), "hit")
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+
+			scan, err := tree.StartSearch(context.Background(), "hit", nil)
+			if err != nil {
+				t.Fatalf("StartSearch() error = %v", err)
+			}
+			steps := 0
+			for {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+				err := scan.Continue(ctx)
+				cancel()
+				steps++
+				if err == nil {
+					break
+				}
+				if err != context.DeadlineExceeded {
+					t.Fatalf("Continue() error = %v", err)
+				}
+				if steps > 100 {
+					t.Fatal("Continue() made no progress")
+				}
+			}
+
+			if !scan.Done() {
+				t.Error("Done() = false after Continue() returned nil")
+			}
+			if got := scan.Scanned(); got != 24 {
+				t.Errorf("Scanned() = %d, want 24", got)
+			}
+			var got []*Node[string]
+			for _, result := range scan.Results() {
+				got = append(got, result.Node)
+			}
+			if diff := cmp.Diff(nodeIDs(want), nodeIDs(got)); diff != "" {
+				t.Errorf("resumed results mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchScan_EmptyTerm(t *testing.T) {
+	scan, err := createScanTree().StartSearch(context.Background(), "", nil)
+	if err != nil {
+		t.Fatalf("StartSearch() error = %v", err)
+	}
+	if !scan.Done() {
+		t.Error("Done() = false for an empty term")
+	}
+	if err := scan.Continue(context.Background()); err != nil {
+		t.Errorf("Continue() error = %v", err)
+	}
+	if got := scan.Results(); len(got) != 0 {
+		t.Errorf("Results() = %v, want none", got)
+	}
+}
+
+// The matcher runs without the tree lock, so writers aren't held up by it,
+// and matches removed while they were evaluated are dropped.
+func TestSearchScan_WorkersEvaluateWithoutLock(t *testing.T) {
+	ctx := context.Background()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	block := func(ctx context.Context, node *Node[string], term string) (Match, bool) {
+		if node.ID() == "0" {
+			close(entered)
+			<-release
+		}
+		return Match{}, true
+	}
+	tree := createScanTree(WithSearchWorkers[string](2))
+
+	scan, err := tree.StartSearch(ctx, "x", block)
+	if err != nil {
+		t.Fatalf("StartSearch() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- scan.Continue(ctx) }()
+	<-entered
+
+	removed := make(chan error, 1)
+	go func() {
+		_, err := tree.RemoveNode(ctx, "0")
+		removed <- err
+	}()
+	select {
+	case err := <-removed:
+		if err != nil {
+			t.Fatalf("RemoveNode() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RemoveNode() blocked while the matcher ran")
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Continue() error = %v", err)
+	}
+	for _, result := range scan.Results() {
+		if result.Node.ID() == "0" {
+			t.Errorf("Results() contains node %q removed during evaluation", "0")
+		}
+	}
+	// The removed node's children are dropped too; the other roots remain
+	if got := len(scan.Results()); got != 18 {
+		t.Errorf("len(Results()) = %d, want 18", got)
+	}
+}
@@ -0,0 +1,315 @@
+package treeview
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SearchMode selects how the TUI interprets the search term.
+type SearchMode int
+
+const (
+	// SearchSubstring uses the tree's configured matcher, by default a
+	// case-insensitive substring search.
+	SearchSubstring SearchMode = iota
+	// SearchCaseSensitive matches names containing the exact term.
+	SearchCaseSensitive
+	// SearchWholeWord matches the term as a whole word, ignoring case.
+	SearchWholeWord
+	// SearchRegex treats the term as a regular expression.
+	SearchRegex
+	// SearchFuzzy uses the fzf-style FuzzyMatcher.
+	SearchFuzzy
+	// SearchQuery treats the term as a query; see QueryParser.
+	SearchQuery
+)
+
+// String returns the label shown in the search prompt.
+func (s SearchMode) String() string {
+	switch s {
+	case SearchSubstring:
+		return "substring"
+	case SearchCaseSensitive:
+		return "case-sensitive"
+	case SearchWholeWord:
+		return "whole word"
+	case SearchRegex:
+		return "regex"
+	case SearchFuzzy:
+		return "fuzzy"
+	case SearchQuery:
+		return "query"
+	default:
+		return "unknown"
+	}
+}
+
+// defaultSearchModes are the modes the TUI cycles through unless
+// WithTuiSearchModes says otherwise.
+var defaultSearchModes = []SearchMode{
+	SearchSubstring, SearchCaseSensitive, SearchWholeWord, SearchRegex, SearchFuzzy,
+}
+
+// RegexMatcher returns a MatchFn that matches re against the node's Name and,
+// failing that, its ID. The search term is ignored. Every match in the name
+// is highlighted; if re has a capturing group, only the first group is.
+func RegexMatcher[T any](re *regexp.Regexp) MatchFn[T] {
+	return func(_ context.Context, node *Node[T], _ string) (Match, bool) {
+		name := node.Name()
+		locs := re.FindAllStringSubmatchIndex(name, -1)
+		if locs == nil {
+			return Match{}, re.MatchString(node.ID())
+		}
+
+		var m Match
+		for _, loc := range locs {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if start == end {
+				continue // Nothing to highlight for empty matches such as "^"
+			}
+			m.Ranges = append(m.Ranges, MatchRange{
+				Start: utf8.RuneCountInString(name[:start]),
+				End:   utf8.RuneCountInString(name[:end]),
+			})
+		}
+		return m, true
+	}
+}
+
+// wholeWordMatcher matches term as a whole word in the node's Name and,
+// failing that, its ID, ignoring case.
+func wholeWordMatcher[T any](term string) MatchFn[T] {
+	return func(_ context.Context, node *Node[T], _ string) (Match, bool) {
+		if ranges := wholeWordRanges(node.Name(), term); ranges != nil {
+			return Match{Ranges: ranges}, true
+		}
+		return Match{}, wholeWordRanges(node.ID(), term) != nil
+	}
+}
+
+// wholeWordRanges returns the case-insensitive occurrences of term in text
+// that don't continue a word: an edge of the term that is a letter, digit or
+// underscore must not touch another such rune. Like \b, this lets ".go" match
+// the end of "main.go".
+func wholeWordRanges(text, term string) []MatchRange {
+	runes := []rune(text)
+	pattern := []rune(term)
+	if len(pattern) == 0 {
+		return nil
+	}
+	isWord := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	touchesWord := func(i int) bool {
+		return i >= 0 && i < len(runes) && isWord(runes[i])
+	}
+	checkStart, checkEnd := isWord(pattern[0]), isWord(pattern[len(pattern)-1])
+
+	var ranges []MatchRange
+	for _, r := range substringRanges(text, term) {
+		if checkStart && touchesWord(r.Start-1) || checkEnd && touchesWord(r.End) {
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// searchMatcher returns the matcher for the current mode and term. It fails
+// for terms the mode can't interpret, such as an invalid regular expression.
+func (m *TuiTreeModel[T]) searchMatcher(term string) (MatchFn[T], error) {
+	switch m.searchMode {
+	case SearchCaseSensitive:
+		return RegexMatcher[T](regexp.MustCompile(regexp.QuoteMeta(term))), nil
+	case SearchWholeWord:
+		return wholeWordMatcher[T](term), nil
+	case SearchRegex:
+		re, err := regexp.Compile(term)
+		if err != nil {
+			return nil, err
+		}
+		return RegexMatcher[T](re), nil
+	case SearchFuzzy:
+		return FuzzyMatcher[T](), nil
+	case SearchQuery:
+		if m.queryParser == nil {
+			m.queryParser = NewQueryParser[T]()
+		}
+		pred, err := m.queryParser.Parse(term)
+		if err != nil {
+			return nil, err
+		}
+		return PredicateMatcher(pred), nil
+	default:
+		return nil, nil // The tree's matcher, which may use its search index
+	}
+}
+
+// SearchMode returns the active search mode.
+func (m *TuiTreeModel[T]) SearchMode() SearchMode {
+	return m.searchMode
+}
+
+// SetSearchMode switches the search mode and re-runs the current search.
+func (m *TuiTreeModel[T]) SetSearchMode(mode SearchMode) {
+	m.searchMode = mode
+	if m.showSearch || m.searchTerm != "" {
+		_, _ = m.Search(m.searchTerm)
+	}
+}
+
+// CycleSearchMode moves to the next (or, with a negative step, previous) of
+// the configured search modes and re-runs the current search.
+func (m *TuiTreeModel[T]) CycleSearchMode(step int) {
+	if len(m.searchModes) == 0 {
+		return
+	}
+	i := slices.Index(m.searchModes, m.searchMode)
+	n := len(m.searchModes)
+	m.SetSearchMode(m.searchModes[((i+step)%n+n)%n])
+}
+
+// NextMatch focuses the next result of the last search, wrapping around after
+// the last one. Collapsed ancestors are expanded so the match is on screen.
+func (m *TuiTreeModel[T]) NextMatch() {
+	m.stepMatch(1)
+}
+
+// PrevMatch focuses the previous result of the last search, wrapping around
+// before the first one.
+func (m *TuiTreeModel[T]) PrevMatch() {
+	m.stepMatch(-1)
+}
+
+// MatchPosition returns the 1-based position of the current match and the
+// number of matches of the last search. Both are 0 when there are no matches.
+func (m *TuiTreeModel[T]) MatchPosition() (current, total int) {
+	if len(m.searchMatches) == 0 {
+		return 0, 0
+	}
+	return m.matchIndex + 1, len(m.searchMatches)
+}
+
+// stepMatch moves the current match by step and focuses it. Matches are
+// visited in result order, so scored searches go from best to worst.
+func (m *TuiTreeModel[T]) stepMatch(step int) {
+	n := len(m.searchMatches)
+	if n == 0 {
+		return
+	}
+	m.matchIndex = ((m.matchIndex+step)%n + n) % n
+	id := m.searchMatches[m.matchIndex].ID()
+
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		if !m.IsFiltering() {
+			// Filtered matches are always shown
+			if err := m.Reveal(ctx, id); err != nil {
+				return err
+			}
+		}
+		_, err := m.SetFocusedID(ctx, id)
+		return err
+	})
+}
+
+// Searching reports whether a search that ran out of time is still
+// continuing in the background. Its matches so far are already shown.
+func (m *TuiTreeModel[T]) Searching() bool {
+	return m.searching
+}
+
+// SearchPartial reports whether the last search was stopped before the whole
+// tree was scanned (see WithTuiSearchLimit), so more nodes may match.
+func (m *TuiTreeModel[T]) SearchPartial() bool {
+	return m.searchPartial
+}
+
+// searchScanMsg reports that a background search step finished.
+type searchScanMsg struct {
+	seq        uint64
+	err        error
+	progressed bool
+}
+
+// stopScan abandons the background search, if any. A step still running
+// finishes on its own and its message is ignored.
+func (m *TuiTreeModel[T]) stopScan() {
+	m.scan = nil
+	m.scanSeq++
+	m.searching = false
+	m.searchPartial = false
+}
+
+// continueScan returns a command running the next step of the background
+// search, or nil if there is none. Each step gets the search timeout.
+func (m *TuiTreeModel[T]) continueScan() tea.Cmd {
+	if m.scan == nil {
+		return nil
+	}
+	scan, seq, timeout := m.scan, m.scanSeq, m.searchTimeout
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		before := scan.Scanned()
+		err := scan.Continue(ctx)
+		return searchScanMsg{seq: seq, err: err, progressed: scan.Scanned() > before}
+	}
+}
+
+// handleScanMsg shows the matches of a finished background step and
+// schedules the next one until the scan is done, makes no progress or
+// exceeds the search limit.
+func (m *TuiTreeModel[T]) handleScanMsg(msg searchScanMsg) tea.Cmd {
+	if msg.seq != m.scanSeq || m.scan == nil {
+		return nil // Superseded by a newer search
+	}
+	m.applySearchResults(m.scan.Term(), m.scan.Results())
+
+	switch {
+	case msg.err == nil:
+		m.scan = nil
+		m.searching = false
+		return nil
+	case !msg.progressed, m.searchLimit > 0 && time.Since(m.scanStarted) >= m.searchLimit:
+		m.scan = nil
+		m.searching = false
+		m.searchPartial = true
+		return nil
+	}
+	return m.continueScan()
+}
+
+// applySearchResults expands towards or filters the matches of a search for
+// term and makes them the current results, keeping the current match if it
+// still exists.
+func (m *TuiTreeModel[T]) applySearchResults(term string, results []SearchResult[T]) {
+	var current *Node[T]
+	if m.matchIndex < len(m.searchMatches) {
+		current = m.searchMatches[m.matchIndex]
+	}
+
+	var matches []*Node[T]
+	switch {
+	case m.filterMode && term == "":
+		m.ClearFilter()
+	case m.filterMode:
+		matches = m.filterMatches(results)
+	case term == "":
+		m.ClearSearch()
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), m.searchTimeout)
+		defer cancel()
+		matches, _ = m.expandMatches(ctx, results)
+	}
+	m.searchMatches = matches
+	m.matchIndex = max(slices.Index(matches, current), 0)
+}
@@ -0,0 +1,25 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// BenchmarkSearch runs an unindexed search over a tree of about 110k nodes.
+func BenchmarkSearch(b *testing.B) {
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers_%d", workers), func(sb *testing.B) {
+			tree := buildDeepTree(5, 10)
+			tree.searchWorkers = workers
+			ctx := context.Background()
+			sb.ReportAllocs()
+			sb.ResetTimer()
+			for i := 0; i < sb.N; i++ {
+				if _, err := tree.Search(ctx, "n7_"); err != nil {
+					sb.Fatalf("Search() error = %v", err)
+				}
+			}
+		})
+	}
+}
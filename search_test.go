@@ -0,0 +1,526 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-cmp/cmp"
+)
+
+// createSearchModel returns a model over flat nodes for search mode tests.
+func createSearchModel(opts ...TuiTreeModelOption[string]) *TuiTreeModel[string] {
+	var nodes []*Node[string]
+	for _, name := range []string{"Main.go", "main_test.go", "domain.txt", "go.mod", "cargo"} {
+		nodes = append(nodes, NewNode(name, name, ""))
+	}
+	return NewTuiTreeModel(NewTree(nodes), opts...)
+}
+
+func TestRegexMatcher(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		pattern    string
+		node       *Node[string]
+		wantOK     bool
+		wantRanges []MatchRange
+	}{
+		{
+			name:       "all_matches_in_name",
+			pattern:    "a.",
+			node:       NewNode("id", "banana", ""),
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 1, End: 3}, {Start: 3, End: 5}},
+		},
+		{
+			name:       "capturing_group",
+			pattern:    `\.(go)$`,
+			node:       NewNode("id", "main.go", ""),
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 5, End: 7}},
+		},
+		{
+			name:       "rune_offsets",
+			pattern:    "ö+",
+			node:       NewNode("id", "äöö", ""),
+			wantOK:     true,
+			wantRanges: []MatchRange{{Start: 1, End: 3}},
+		},
+		{name: "empty_match", pattern: "^", node: NewNode("id", "x", ""), wantOK: true},
+		{name: "id_fallback", pattern: "^path/", node: NewNode("path/x", "x", ""), wantOK: true},
+		{name: "no_match", pattern: "z", node: NewNode("id", "x", ""), wantOK: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := RegexMatcher[string](regexp.MustCompile(test.pattern))(ctx, test.node, "")
+			if ok != test.wantOK {
+				t.Fatalf("RegexMatcher(%q) ok = %v, want %v", test.pattern, ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.wantRanges, got.Ranges); diff != "" {
+				t.Errorf("RegexMatcher(%q) ranges mismatch (-want +got):\n%s", test.pattern, diff)
+			}
+		})
+	}
+}
+
+func TestWholeWordRanges(t *testing.T) {
+	tests := []struct {
+		text string
+		term string
+		want []MatchRange
+	}{
+		{text: "go go", term: "go", want: []MatchRange{{Start: 0, End: 2}, {Start: 3, End: 5}}},
+		{text: "cargo", term: "go", want: nil},
+		{text: "go_mod", term: "go", want: nil},
+		{text: "main.go", term: ".go", want: []MatchRange{{Start: 4, End: 7}}},
+		{text: "Main.Go", term: "go", want: []MatchRange{{Start: 5, End: 7}}},
+		{text: "a-b", term: "-", want: []MatchRange{{Start: 1, End: 2}}},
+	}
+
+	for _, test := range tests {
+		if diff := cmp.Diff(test.want, wholeWordRanges(test.text, test.term)); diff != "" {
+			t.Errorf("wholeWordRanges(%q, %q) mismatch (-want +got):\n%s", test.text, test.term, diff)
+		}
+	}
+}
+
+func TestSearch_Modes(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    SearchMode
+		term    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "substring", mode: SearchSubstring, term: "main", want: []string{"Main.go", "main_test.go", "domain.txt"}},
+		{name: "case_sensitive", mode: SearchCaseSensitive, term: "main", want: []string{"main_test.go", "domain.txt"}},
+		{name: "whole_word", mode: SearchWholeWord, term: "go", want: []string{"Main.go", "main_test.go", "go.mod"}},
+		{name: "regex", mode: SearchRegex, term: `^m.*\.go$`, want: []string{"main_test.go"}},
+		{name: "fuzzy", mode: SearchFuzzy, term: "mgo", want: []string{"Main.go", "main_test.go"}},
+		{name: "query", mode: SearchQuery, term: "ext:go AND NOT name:test", want: []string{"Main.go"}},
+		{name: "invalid_regex", mode: SearchRegex, term: "(", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			model := createSearchModel()
+			model.BeginSearch()
+			model.SetSearchMode(test.mode)
+
+			matches, err := model.Search(test.term)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Search(%q) error = %v, wantErr %v", test.term, err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			got := nodeIDs(matches)
+			if test.mode != SearchFuzzy {
+				// Only fuzzy results are ranked, compare the others as sets
+				if !sameIDSet(test.want, got) {
+					t.Errorf("Search(%q) = %v, want %v", test.term, got, test.want)
+				}
+				return
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Search(%q) mismatch (-want +got):\n%s", test.term, diff)
+			}
+		})
+	}
+}
+
+func TestSearch_InvalidRegexShownInline(t *testing.T) {
+	model := createSearchModel()
+	model.BeginSearch()
+	model.SetSearchMode(SearchRegex)
+
+	if _, err := model.Search("go$"); err != nil {
+		t.Fatalf("Search(go$) error = %v", err)
+	}
+	matches, err := model.Search("go$(")
+	if err == nil {
+		t.Fatalf("Search(go$() error = nil, want a regexp error")
+	}
+	if diff := cmp.Diff([]string{"Main.go", "main_test.go", "cargo"}, nodeIDs(matches)); diff != "" {
+		t.Errorf("Search(go$() kept results mismatch (-want +got):\n%s", diff)
+	}
+
+	view := model.View()
+	for _, want := range []string{"Search: go$(  [regex]", "⚠ error parsing regexp"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() = %q, want it to contain %q", view, want)
+		}
+	}
+}
+
+func TestCycleSearchMode(t *testing.T) {
+	model := createSearchModel(WithTuiSearchModes[string](SearchSubstring, SearchRegex, SearchFuzzy))
+	model.BeginSearch()
+
+	steps := []struct {
+		key  tea.KeyMsg
+		want SearchMode
+	}{
+		{key: tea.KeyMsg{Type: tea.KeyTab}, want: SearchRegex},
+		{key: tea.KeyMsg{Type: tea.KeyTab}, want: SearchFuzzy},
+		{key: tea.KeyMsg{Type: tea.KeyTab}, want: SearchSubstring},
+		{key: tea.KeyMsg{Type: tea.KeyShiftTab}, want: SearchFuzzy},
+	}
+	for i, step := range steps {
+		model.Update(step.key)
+		if got := model.SearchMode(); got != step.want {
+			t.Fatalf("step %d: SearchMode() = %v, want %v", i, got, step.want)
+		}
+	}
+	if view := model.View(); !strings.Contains(view, "Search:   [fuzzy]") {
+		t.Errorf("View() = %q, want the mode in the prompt", view)
+	}
+}
+
+func TestCycleSearchMode_RerunsSearch(t *testing.T) {
+	model := createSearchModel(WithTuiSearchModes[string](SearchSubstring, SearchCaseSensitive))
+	model.BeginSearch()
+	if _, err := model.Search("main"); err != nil {
+		t.Fatalf("Search(main) error = %v", err)
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	var got *SearchChangedMsg
+	for _, msg := range collectMsgs(cmd) {
+		if m, ok := msg.(SearchChangedMsg); ok {
+			got = &m
+		}
+	}
+	want := &SearchChangedMsg{Term: "main", Mode: SearchCaseSensitive, MatchIDs: []string{"main_test.go", "domain.txt"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Update(tab) SearchChangedMsg mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearch_Debounce(t *testing.T) {
+	model := createSearchModel(WithTuiSearchDebounce[string](time.Hour))
+	model.BeginSearch()
+
+	var cmds []tea.Cmd
+	for _, r := range "go" {
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		if cmd == nil {
+			t.Fatalf("Update(%q) returned no command, want a debounce tick", r)
+		}
+		cmds = append(cmds, cmd)
+	}
+	if model.searchMatches != nil {
+		t.Fatalf("search ran before the debounce delay: %v", nodeIDs(model.searchMatches))
+	}
+
+	// The tick of the first key is stale and must not search
+	model.Update(searchDebounceMsg{seq: model.searchSeq - 1})
+	if model.searchMatches != nil {
+		t.Fatalf("stale debounce tick ran the search")
+	}
+
+	_, cmd := model.Update(searchDebounceMsg{seq: model.searchSeq})
+	var got *SearchChangedMsg
+	for _, msg := range collectMsgs(cmd) {
+		if m, ok := msg.(SearchChangedMsg); ok {
+			got = &m
+		}
+	}
+	if got == nil || got.Term != "go" || len(got.MatchIDs) != 4 {
+		t.Errorf("debounced search message = %+v, want term \"go\" with 4 matches", got)
+	}
+}
+
+func TestSearch_DebounceAccept(t *testing.T) {
+	if d := createSearchModel().searchDebounce; d <= 0 {
+		t.Errorf("default searchDebounce = %v, want a delay", d)
+	}
+	model := createSearchModel(WithTuiSearchDebounce[string](time.Hour))
+	model.BeginSearch()
+	for _, r := range "go" {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	seq := model.searchSeq
+
+	// Accepting within the delay evaluates the term at once
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	var got *SearchChangedMsg
+	for _, msg := range collectMsgs(cmd) {
+		if m, ok := msg.(SearchChangedMsg); ok {
+			got = &m
+		}
+	}
+	if got == nil || got.Term != "go" || len(got.MatchIDs) != 4 {
+		t.Errorf("accepted search message = %+v, want term \"go\" with 4 matches", got)
+	}
+
+	// The pending tick is cancelled
+	if _, cmd := model.Update(searchDebounceMsg{seq: seq}); cmd != nil {
+		t.Errorf("debounce tick after accepting returned a command")
+	}
+}
+
+func TestWithTuiQuerySearch_AddsMode(t *testing.T) {
+	model := createSearchModel(WithTuiQuerySearch[string](nil))
+
+	if got := model.SearchMode(); got != SearchQuery {
+		t.Errorf("SearchMode() = %v, want %v", got, SearchQuery)
+	}
+	want := append(defaultSearchModes[:len(defaultSearchModes):len(defaultSearchModes)], SearchQuery)
+	if diff := cmp.Diff(want, model.searchModes); diff != "" {
+		t.Errorf("searchModes mismatch (-want +got):\n%s", diff)
+	}
+	if len(defaultSearchModes) != 5 {
+		t.Errorf("defaultSearchModes was modified: %v", defaultSearchModes)
+	}
+	if _, err := model.Search("name~("); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Search(name~() error = %v, want %v", err, ErrInvalidQuery)
+	}
+}
+
+func TestMatchNavigation(t *testing.T) {
+	// Three matches spread over collapsed parents
+	var roots []*Node[string]
+	for _, id := range []string{"a", "b", "c"} {
+		parent := NewNode(id, id, "")
+		parent.AddChild(NewNode(id+"-match", id+"-match", ""))
+		roots = append(roots, parent)
+	}
+	model := NewTuiTreeModel(NewTree(roots), WithTuiHeight[string](6))
+	model.BeginSearch()
+	if _, err := model.Search("match"); err != nil {
+		t.Fatalf("Search(match) error = %v", err)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter}) // Accept the search
+
+	// The user collapses a parent; jumping to its match must reveal it again
+	if _, err := model.SetExpanded(context.Background(), "c", false); err != nil {
+		t.Fatalf("SetExpanded(c) error = %v", err)
+	}
+
+	steps := []struct {
+		key         string
+		wantFocus   string
+		wantCurrent int
+	}{
+		{key: "n", wantFocus: "b-match", wantCurrent: 2},
+		{key: "n", wantFocus: "c-match", wantCurrent: 3},
+		{key: "n", wantFocus: "a-match", wantCurrent: 1},
+		{key: "N", wantFocus: "c-match", wantCurrent: 3},
+	}
+	for i, step := range steps {
+		_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(step.key)})
+
+		if got := model.GetAllFocusedIDs(); !cmp.Equal(got, []string{step.wantFocus}) {
+			t.Fatalf("step %d (%s): focused = %v, want [%s]", i, step.key, got, step.wantFocus)
+		}
+		current, total := model.MatchPosition()
+		if current != step.wantCurrent || total != 3 {
+			t.Errorf("step %d (%s): MatchPosition() = %d/%d, want %d/3", i, step.key, current, total, step.wantCurrent)
+		}
+		var focusMsg bool
+		for _, msg := range collectMsgs(cmd) {
+			if m, ok := msg.(FocusChangedMsg); ok && m.ID == step.wantFocus {
+				focusMsg = true
+			}
+		}
+		if !focusMsg {
+			t.Errorf("step %d (%s): no FocusChangedMsg for %s", i, step.key, step.wantFocus)
+		}
+	}
+
+	node, _ := model.FindByID(context.Background(), "c")
+	if !node.IsExpanded() {
+		t.Errorf("parent c is collapsed, want it expanded to reveal c-match")
+	}
+	view := model.View()
+	if !strings.Contains(view, "c-match") {
+		t.Errorf("View() = %q, want the viewport scrolled to c-match", view)
+	}
+	if !strings.Contains(view, "n/N: Match 3/3") {
+		t.Errorf("View() = %q, want the match counter in the navigation bar", view)
+	}
+}
+
+func TestMatchNavigation_Counter(t *testing.T) {
+	model := createSearchModel()
+	model.BeginSearch()
+
+	tests := []struct {
+		term string
+		want string
+	}{
+		{term: "go", want: "Search: go  [substring]  1/4"},
+		{term: "zzz", want: "Search: zzz  [substring]  no matches"},
+	}
+	for _, test := range tests {
+		if _, err := model.Search(test.term); err != nil {
+			t.Fatalf("Search(%q) error = %v", test.term, err)
+		}
+		if view := model.View(); !strings.Contains(view, test.want) {
+			t.Errorf("View() after Search(%q) = %q, want it to contain %q", test.term, view, test.want)
+		}
+	}
+
+	// Without matches navigation is a no-op
+	model.NextMatch()
+	if current, total := model.MatchPosition(); current != 0 || total != 0 {
+		t.Errorf("MatchPosition() = %d/%d, want 0/0", current, total)
+	}
+}
+
+func TestSearchStreaming(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []TuiTreeModelOption[string]
+		wantPartial bool
+		wantStatus  string
+	}{
+		{name: "completes_in_background", wantStatus: "4/8"},
+		{
+			name:        "limit_marks_partial",
+			opts:        []TuiTreeModelOption[string]{WithTuiSearchLimit[string](time.Nanosecond)},
+			wantPartial: true,
+			wantStatus:  "partial results",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createScanTree(WithMatcher(slowMatcher(2 * time.Millisecond)))
+			opts := append([]TuiTreeModelOption[string]{
+				WithTuiSearchTimeout[string](10 * time.Millisecond),
+				WithTuiSearchDebounce[string](0),
+			}, test.opts...)
+			model := NewTuiTreeModel(tree, opts...)
+
+			model.BeginSearch()
+			var cmd tea.Cmd
+			for _, r := range "hit" {
+				_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+			if !model.Searching() {
+				t.Fatal("Searching() = false after the search ran out of time")
+			}
+			if view := model.View(); !strings.Contains(view, "searching…") {
+				t.Errorf("View() lacks searching state:\n%s", view)
+			}
+			first := len(model.searchMatches)
+
+			// Drive the background steps like the Bubble Tea runtime would
+			for steps := 0; cmd != nil; steps++ {
+				if steps > 100 {
+					t.Fatal("background search did not finish")
+				}
+				msg := cmd()
+				if batch, ok := msg.(tea.BatchMsg); ok {
+					cmd = nil
+					for _, c := range batch {
+						if c == nil {
+							continue
+						}
+						if scanMsg, ok := c().(searchScanMsg); ok {
+							_, cmd = model.Update(scanMsg)
+						}
+					}
+					continue
+				}
+				_, cmd = model.Update(msg)
+			}
+
+			if model.Searching() {
+				t.Error("Searching() = true after the scan stopped")
+			}
+			if got := model.SearchPartial(); got != test.wantPartial {
+				t.Errorf("SearchPartial() = %v, want %v", got, test.wantPartial)
+			}
+			if !test.wantPartial && len(model.searchMatches) != 8 {
+				t.Errorf("found %d matches, want 8 (first frame had %d)", len(model.searchMatches), first)
+			}
+			model.NextMatch()
+			model.NextMatch()
+			model.NextMatch()
+			if view := model.View(); !strings.Contains(view, test.wantStatus) {
+				t.Errorf("View() lacks %q:\n%s", test.wantStatus, view)
+			}
+		})
+	}
+}
+
+func TestSearchStreaming_SupersededByNewTerm(t *testing.T) {
+	tree := createScanTree(WithMatcher(slowMatcher(2 * time.Millisecond)))
+	model := NewTuiTreeModel(tree, WithTuiSearchTimeout[string](5*time.Millisecond), WithTuiSearchDebounce[string](0))
+
+	model.BeginSearch()
+	stale := backgroundStep(t, model, "hi")
+	model.EndSearch()
+
+	if cmd := model.handleScanMsg(stale); cmd != nil {
+		t.Error("handleScanMsg() continued a superseded search")
+	}
+	if model.Searching() || len(model.searchMatches) != 0 {
+		t.Errorf("stale step changed the model: searching=%v matches=%d", model.Searching(), len(model.searchMatches))
+	}
+}
+
+// backgroundStep searches term and runs one background step, returning its
+// message without handling it.
+func backgroundStep[T any](t *testing.T, m *TuiTreeModel[T], term string) searchScanMsg {
+	t.Helper()
+	m.searchTerm = term
+	cmd := m.scheduleSearch()
+	if cmd == nil {
+		t.Fatalf("search for %q finished within the timeout", term)
+	}
+	return cmd().(searchScanMsg)
+}
+
+func TestSearchStreaming_ChangeMessages(t *testing.T) {
+	tree := createScanTree(WithMatcher(slowMatcher(2 * time.Millisecond)))
+	model := NewTuiTreeModel(tree, WithTuiSearchTimeout[string](10*time.Millisecond), WithTuiSearchDebounce[string](0))
+
+	model.BeginSearch()
+	var cmd tea.Cmd
+	for _, r := range "hit" {
+		_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+
+	// Drive the background steps, keeping every search message
+	var searches []SearchChangedMsg
+	for steps := 0; cmd != nil; steps++ {
+		if steps > 100 {
+			t.Fatal("background search did not finish")
+		}
+		var next tea.Cmd
+		for _, msg := range collectMsgs(cmd) {
+			switch msg := msg.(type) {
+			case searchScanMsg:
+				_, next = model.Update(msg)
+			case SearchChangedMsg:
+				searches = append(searches, msg)
+			}
+		}
+		cmd = next
+	}
+
+	if len(searches) < 2 {
+		t.Fatalf("got %d SearchChangedMsg, want one per step that found matches and a final one", len(searches))
+	}
+	if !searches[0].Searching {
+		t.Error("first SearchChangedMsg has Searching = false, want true")
+	}
+	last := searches[len(searches)-1]
+	if last.Searching || last.Partial {
+		t.Errorf("final SearchChangedMsg Searching = %v, Partial = %v, want both false", last.Searching, last.Partial)
+	}
+	if len(last.MatchIDs) != 8 {
+		t.Errorf("final SearchChangedMsg has %d matches, want 8", len(last.MatchIDs))
+	}
+}
@@ -0,0 +1,280 @@
+package treeview
+
+import (
+	"context"
+)
+
+// CheckState describes how a node is rendered in checkbox selection mode.
+type CheckState int
+
+const (
+	// Unchecked means neither the node nor any of its descendants is selected.
+	Unchecked CheckState = iota
+	// Checked means the node and its whole subtree are selected.
+	Checked
+	// PartiallyChecked means some, but not all, descendants are selected.
+	PartiallyChecked
+)
+
+// Checkbox glyphs drawn in front of each node when checkboxes are enabled.
+const (
+	checkboxUnchecked = "[ ] "
+	checkboxChecked   = "[x] "
+	checkboxPartial   = "[-] "
+)
+
+// Selection is kept separate from focus: focus is the cursor the user moves
+// around, while selection is the set of checked nodes. Selection is stored by
+// ID so it survives navigation, searches and re-renders. Selecting a node
+// always selects its whole subtree, and a parent is selected exactly when all
+// of its children are; parents with only some selected descendants are
+// tracked as partial.
+
+// SetSelected selects or deselects the node with the given ID together with
+// its entire subtree, then updates the check state of its ancestors.
+// Returns ErrNodeNotFound if the ID doesn't exist, or context errors unwrapped.
+func (t *Tree[T]) SetSelected(ctx context.Context, id string, selected bool) error {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return ErrNodeNotFound
+	}
+	return t.setSelected(ctx, node, selected)
+}
+
+// ToggleSelected selects the node's subtree unless the node is already fully
+// checked, in which case the subtree is deselected. Partially checked parents
+// therefore become fully checked. The check state is read and changed under
+// one lock, so concurrent toggles of the same node don't cancel out into the
+// same result. Returns ErrNodeNotFound if the ID doesn't exist, or context
+// errors unwrapped.
+func (t *Tree[T]) ToggleSelected(ctx context.Context, id string) error {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return ErrNodeNotFound
+	}
+	return t.setSelected(ctx, node, t.checkState(node.ID()) != Checked)
+}
+
+// setSelected is SetSelected for callers holding t.mu.
+func (t *Tree[T]) setSelected(ctx context.Context, node *Node[T], selected bool) error {
+	if err := t.setSubtreeSelected(ctx, node, selected); err != nil {
+		return err
+	}
+	t.refreshAncestorSelection(node.Parent())
+	t.selectionVersion++
+	return nil
+}
+
+// SelectAll selects every node in the tree. Returns context errors unwrapped.
+func (t *Tree[T]) SelectAll(ctx context.Context) error {
+	selected := make(map[string]bool)
+	for info, err := range t.All(ctx) {
+		if err != nil {
+			return err
+		}
+		selected[info.Node.ID()] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.selectedIDs = selected
+	t.partialIDs = make(map[string]bool)
+	t.selectionVersion++
+	return nil
+}
+
+// ClearSelection deselects every node.
+func (t *Tree[T]) ClearSelection() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.selectedIDs = make(map[string]bool)
+	t.partialIDs = make(map[string]bool)
+	t.selectionVersion++
+}
+
+// IsSelected reports whether the node with the given ID is selected.
+func (t *Tree[T]) IsSelected(id string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.selectedIDs[id]
+}
+
+// CheckState returns the tri-state check mark for the node with the given ID.
+func (t *Tree[T]) CheckState(id string) CheckState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.checkState(id)
+}
+
+// checkState is CheckState for callers holding t.mu.
+func (t *Tree[T]) checkState(id string) CheckState {
+	switch {
+	case t.selectedIDs[id]:
+		return Checked
+	case t.partialIDs[id]:
+		return PartiallyChecked
+	default:
+		return Unchecked
+	}
+}
+
+// SelectedIDs returns the IDs of all selected nodes in depth-first tree order.
+func (t *Tree[T]) SelectedIDs() []string {
+	var ids []string
+	for info, err := range t.AllSelected(context.Background()) {
+		if err != nil {
+			break
+		}
+		ids = append(ids, info.Node.ID())
+	}
+	return ids
+}
+
+// selectionVersionNow returns a counter that increases on every selection change.
+func (t *Tree[T]) selectionVersionNow() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.selectionVersion
+}
+
+// setSubtreeSelected marks node and all of its descendants as (de)selected.
+// Callers must hold t.mu.
+func (t *Tree[T]) setSubtreeSelected(ctx context.Context, node *Node[T], selected bool) error {
+	if t.selectedIDs == nil {
+		t.selectedIDs = make(map[string]bool)
+	}
+	if t.partialIDs == nil {
+		t.partialIDs = make(map[string]bool)
+	}
+	for info, err := range node.All(ctx) {
+		if err != nil {
+			return err
+		}
+		id := info.Node.ID()
+		if selected {
+			t.selectedIDs[id] = true
+		} else {
+			delete(t.selectedIDs, id)
+		}
+		delete(t.partialIDs, id)
+	}
+	return nil
+}
+
+// subtreeSelection returns the check state of every checked or partially
+// checked node in node's subtree. Callers must hold t.mu.
+func (t *Tree[T]) subtreeSelection(node *Node[T]) map[string]CheckState {
+	states := make(map[string]CheckState)
+	for info := range dfsSeq(context.Background(), []*Node[T]{node}, true, nil) {
+		if state := t.checkState(info.Node.ID()); state != Unchecked {
+			states[info.Node.ID()] = state
+		}
+	}
+	return states
+}
+
+// restoreSelection puts back check states returned by subtreeSelection.
+// Callers must hold t.mu and refresh the ancestors afterwards.
+func (t *Tree[T]) restoreSelection(states map[string]CheckState) {
+	if len(states) == 0 {
+		return
+	}
+	if t.selectedIDs == nil {
+		t.selectedIDs = make(map[string]bool)
+	}
+	if t.partialIDs == nil {
+		t.partialIDs = make(map[string]bool)
+	}
+	for id, state := range states {
+		if state == Checked {
+			t.selectedIDs[id] = true
+		} else {
+			t.partialIDs[id] = true
+		}
+	}
+	t.selectionVersion++
+}
+
+// dropSelection removes nodes that left the tree from the selection, so
+// their IDs don't linger in SelectedIDs or come back checked with a new node
+// of the same ID. Callers must hold t.mu and refresh the ancestors
+// afterwards.
+func (t *Tree[T]) dropSelection(nodes map[*Node[T]]bool) {
+	changed := false
+	for node := range nodes {
+		id := node.ID()
+		if t.selectedIDs[id] || t.partialIDs[id] {
+			delete(t.selectedIDs, id)
+			delete(t.partialIDs, id)
+			changed = true
+		}
+	}
+	if changed {
+		t.selectionVersion++
+	}
+}
+
+// refreshAncestorSelection walks from node up to the root recomputing each
+// ancestor's state from its direct children. A node left without children
+// keeps its own selected state. Callers must hold t.mu.
+func (t *Tree[T]) refreshAncestorSelection(node *Node[T]) {
+	if t.selectedIDs == nil || t.partialIDs == nil {
+		return // Nothing has been selected yet
+	}
+	for current := node; current != nil; current = current.Parent() {
+		if !current.HasChildren() {
+			// Zero children would make it Checked out of nothing
+			delete(t.partialIDs, current.ID())
+			continue
+		}
+		all, some := true, false
+		for _, child := range current.Children() {
+			switch {
+			case t.selectedIDs[child.ID()]:
+				some = true
+			case t.partialIDs[child.ID()]:
+				some = true
+				all = false
+			default:
+				all = false
+			}
+		}
+
+		id := current.ID()
+		if all {
+			t.selectedIDs[id] = true
+		} else {
+			delete(t.selectedIDs, id)
+		}
+		if some && !all {
+			t.partialIDs[id] = true
+		} else {
+			delete(t.partialIDs, id)
+		}
+	}
+}
+
+// checkbox returns the glyph for the node's check state. Callers must hold
+// t.mu.
+func (t *Tree[T]) checkbox(node *Node[T]) string {
+	switch t.checkState(node.ID()) {
+	case Checked:
+		return checkboxChecked
+	case PartiallyChecked:
+		return checkboxPartial
+	default:
+		return checkboxUnchecked
+	}
+}
@@ -0,0 +1,299 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTree_SetSelected_TriState(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		ops        func(tree *Tree[string]) error
+		wantIDs    []string
+		wantStates map[string]CheckState
+	}{
+		{
+			name: "select_parent_selects_subtree",
+			ops: func(tree *Tree[string]) error {
+				return tree.SetSelected(ctx, "1", true)
+			},
+			wantIDs: []string{"1", "1.1", "1.1.1", "1.2"},
+			wantStates: map[string]CheckState{
+				"1": Checked, "1.1": Checked, "1.1.1": Checked, "1.2": Checked, "2": Unchecked,
+			},
+		},
+		{
+			name: "deselect_child_makes_parent_partial",
+			ops: func(tree *Tree[string]) error {
+				if err := tree.SetSelected(ctx, "1", true); err != nil {
+					return err
+				}
+				return tree.SetSelected(ctx, "1.2", false)
+			},
+			wantIDs: []string{"1.1", "1.1.1"},
+			wantStates: map[string]CheckState{
+				"1": PartiallyChecked, "1.1": Checked, "1.2": Unchecked,
+			},
+		},
+		{
+			name: "select_deep_leaf_propagates",
+			ops: func(tree *Tree[string]) error {
+				return tree.SetSelected(ctx, "1.1.1", true)
+			},
+			wantIDs: []string{"1.1", "1.1.1"},
+			wantStates: map[string]CheckState{
+				"1": PartiallyChecked, "1.1": Checked, "1.1.1": Checked, "1.2": Unchecked,
+			},
+		},
+		{
+			name: "selecting_all_children_checks_parent",
+			ops: func(tree *Tree[string]) error {
+				if err := tree.SetSelected(ctx, "1.1", true); err != nil {
+					return err
+				}
+				return tree.SetSelected(ctx, "1.2", true)
+			},
+			wantIDs:    []string{"1", "1.1", "1.1.1", "1.2"},
+			wantStates: map[string]CheckState{"1": Checked},
+		},
+		{
+			name: "toggle_partial_parent_checks_subtree",
+			ops: func(tree *Tree[string]) error {
+				if err := tree.SetSelected(ctx, "1.2", true); err != nil {
+					return err
+				}
+				return tree.ToggleSelected(ctx, "1")
+			},
+			wantIDs:    []string{"1", "1.1", "1.1.1", "1.2"},
+			wantStates: map[string]CheckState{"1": Checked},
+		},
+		{
+			name: "toggle_checked_parent_clears_subtree",
+			ops: func(tree *Tree[string]) error {
+				if err := tree.ToggleSelected(ctx, "2"); err != nil {
+					return err
+				}
+				return tree.ToggleSelected(ctx, "2")
+			},
+			wantIDs:    nil,
+			wantStates: map[string]CheckState{"2": Unchecked, "2.1": Unchecked},
+		},
+		{
+			name: "select_all_then_clear",
+			ops: func(tree *Tree[string]) error {
+				if err := tree.SelectAll(ctx); err != nil {
+					return err
+				}
+				tree.ClearSelection()
+				return nil
+			},
+			wantIDs:    nil,
+			wantStates: map[string]CheckState{"1": Unchecked, "2.1": Unchecked},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createSimpleTree(t)
+			if err := test.ops(tree); err != nil {
+				t.Fatalf("selection ops error = %v, want nil", err)
+			}
+
+			if diff := cmp.Diff(test.wantIDs, tree.SelectedIDs()); diff != "" {
+				t.Errorf("SelectedIDs() mismatch (-want +got):\n%s", diff)
+			}
+			for id, want := range test.wantStates {
+				if got := tree.CheckState(id); got != want {
+					t.Errorf("CheckState(%q) = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTree_SetSelected_NotFound(t *testing.T) {
+	tree := createSimpleTree(t)
+
+	err := tree.SetSelected(context.Background(), "missing", true)
+	if !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("SetSelected(missing) error = %v, want %v", err, ErrNodeNotFound)
+	}
+}
+
+func TestTree_ToggleSelected_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	tree := createSimpleTree(t)
+
+	// Each toggle must see the state left by the previous one, so an even
+	// number of toggles leaves the node as it was
+	const toggles = 64
+	var wg sync.WaitGroup
+	for range toggles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tree.ToggleSelected(ctx, "1.1"); err != nil {
+				t.Errorf("ToggleSelected() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range []string{"1", "1.1", "1.1.1"} {
+		if got := tree.CheckState(id); got != Unchecked {
+			t.Errorf("CheckState(%q) = %v after %d toggles, want %v", id, got, toggles, Unchecked)
+		}
+	}
+}
+
+func TestTree_RemoveNode_DropsSelection(t *testing.T) {
+	ctx := context.Background()
+	tree := newHistoryTree(t, defaultHistoryLimit)
+	if err := tree.SetSelected(ctx, "1.1", true); err != nil {
+		t.Fatalf("SetSelected(1.1) error = %v", err)
+	}
+	if _, err := tree.RemoveNode(ctx, "1.1"); err != nil {
+		t.Fatalf("RemoveNode(1.1) error = %v", err)
+	}
+
+	states := func() map[string]CheckState {
+		got := make(map[string]CheckState)
+		for _, id := range []string{"1", "1.1", "1.1.1", "1.2"} {
+			got[id] = tree.CheckState(id)
+		}
+		return got
+	}
+	removed := map[string]CheckState{"1": Unchecked, "1.1": Unchecked, "1.1.1": Unchecked, "1.2": Unchecked}
+	if diff := cmp.Diff(removed, states()); diff != "" {
+		t.Errorf("check states after RemoveNode() mismatch (-want +got):\n%s", diff)
+	}
+	if len(tree.selectedIDs)+len(tree.partialIDs) != 0 {
+		t.Errorf("selection after RemoveNode() = %v, partial %v, want both empty", tree.selectedIDs, tree.partialIDs)
+	}
+
+	// A new node reusing the ID starts unchecked
+	if err := tree.InsertNode(ctx, "1", 0, NewNode("1.1", "new", "new")); err != nil {
+		t.Fatalf("InsertNode() error = %v", err)
+	}
+	if got := tree.CheckState("1.1"); got != Unchecked {
+		t.Errorf("CheckState(1.1) of the inserted node = %v, want %v", got, Unchecked)
+	}
+
+	// Undoing the removal brings the selection back with the nodes
+	for range 2 {
+		if ok, err := tree.Undo(ctx); !ok || err != nil {
+			t.Fatalf("Undo() = %v, %v, want true, nil", ok, err)
+		}
+	}
+	restored := map[string]CheckState{"1": PartiallyChecked, "1.1": Checked, "1.1.1": Checked, "1.2": Unchecked}
+	if diff := cmp.Diff(restored, states()); diff != "" {
+		t.Errorf("check states after Undo() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTree_Selection_SurvivesNavigationAndSearch(t *testing.T) {
+	ctx := context.Background()
+	tree := createSimpleTree(t)
+	tree.searcher = defaultSearchFn[string]
+	tree.focusPol = defaultFocusPolicy[string]
+
+	if err := tree.SetSelected(ctx, "2", true); err != nil {
+		t.Fatalf("SetSelected(2) error = %v", err)
+	}
+	if _, err := tree.Move(ctx, 1); err != nil {
+		t.Fatalf("Move(1) error = %v", err)
+	}
+	if _, err := tree.SearchAndExpand(ctx, "1.1"); err != nil {
+		t.Fatalf("SearchAndExpand(1.1) error = %v", err)
+	}
+
+	want := []string{"2", "2.1"}
+	if diff := cmp.Diff(want, tree.SelectedIDs()); diff != "" {
+		t.Errorf("SelectedIDs() after navigation and search mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTree_Render_Checkboxes(t *testing.T) {
+	ctx := context.Background()
+	parent := NewNode("parent", "parent", "parent")
+	parent.AddChild(NewNode("a", "a", "a"))
+	parent.AddChild(NewNode("b", "b", "b"))
+	parent.Expand()
+	tree := NewTree([]*Node[string]{parent}, WithCheckboxes[string]())
+
+	if err := tree.SetSelected(ctx, "a", true); err != nil {
+		t.Fatalf("SetSelected(a) error = %v", err)
+	}
+
+	output, err := tree.Render(ctx)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{"[-] parent", "[x] a", "[ ] b"} {
+		if !strings.Contains(stripANSI(output), want) {
+			t.Errorf("Render() = %q, want it to contain %q", output, want)
+		}
+	}
+}
+
+func TestTree_Selection_ParentLosesLastChild(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		selected string
+		change   func(*Tree[string]) error
+		want     []string
+	}{
+		{
+			name:     "move_unchecked_child",
+			selected: "o",
+			change: func(tree *Tree[string]) error {
+				return tree.MoveNode(ctx, "c", "q", -1)
+			},
+			want: []string{"o"},
+		},
+		{
+			name:     "remove_unchecked_child",
+			selected: "o",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.RemoveNode(ctx, "c")
+				return err
+			},
+			want: []string{"o"},
+		},
+		{
+			name:     "move_checked_child",
+			selected: "c",
+			change: func(tree *Tree[string]) error {
+				return tree.MoveNode(ctx, "c", "q", -1)
+			},
+			want: []string{"p", "q", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createDiffTree([][3]string{{"p", "", ""}, {"c", "p", ""}, {"q", "", ""}, {"o", "", ""}})
+			if err := tree.SetSelected(ctx, test.selected, true); err != nil {
+				t.Fatalf("SetSelected() error = %v", err)
+			}
+			if err := test.change(tree); err != nil {
+				t.Fatalf("change error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, tree.SelectedIDs()); diff != "" {
+				t.Errorf("SelectedIDs() mismatch (-want +got):\n%s", diff)
+			}
+			if state := tree.CheckState("p"); state == PartiallyChecked {
+				t.Errorf("CheckState(p) = %v for a node without children", state)
+			}
+		})
+	}
+}
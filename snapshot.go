@@ -0,0 +1,257 @@
+package treeview
+
+import (
+	"context"
+	"iter"
+	"maps"
+)
+
+// A snapshot freezes a tree so that many goroutines can render, search and
+// iterate it without taking the tree lock, while writers keep changing the
+// live tree. Every node of the live tree caches its frozen copy; changes made
+// through Tree methods drop the copies of the changed node and its ancestors,
+// so the next snapshot only copies the paths touched since the previous one
+// and shares everything else with it. Changes made directly on Node values
+// are not noticed, as with subscriptions.
+//
+// A frozen node's Parent is the copy of its parent in the same snapshot. A
+// shared subtree keeps the parent links of the snapshot it was copied for,
+// so walking up more than one level may reach an older copy of an ancestor.
+// Formatters that look nodes up in state kept for the live tree see the
+// copies as nodes they have not met before; an Aggregation computes their
+// values apart from the live ones and drops them when the next snapshot is
+// taken.
+//
+// Copies are shallow: a frozen node holds the same payload as the live node
+// it was copied from, so pointers, maps and slices inside T are shared with
+// the live tree, and with every snapshot taken since the node last changed.
+
+// Snapshot is a view of a Tree at one moment whose structure and state never
+// change; see Tree.Snapshot. Its methods take no locks and are safe for
+// concurrent use. The nodes it returns belong to the snapshot and must not
+// be changed. Payloads are shared with the live tree and are only as
+// immutable as the caller keeps them.
+type Snapshot[T any] struct {
+	tree *Tree[T] // Never changed after Tree.Snapshot returns
+}
+
+// Snapshot returns a frozen view of the tree: its nodes, focus, selection,
+// filter, hoist and search highlights. Node payloads are not copied, so
+// changes made inside a payload, such as writing to FileInfo.Extra, show in
+// every snapshot holding that node. The view shares unchanged
+// subtrees with earlier snapshots, so taking one costs time proportional to
+// the nodes changed since the last, not to the size of the tree.
+func (t *Tree[T]) Snapshot() *Snapshot[T] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	frozen := &Tree[T]{
+		nodes:         make([]*Node[T], len(t.nodes)),
+		focusedIDs:    maps.Clone(t.focusedIDs),
+		selectedIDs:   maps.Clone(t.selectedIDs),
+		partialIDs:    maps.Clone(t.partialIDs),
+		searcher:      t.searcher,
+		matcher:       t.matcher,
+		focusPol:      t.focusPol,
+		provider:      t.provider,
+		movePolicy:    t.movePolicy,
+		truncateWidth: t.truncateWidth,
+		checkboxes:    t.checkboxes,
+		matches:       t.matches, // Replaced, never changed in place
+
+		filterDescendants: t.filterDescendants,
+		searchWorkers:     t.searchWorkers,
+	}
+	for i, root := range t.nodes {
+		frozen.nodes[i] = freeze(root, nil)
+	}
+
+	// Every node in the tree now caches its copy in this snapshot
+	for _, node := range t.focusedNodes {
+		if node.snap != nil {
+			frozen.focusedNodes = append(frozen.focusedNodes, node.snap)
+		}
+	}
+	if t.filter != nil {
+		view := &filterView[T]{
+			shown:       make(map[*Node[T]]bool, len(t.filter.shown)),
+			matched:     make(map[*Node[T]]bool, len(t.filter.matched)),
+			descendants: t.filter.descendants,
+		}
+		for node := range t.filter.shown {
+			if node.snap != nil {
+				view.shown[node.snap] = true
+			}
+		}
+		for node := range t.filter.matched {
+			if node.snap != nil {
+				view.matched[node.snap] = true
+			}
+		}
+		frozen.filter = view
+	}
+	if t.hoist != nil {
+		frozen.hoist = t.hoist.snap
+	}
+	for _, a := range t.aggregates {
+		a.dropFrozen()
+	}
+	return &Snapshot[T]{tree: frozen}
+}
+
+// freeze returns the frozen copy of node under the frozen parent, reusing
+// the cached copy when nothing below node changed. Callers must hold t.mu
+// for writing.
+func freeze[T any](node, parent *Node[T]) *Node[T] {
+	if node.snap != nil {
+		if node.snap.parent == parent {
+			return node.snap
+		}
+		// Unchanged, but its parent was copied: a shallow copy keeps its
+		// Parent in this snapshot while the children stay shared
+		c := *node.snap
+		c.parent = parent
+		node.snap = &c
+		return node.snap
+	}
+
+	c := &Node[T]{
+		id:       node.id,
+		name:     node.name,
+		data:     node.data,
+		parent:   parent,
+		expanded: node.expanded,
+		visible:  node.visible,
+		frozen:   true,
+	}
+	if len(node.children) > 0 {
+		c.children = make([]*Node[T], len(node.children))
+		for i, child := range node.children {
+			c.children[i] = freeze(child, c)
+		}
+	}
+	node.snap = c
+	return c
+}
+
+// touch drops the frozen copies of node and its ancestors after node or its
+// children changed. A node without a copy has ancestors without one, so the
+// walk stops there. Callers must hold t.mu.
+func (t *Tree[T]) touch(node *Node[T]) {
+	for n := node; n != nil && n.snap != nil; n = n.parent {
+		n.snap = nil
+	}
+}
+
+// Nodes returns the root nodes of the snapshot.
+func (s *Snapshot[T]) Nodes() []*Node[T] {
+	return s.tree.nodes
+}
+
+// Provider returns the provider the tree rendered with when the snapshot
+// was taken.
+func (s *Snapshot[T]) Provider() NodeProvider[T] {
+	return s.tree.provider
+}
+
+// All returns an iterator that yields to every node using depth-first traversal.
+// Context errors are returned unwrapped.
+func (s *Snapshot[T]) All(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
+	return dfsSeq(ctx, s.tree.nodes, true, nil)
+}
+
+// AllVisible returns an iterator over visible nodes using depth-first
+// traversal, or over the filtered view if a filter was active. Context errors
+// are returned unwrapped.
+func (s *Snapshot[T]) AllVisible(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
+	return s.tree.visibleSeq(ctx, nil)
+}
+
+// AllBottomUp returns an iterator that yields nodes in bottom-up order (leaves first, then parents).
+// Context errors are returned unwrapped.
+func (s *Snapshot[T]) AllBottomUp(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
+	return bottomUpSeq(ctx, s.tree.nodes, true, nil)
+}
+
+// BreadthFirst returns an iterator over nodes using breadth first traversal.
+// Context errors are returned unwrapped.
+func (s *Snapshot[T]) BreadthFirst(ctx context.Context) iter.Seq2[NodeInfo[T], error] {
+	return bfsSeq(ctx, s.tree.nodes, true, nil)
+}
+
+// FindByID searches the snapshot for a node with the given ID. Returns
+// ErrNodeNotFound if no node matches, or context errors unwrapped.
+func (s *Snapshot[T]) FindByID(ctx context.Context, id string) (*Node[T], error) {
+	for info, err := range s.All(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		if info.Node.ID() == id {
+			return info.Node, nil
+		}
+	}
+	return nil, ErrNodeNotFound
+}
+
+// GetFocusedID returns the ID of the primary focused node, or "" if none.
+func (s *Snapshot[T]) GetFocusedID() string {
+	if len(s.tree.focusedNodes) == 0 {
+		return ""
+	}
+	return s.tree.focusedNodes[0].ID()
+}
+
+// GetFocusedNode returns the primary focused node or nil if none is focused.
+func (s *Snapshot[T]) GetFocusedNode() *Node[T] {
+	if len(s.tree.focusedNodes) == 0 {
+		return nil
+	}
+	return s.tree.focusedNodes[0]
+}
+
+// GetAllFocusedIDs returns the IDs of all focused nodes, primary first.
+func (s *Snapshot[T]) GetAllFocusedIDs() []string {
+	return nodeIDs(s.tree.focusedNodes)
+}
+
+// IsFocused reports whether the node with the given ID is focused.
+func (s *Snapshot[T]) IsFocused(id string) bool {
+	return s.tree.focusedIDs[id]
+}
+
+// CheckState returns the tri-state check mark for the node with the given ID.
+func (s *Snapshot[T]) CheckState(id string) CheckState {
+	return s.tree.checkState(id)
+}
+
+// Search returns the nodes matching term as Tree.Search does. The search
+// index of the tree, which follows the live nodes, is not used. Returns
+// context errors unwrapped.
+func (s *Snapshot[T]) Search(ctx context.Context, term string) ([]*Node[T], error) {
+	results, err := s.SearchMatches(ctx, term)
+	return resultNodes(results), err
+}
+
+// SearchMatches is Search with the score and matched ranges of every result.
+// Returns context errors unwrapped.
+func (s *Snapshot[T]) SearchMatches(ctx context.Context, term string) ([]SearchResult[T], error) {
+	if term == "" {
+		return nil, nil
+	}
+	scan := s.StartSearch(term, nil)
+	err := scan.Continue(ctx)
+	return scan.Results(), err
+}
+
+// StartSearch prepares a resumable search of the snapshot for term; see
+// Tree.StartSearch. A nil match uses the tree's configured matcher.
+func (s *Snapshot[T]) StartSearch(term string, match MatchFn[T]) *SearchScan[T] {
+	return s.tree.newSearchScan(s.tree.nodes, term, match, nil)
+}
+
+// Render produces a string representation of the snapshot using the
+// tree's renderer. Returns context errors unwrapped.
+func (s *Snapshot[T]) Render(ctx context.Context) (string, error) {
+	output, _, err := renderTree(ctx, s.tree)
+	return output, err
+}
@@ -0,0 +1,247 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// describeNodes lists the state of every node yielded by seq.
+func describeNodes(t *testing.T, seq iter.Seq2[NodeInfo[string], error]) []string {
+	t.Helper()
+	var lines []string
+	for info, err := range seq {
+		if err != nil {
+			t.Fatalf("iteration error = %v", err)
+		}
+		n := info.Node
+		lines = append(lines, fmt.Sprintf("%d %s %q data=%q expanded=%v visible=%v", info.Depth, n.ID(), n.Name(), *n.Data(), n.IsExpanded(), n.IsVisible()))
+	}
+	return lines
+}
+
+// visibleIDs lists the IDs yielded by seq.
+func visibleIDs(t *testing.T, seq func(context.Context) iter.Seq2[NodeInfo[string], error]) []string {
+	t.Helper()
+	var ids []string
+	for info, err := range seq(context.Background()) {
+		if err != nil {
+			t.Fatalf("iteration error = %v", err)
+		}
+		ids = append(ids, info.Node.ID())
+	}
+	return ids
+}
+
+func TestTree_Snapshot(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(*Tree[string]) error
+	}{
+		{
+			name: "data",
+			change: func(tree *Tree[string]) error {
+				return tree.SetNodeData(ctx, "a1", "new")
+			},
+		},
+		{
+			name: "rename",
+			change: func(tree *Tree[string]) error {
+				return tree.Rename(ctx, "a2", "renamed")
+			},
+		},
+		{
+			name: "collapse",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.SetExpanded(ctx, "a", false)
+				return err
+			},
+		},
+		{
+			name: "hide_all",
+			change: func(tree *Tree[string]) error {
+				return tree.HideAll(ctx)
+			},
+		},
+		{
+			name: "insert",
+			change: func(tree *Tree[string]) error {
+				return tree.InsertNode(ctx, "b", 0, NewNode("b1", "b1", ""))
+			},
+		},
+		{
+			name: "move",
+			change: func(tree *Tree[string]) error {
+				return tree.MoveNode(ctx, "a1", "", 0)
+			},
+		},
+		{
+			name: "remove",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.RemoveNode(ctx, "a")
+				return err
+			},
+		},
+		{
+			name: "sort",
+			change: func(tree *Tree[string]) error {
+				return tree.SortSubtree(ctx, "a", Reverse(ByName[string]()))
+			},
+		},
+		{
+			name: "undo",
+			change: func(tree *Tree[string]) error {
+				_, err := tree.Undo(ctx)
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := createEventTree()
+			_ = tree.SetNodeData(ctx, "c", "set") // Something for undo
+			before := tree.Snapshot()
+			want := describeNodes(t, before.All(ctx))
+
+			if err := test.change(tree); err != nil {
+				t.Fatalf("change error = %v", err)
+			}
+			if diff := cmp.Diff(want, describeNodes(t, before.All(ctx))); diff != "" {
+				t.Errorf("earlier snapshot changed (-want +got):\n%s", diff)
+			}
+
+			after := tree.Snapshot()
+			live := describeNodes(t, tree.All(ctx))
+			if cmp.Equal(want, live) {
+				t.Fatalf("change did not change the tree")
+			}
+			if diff := cmp.Diff(live, describeNodes(t, after.All(ctx))); diff != "" {
+				t.Errorf("new snapshot differs from the tree (-want +got):\n%s", diff)
+			}
+			liveOut, _ := tree.Render(ctx)
+			if got, _ := after.Render(ctx); got != liveOut {
+				t.Errorf("Render() = %q, want %q as the tree renders", got, liveOut)
+			}
+		})
+	}
+}
+
+func TestTree_Snapshot_Sharing(t *testing.T) {
+	ctx := context.Background()
+	tree := createEventTree()
+	first := tree.Snapshot()
+	find := func(s *Snapshot[string], id string) *Node[string] {
+		t.Helper()
+		node, err := s.FindByID(ctx, id)
+		if err != nil {
+			t.Fatalf("FindByID(%q) error = %v", id, err)
+		}
+		return node
+	}
+
+	for i, root := range tree.Snapshot().Nodes() {
+		if root != first.Nodes()[i] {
+			t.Errorf("snapshot of an unchanged tree copied root %s", root.ID())
+		}
+	}
+
+	if err := tree.SetNodeData(ctx, "a1", "new"); err != nil {
+		t.Fatalf("SetNodeData() error = %v", err)
+	}
+	second := tree.Snapshot()
+	for _, id := range []string{"b", "c"} {
+		if find(first, id) != find(second, id) {
+			t.Errorf("%s copied, want it shared with the earlier snapshot", id)
+		}
+	}
+	for _, id := range []string{"a", "a1", "a2"} {
+		if find(first, id) == find(second, id) {
+			t.Errorf("%s shared, want a copy on the changed path", id)
+		}
+	}
+	if got := find(second, "a2").Parent(); got != find(second, "a") {
+		t.Errorf("a2.Parent() is not a of the same snapshot")
+	}
+	if got := *find(second, "a1").Data(); got != "new" {
+		t.Errorf("a1 data = %q, want %q", got, "new")
+	}
+	if got := *find(first, "a1").Data(); got != "" {
+		t.Errorf("a1 data in the earlier snapshot = %q, want it unchanged", got)
+	}
+}
+
+func TestSnapshot_View(t *testing.T) {
+	ctx := context.Background()
+	tree := createEventTree()
+	if err := tree.SetSelected(ctx, "a1", true); err != nil {
+		t.Fatalf("SetSelected() error = %v", err)
+	}
+	if _, err := tree.Filter(ctx, "a2"); err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	snap := tree.Snapshot()
+	tree.ClearFilter()
+	_ = tree.SetSelected(ctx, "a1", false)
+	_, _ = tree.SetFocusedID(ctx, "b")
+
+	if diff := cmp.Diff([]string{"a", "a2"}, visibleIDs(t, snap.AllVisible)); diff != "" {
+		t.Errorf("AllVisible() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"c", "a", "a1", "a2", "b"}, visibleIDs(t, snap.All)); diff != "" {
+		t.Errorf("All() mismatch (-want +got):\n%s", diff)
+	}
+	if got := snap.GetFocusedID(); got != "a2" {
+		t.Errorf("GetFocusedID() = %q, want %q", got, "a2")
+	}
+	if got := snap.CheckState("a"); got != PartiallyChecked {
+		t.Errorf("CheckState(a) = %v, want %v", got, PartiallyChecked)
+	}
+
+	found, err := snap.Search(ctx, "a")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"a", "a1", "a2"}, nodeIDs(found)); diff != "" {
+		t.Errorf("Search() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// Run with -race: readers use snapshots while the tree keeps changing.
+func TestSnapshot_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	tree := createEventTree()
+	snaps := make(chan *Snapshot[string], 1)
+	snaps <- tree.Snapshot()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				snap := <-snaps
+				snaps <- snap
+				_, _ = snap.Render(ctx)
+				_, _ = snap.Search(ctx, "a")
+				for range snap.AllVisible(ctx) {
+				}
+			}
+		}()
+	}
+	for i := range 50 {
+		id := fmt.Sprint("n", i)
+		_ = tree.InsertNode(ctx, "a", 0, NewNode(id, id, ""))
+		_ = tree.SetNodeData(ctx, "a1", id)
+		_, _ = tree.SetExpanded(ctx, "a", i%2 == 0)
+		<-snaps
+		snaps <- tree.Snapshot()
+	}
+	wg.Wait()
+}
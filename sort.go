@@ -0,0 +1,314 @@
+package treeview
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LessFn reports whether node a sorts before node b. Sorting is stable, so
+// nodes for which neither is less keep their relative order.
+type LessFn[T any] func(a, b *Node[T]) bool
+
+// SortMode is a named sort order the TUI can cycle through; see
+// WithTuiSortModes.
+type SortMode[T any] struct {
+	Name string
+	Less LessFn[T]
+}
+
+// ByName sorts nodes by Name in natural order: case-insensitively, with runs
+// of digits compared by their numeric value, so "file2" sorts before
+// "file10".
+func ByName[T any]() LessFn[T] {
+	return func(a, b *Node[T]) bool {
+		return NaturalCompare(a.Name(), b.Name()) < 0
+	}
+}
+
+// ByDirsFirst sorts directories before files and otherwise keeps the order.
+// Combine it with another order using ThenBy.
+func ByDirsFirst() LessFn[FileInfo] {
+	return func(a, b *Node[FileInfo]) bool {
+		return isDir(a) && !isDir(b)
+	}
+}
+
+// BySize sorts files by size, smallest first. Directories count as empty,
+// since the size the file system reports for them says nothing about their
+// contents. Use Reverse for largest first.
+func BySize() LessFn[FileInfo] {
+	return func(a, b *Node[FileInfo]) bool {
+		return fileSize(a) < fileSize(b)
+	}
+}
+
+// ByModTime sorts files by modification time, oldest first. Use Reverse for
+// newest first.
+func ByModTime() LessFn[FileInfo] {
+	return func(a, b *Node[FileInfo]) bool {
+		ta, tb := a.Data().FileInfo, b.Data().FileInfo
+		if ta == nil || tb == nil {
+			return ta == nil && tb != nil
+		}
+		return ta.ModTime().Before(tb.ModTime())
+	}
+}
+
+// Reverse inverts less.
+func Reverse[T any](less LessFn[T]) LessFn[T] {
+	return func(a, b *Node[T]) bool {
+		return less(b, a)
+	}
+}
+
+// ThenBy combines orders: nodes are compared by the first order that tells
+// them apart.
+func ThenBy[T any](orders ...LessFn[T]) LessFn[T] {
+	return func(a, b *Node[T]) bool {
+		for _, less := range orders {
+			if less(a, b) {
+				return true
+			}
+			if less(b, a) {
+				return false
+			}
+		}
+		return false
+	}
+}
+
+// FileSortModes returns the built-in sort modes for file system trees:
+// directories first by name, by name, largest first and newest first.
+func FileSortModes() []SortMode[FileInfo] {
+	return []SortMode[FileInfo]{
+		{Name: "dirs first", Less: ThenBy(ByDirsFirst(), ByName[FileInfo]())},
+		{Name: "name", Less: ByName[FileInfo]()},
+		{Name: "size", Less: ThenBy(Reverse(BySize()), ByName[FileInfo]())},
+		{Name: "modified", Less: ThenBy(Reverse(ByModTime()), ByName[FileInfo]())},
+	}
+}
+
+// isDir reports whether a file system node is a directory.
+func isDir(n *Node[FileInfo]) bool {
+	info := n.Data().FileInfo
+	return info != nil && info.IsDir()
+}
+
+// fileSize returns the size of a file, 0 for directories or if unknown.
+func fileSize(n *Node[FileInfo]) int64 {
+	if info := n.Data().FileInfo; info != nil && !info.IsDir() {
+		return info.Size()
+	}
+	return 0
+}
+
+// NaturalCompare compares strings case-insensitively, treating runs of
+// digits as numbers. It returns a negative number if a sorts before b, a
+// positive number if after, and 0 only if the strings are equal. Ties between
+// strings differing only in case or leading zeros are broken consistently.
+func NaturalCompare(a, b string) int {
+	sa, sb := a, b
+	for sa != "" && sb != "" {
+		ra, wa := utf8.DecodeRuneInString(sa)
+		rb, wb := utf8.DecodeRuneInString(sb)
+
+		if isDigit(ra) && isDigit(rb) {
+			da, db := digitRun(sa), digitRun(sb)
+			if c := compareNumbers(da, db); c != 0 {
+				return c
+			}
+			sa, sb = sa[len(da):], sb[len(db):]
+			continue
+		}
+
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			if la < lb {
+				return -1
+			}
+			return 1
+		}
+		sa, sb = sa[wa:], sb[wb:]
+	}
+
+	// A string that is a prefix of the other sorts first
+	switch {
+	case sa == "" && sb != "":
+		return -1
+	case sa != "" && sb == "":
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// isDigit reports whether r is an ASCII digit.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// digitRun returns the leading ASCII digits of s.
+func digitRun(s string) string {
+	i := 0
+	for i < len(s) && isDigit(rune(s[i])) {
+		i++
+	}
+	return s[:i]
+}
+
+// compareNumbers compares digit strings by value.
+func compareNumbers(a, b string) int {
+	ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(ta) != len(tb) {
+		return len(ta) - len(tb)
+	}
+	return strings.Compare(ta, tb)
+}
+
+// sortCompare turns less into a comparison for slices.SortStableFunc.
+func sortCompare[T any](less LessFn[T]) func(a, b *Node[T]) int {
+	return func(a, b *Node[T]) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		default:
+			return 0
+		}
+	}
+}
+
+// sortNodes sorts nodes and all their descendants in place. It is used while
+// building a tree, before anything else can see the nodes.
+func sortNodes[T any](nodes []*Node[T], less LessFn[T]) {
+	slices.SortStableFunc(nodes, sortCompare(less))
+	for _, node := range nodes {
+		if node.HasChildren() {
+			sortNodes(node.children, less)
+		}
+	}
+}
+
+// Sort reorders the roots and every list of siblings by less. The sort is
+// stable and recorded as a single undo step. Focus and selection are kept.
+// Returns context errors unwrapped.
+func (t *Tree[T]) Sort(ctx context.Context, less LessFn[T]) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sortBelow(ctx, nil, less)
+}
+
+// SortSubtree is Sort limited to the descendants of the node with the given
+// ID. Returns ErrNodeNotFound if the ID doesn't exist, or context errors
+// unwrapped.
+func (t *Tree[T]) SortSubtree(ctx context.Context, id string, less LessFn[T]) error {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return ErrNodeNotFound
+	}
+	return t.sortBelow(ctx, node, less)
+}
+
+// sortBelow sorts the children of parent (the roots if nil) and everything
+// below them. Sorted lists replace the old slices instead of being sorted in
+// place, so iterators holding the old ones are not disturbed and undo can
+// put them back. Callers must hold t.mu.
+func (t *Tree[T]) sortBelow(ctx context.Context, parent *Node[T], less LessFn[T]) error {
+	type reorder struct {
+		parent        *Node[T]
+		before, after []*Node[T]
+	}
+	var changes []reorder
+
+	compare := sortCompare(less)
+	lists := []*Node[T]{parent}
+	for len(lists) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p := lists[len(lists)-1]
+		lists = lists[:len(lists)-1]
+
+		old := t.nodes
+		if p != nil {
+			old = p.children
+		}
+		for _, child := range old {
+			if child.HasChildren() {
+				lists = append(lists, child)
+			}
+		}
+		if slices.IsSortedFunc(old, compare) {
+			continue
+		}
+		sorted := slices.Clone(old)
+		slices.SortStableFunc(sorted, compare)
+		changes = append(changes, reorder{parent: p, before: old, after: sorted})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var reordered []string // Nil if the roots were reordered
+	for _, c := range changes {
+		if c.parent == nil {
+			reordered = nil
+			break
+		}
+		reordered = append(reordered, c.parent.ID())
+	}
+	apply := func(sorted bool) {
+		t.emit(EventStructure, reordered)
+		for _, c := range changes {
+			list := c.before
+			if sorted {
+				list = c.after
+			}
+			if c.parent == nil {
+				t.nodes = list
+			} else {
+				c.parent.children = list
+				t.invalidateAggregates(c.parent)
+				t.touch(c.parent)
+			}
+		}
+		t.indexReordered()
+	}
+	apply(true)
+	parents := make([]*Node[T], len(changes))
+	for i, c := range changes {
+		parents[i] = c.parent
+	}
+	t.history.record(func() { apply(false) }, func() { apply(true) }, parents...)
+	return nil
+}
+
+// CycleSort re-sorts the tree by the next of the configured sort modes (see
+// WithTuiSortModes), wrapping around after the last one.
+func (m *TuiTreeModel[T]) CycleSort() {
+	if len(m.sortModes) == 0 {
+		return
+	}
+	m.sortIndex = (m.sortIndex + 1) % len(m.sortModes)
+	less := m.sortModes[m.sortIndex].Less
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		return m.Sort(ctx, less)
+	})
+}
+
+// SortModeName returns the name of the sort mode last applied with
+// CycleSort, or "" if none was.
+func (m *TuiTreeModel[T]) SortModeName() string {
+	if m.sortIndex < 0 || m.sortIndex >= len(m.sortModes) {
+		return ""
+	}
+	return m.sortModes[m.sortIndex].Name
+}
@@ -0,0 +1,202 @@
+package treeview
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"File1", "file2", -1},
+		{"a", "B", -1},
+		{"abc", "abcd", -1},
+		{"x01", "x1", -1}, // Equal value, broken by the raw strings
+		{"v1.10", "v1.9", 1},
+		{"same", "same", 0},
+		{"Same", "same", -1},
+		{"äpfel", "zebra", 1},
+		{"", "a", -1},
+	}
+
+	for _, test := range tests {
+		got := NaturalCompare(test.a, test.b)
+		if got < 0 {
+			got = -1
+		} else if got > 0 {
+			got = 1
+		}
+		if got != test.want {
+			t.Errorf("NaturalCompare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+// createSortTree returns roots "b10", "b9" and "a" where "b10" has children
+// "x2", "x10" and "X1".
+func createSortTree(opts ...Option[string]) *Tree[string] {
+	b10 := NewNode("b10", "b10", "")
+	b10.SetChildren([]*Node[string]{NewNode("x2", "x2", ""), NewNode("x10", "x10", ""), NewNode("X1", "X1", "")})
+	return NewTree([]*Node[string]{b10, NewNode("b9", "b9", ""), NewNode("a", "a", "")}, opts...)
+}
+
+// allIDs returns the IDs of every node in depth-first order.
+func allIDs(t *testing.T, tree *Tree[string]) []string {
+	t.Helper()
+	var ids []string
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		ids = append(ids, info.Node.ID())
+	}
+	return ids
+}
+
+func TestTree_Sort(t *testing.T) {
+	ctx := context.Background()
+	tree := createSortTree()
+	original := allIDs(t, tree)
+
+	if err := tree.Sort(ctx, ByName[string]()); err != nil {
+		t.Fatalf("Sort() error = %v", err)
+	}
+	want := []string{"a", "b9", "b10", "X1", "x2", "x10"}
+	if diff := cmp.Diff(want, allIDs(t, tree)); diff != "" {
+		t.Errorf("Sort() order mismatch (-want +got):\n%s", diff)
+	}
+	if got := tree.GetFocusedID(); got != "b10" {
+		t.Errorf("focused after Sort() = %q, want b10", got)
+	}
+
+	if _, err := tree.Undo(ctx); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if diff := cmp.Diff(original, allIDs(t, tree)); diff != "" {
+		t.Errorf("order after Undo() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := tree.SortSubtree(ctx, "b10", Reverse(ByName[string]())); err != nil {
+		t.Fatalf("SortSubtree() error = %v", err)
+	}
+	want = []string{"b10", "x10", "x2", "X1", "b9", "a"}
+	if diff := cmp.Diff(want, allIDs(t, tree)); diff != "" {
+		t.Errorf("SortSubtree() order mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := tree.SortSubtree(ctx, "missing", ByName[string]()); err != ErrNodeNotFound {
+		t.Errorf("SortSubtree(missing) error = %v, want %v", err, ErrNodeNotFound)
+	}
+}
+
+func TestTree_Sort_StableAndSorted(t *testing.T) {
+	ctx := context.Background()
+	tree := createSortTree(WithSort(ByName[string]()))
+	if diff := cmp.Diff([]string{"a", "b9", "b10", "X1", "x2", "x10"}, allIDs(t, tree)); diff != "" {
+		t.Errorf("WithSort() order mismatch (-want +got):\n%s", diff)
+	}
+	if got := tree.GetFocusedID(); got != "a" {
+		t.Errorf("initial focus = %q, want the first sorted root", got)
+	}
+
+	// Sorting an already sorted tree records nothing
+	if err := tree.Sort(ctx, ByName[string]()); err != nil {
+		t.Fatalf("Sort() error = %v", err)
+	}
+	if tree.CanUndo() {
+		t.Error("CanUndo() = true after sorting a sorted tree")
+	}
+
+	// Equal keys keep their order
+	byLength := func(a, b *Node[string]) bool { return len(a.Name()) < len(b.Name()) }
+	if err := tree.Sort(ctx, byLength); err != nil {
+		t.Fatalf("Sort() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"a", "b9", "b10", "X1", "x2", "x10"}, allIDs(t, tree)); diff != "" {
+		t.Errorf("stable Sort() order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFileSortModes(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, f := range []struct {
+		name string
+		size int
+	}{{"b.txt", 30}, {"a10.txt", 10}, {"a9.txt", 20}} {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, make([]byte, f.size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "z"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"dirs first": {"z", "a9.txt", "a10.txt", "b.txt"},
+		"name":       {"a9.txt", "a10.txt", "b.txt", "z"},
+		"size":       {"b.txt", "a9.txt", "a10.txt", "z"},
+	}
+	tree, err := NewTreeFromFileSystem(context.Background(), dir, false)
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	for _, mode := range FileSortModes() {
+		if err := tree.Sort(context.Background(), mode.Less); err != nil {
+			t.Fatalf("Sort(%s) error = %v", mode.Name, err)
+		}
+		var got []string
+		for _, child := range tree.Nodes()[0].Children() {
+			got = append(got, child.Name())
+		}
+		if mode.Name == "modified" {
+			// The directory was created last
+			got = got[1:]
+			if diff := cmp.Diff([]string{"a9.txt", "a10.txt", "b.txt"}, got); diff != "" {
+				t.Errorf("%s order mismatch (-want +got):\n%s", mode.Name, diff)
+			}
+			continue
+		}
+		if diff := cmp.Diff(want[mode.Name], got); diff != "" {
+			t.Errorf("%s order mismatch (-want +got):\n%s", mode.Name, diff)
+		}
+	}
+}
+
+func TestCycleSortKey(t *testing.T) {
+	byLength := SortMode[string]{Name: "length", Less: func(a, b *Node[string]) bool { return len(a.Name()) < len(b.Name()) }}
+	model := NewTuiTreeModel(createSortTree(),
+		WithTuiSortModes(SortMode[string]{Name: "name", Less: ByName[string]()}, byLength))
+
+	if got := model.SortModeName(); got != "" {
+		t.Errorf("SortModeName() = %q before sorting, want empty", got)
+	}
+	for _, want := range []string{"name", "length", "name"} {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+		if got := model.SortModeName(); got != want {
+			t.Errorf("SortModeName() = %q, want %q", got, want)
+		}
+	}
+	if diff := cmp.Diff([]string{"a", "b9", "b10", "X1", "x2", "x10"}, allIDs(t, model.Tree)); diff != "" {
+		t.Errorf("order mismatch (-want +got):\n%s", diff)
+	}
+	if nav := model.NavBar(); !strings.Contains(nav, "Sort (name)") {
+		t.Errorf("NavBar() = %q, want the sort mode", nav)
+	}
+}
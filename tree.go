@@ -23,20 +23,70 @@ import (
 
 // Tree wraps a collection of nodes and offers rich operations such as
 // filtering, searching, focusing, and rendering. All public methods are safe
-// for concurrent use; a sync.RWMutex guards internal state.
+// for concurrent use; a sync.RWMutex guards internal state, including the
+// expanded, visible and data fields of its nodes. Change nodes through Tree
+// methods such as SetExpanded and SetNodeData while other goroutines use the
+// tree.
 type Tree[T any] struct {
 	mu           sync.RWMutex
 	nodes        []*Node[T]
 	focusedNodes []*Node[T]
 	focusedIDs   map[string]bool
 
-	searcher SearchFn[T]
-	focusPol FocusPolicyFn[T]
-	provider NodeProvider[T]
+	// selectedIDs and partialIDs hold the checkbox selection; see selection.go.
+	selectedIDs      map[string]bool
+	partialIDs       map[string]bool
+	selectionVersion uint64
+
+	searcher   SearchFn[T]
+	matcher    MatchFn[T]
+	focusPol   FocusPolicyFn[T]
+	provider   NodeProvider[T]
+	movePolicy MovePolicyFn[T]
 
 	// truncateWidth specifies the maximum width for rendered lines.
 	// 0 means no truncation (default).
 	truncateWidth int
+
+	// checkboxes renders a tri-state check mark in front of every node.
+	checkboxes bool
+
+	// filter is the visibility overlay of an active filter; see filter.go.
+	filter            *filterView[T]
+	filterDescendants bool
+
+	// hoist is the node shown as the only root by AllVisible, hoistOpened
+	// the nodes hoisting expanded or showed; see hoist.go.
+	hoist       *Node[T]
+	hoistOpened []hoistOpening[T]
+
+	// searchWorkers is the number of goroutines evaluating the matcher.
+	searchWorkers int
+
+	// index is the optional search index; see index.go.
+	index *searchIndex[T]
+
+	// matches holds the match details of the last SearchAndExpand by node
+	// ID; the renderer highlights their ranges. contentNodes are the nodes
+	// whose FileInfo.Extra holds their content matches; see content.go.
+	matches      map[string]Match
+	contentNodes []*Node[T]
+
+	// aggregates are the registered aggregations; see aggregate.go.
+	aggregates []aggregateCache[T]
+
+	// fsSource records how NewTreeFromFileSystem built the tree, for
+	// WatchFileSystem; see watch.go.
+	fsSource *fileSystemSource
+
+	// events holds the change subscriptions; see events.go.
+	events eventHub
+
+	// expansionVersion increases on every expansion change.
+	expansionVersion uint64
+
+	// history records undoable mutations; see history.go.
+	history history[T]
 }
 
 // Nodes returns the current root slice. The caller must treat the returned
@@ -49,10 +99,59 @@ func (t *Tree[T]) Nodes() []*Node[T] {
 
 // SetNodes replaces the root nodes of the tree. This is useful for removing
 // root nodes or restructuring the tree. The operation is thread-safe.
+//
+// Everything tied to the old nodes is dropped: the undo history, the hoist,
+// an active filter, search matches and the checkbox selection. Focused nodes
+// that are not part of the new tree lose the focus.
 func (t *Tree[T]) SetNodes(nodes []*Node[T]) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.nodes = nodes
+	t.hoist = nil
+	t.hoistOpened = nil
+	if t.filter != nil {
+		t.filter = nil
+		t.emit(EventVisibility, nil)
+	}
+	t.setMatches(nil, nil)
+	t.history.undo = nil
+	t.history.redo = nil
+	if len(t.selectedIDs) > 0 || len(t.partialIDs) > 0 {
+		t.selectedIDs = make(map[string]bool)
+		t.partialIDs = make(map[string]bool)
+		t.selectionVersion++
+	}
+	if t.index != nil {
+		t.index.reset()
+	}
+	t.resetAggregates()
+	t.emit(EventStructure, nil)
+
+	// Only nodes of the new tree can keep the focus
+	focused := t.focusedNodes[:0:0]
+	for _, node := range t.focusedNodes {
+		if t.contains(node) {
+			focused = append(focused, node)
+		}
+	}
+	if len(focused) != len(t.focusedNodes) {
+		t.focusNodes(focused)
+	}
+}
+
+// Provider returns the provider used to render nodes.
+func (t *Tree[T]) Provider() NodeProvider[T] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.provider
+}
+
+// SetProvider replaces the provider used to render nodes, for example with
+// one whose formatter reads an Aggregation of this tree.
+func (t *Tree[T]) SetProvider(provider NodeProvider[T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.provider = provider
 }
 
 // GetFocusedID returns the ID of the currently focused node or "" if none.
@@ -87,6 +186,9 @@ func (t *Tree[T]) SetFocusedID(ctx context.Context, id string) (bool, error) {
 		changed := len(t.focusedNodes) > 0
 		t.focusedNodes = nil
 		t.focusedIDs = make(map[string]bool)
+		if changed {
+			t.emitFocus()
+		}
 		return changed, nil
 	}
 
@@ -99,6 +201,9 @@ func (t *Tree[T]) SetFocusedID(ctx context.Context, id string) (bool, error) {
 	// Now acquire the lock to update the focused node
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return false, ErrNodeNotFound
+	}
 
 	// Check if we're already focused on this node (and only this node)
 	if len(t.focusedNodes) == 1 && t.focusedNodes[0] == node {
@@ -108,6 +213,7 @@ func (t *Tree[T]) SetFocusedID(ctx context.Context, id string) (bool, error) {
 	// Clear multi-focus and set single focus (backward compatible)
 	t.focusedNodes = []*Node[T]{node}
 	t.focusedIDs = map[string]bool{node.ID(): true}
+	t.emitFocus()
 	return true, nil
 }
 
@@ -155,11 +261,13 @@ func (t *Tree[T]) Move(ctx context.Context, offset int) (bool, error) {
 		return false, err
 	}
 
-	// Update focus if it actually changed
-	if next != currentFocus {
+	// Update focus if it actually changed. The policy finds nothing when no
+	// node is visible, for example while a search hides the tree
+	if next != nil && next != currentFocus {
 		// Clear multi-focus and set single focus (backward compatible)
 		t.focusedNodes = []*Node[T]{next}
 		t.focusedIDs = map[string]bool{next.ID(): true}
+		t.emitFocus()
 		return true, nil
 	}
 
@@ -178,21 +286,81 @@ func (t *Tree[T]) SetExpanded(ctx context.Context, id string, expanded bool) (bo
 	}
 
 	// Apply the requested expansion state
-	if expanded {
-		node.Expand()
-	} else {
-		node.Collapse()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return false, ErrNodeNotFound
 	}
+	t.setNodeExpanded(node, expanded)
 	return true, nil
 }
 
-// ToggleFocused flips the expansion state of all focused nodes.
-func (t *Tree[T]) ToggleFocused(ctx context.Context) {
+// setNodeExpanded changes the expansion state of node and records the change
+// in the undo history. Callers must hold t.mu.
+func (t *Tree[T]) setNodeExpanded(node *Node[T], expanded bool) {
+	was := node.IsExpanded()
+	if was == expanded {
+		return
+	}
+	set := func(expanded bool) {
+		node.SetExpanded(expanded)
+		t.expansionVersion++
+		t.touch(node)
+		t.emit(EventExpansion, []string{node.ID()})
+	}
+	set(expanded)
+	t.history.record(
+		func() { set(was) },
+		func() { set(expanded) },
+		node,
+	)
+}
+
+// Rename sets the display name of the node with the given ID. Returns
+// ErrNodeNotFound if the ID doesn't exist, or context errors unwrapped.
+func (t *Tree[T]) Rename(ctx context.Context, id, name string) error {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	for _, node := range t.focusedNodes {
-		node.Toggle()
-	}
+	if !t.contains(node) {
+		return ErrNodeNotFound
+	}
+	oldName := node.name
+	t.renameNode(node, name)
+	t.history.record(
+		func() { t.renameNode(node, oldName) },
+		func() { t.renameNode(node, name) },
+		node,
+	)
+	return nil
+}
+
+// renameNode changes the display name of node, which must belong to the
+// tree, and updates the search index, aggregates and snapshot copies. It
+// doesn't record history. Callers must hold t.mu.
+func (t *Tree[T]) renameNode(node *Node[T], name string) {
+	node.SetName(name)
+	t.indexUpdate(node)
+	t.invalidateAggregates(node)
+	t.touch(node)
+	t.emit(EventData, []string{node.ID()})
+}
+
+// ToggleFocused flips the expansion state of all focused nodes. The change is
+// recorded as a single undo step.
+func (t *Tree[T]) ToggleFocused(ctx context.Context) {
+	_ = t.Group(func() error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for _, node := range t.focusedNodes {
+			t.setNodeExpanded(node, !node.IsExpanded())
+		}
+		return nil
+	})
 }
 
 // ExpandAll expands every node in the tree. Returns context errors unwrapped.
@@ -215,46 +383,123 @@ func (t *Tree[T]) HideAll(ctx context.Context) error {
 	return t.setVisibleState(ctx, false)
 }
 
-// Search scans the tree for nodes matching term. Returns context errors unwrapped.
+// Search scans the tree for nodes matching term. When a MatchFn is
+// configured (see WithMatcher) the results are ordered by descending score,
+// ties keeping tree order. If ctx ends before the whole tree was searched, the
+// matches found so far are returned together with the context error; use
+// StartSearch to resume instead. Returns context errors unwrapped.
 func (t *Tree[T]) Search(ctx context.Context, term string) ([]*Node[T], error) {
+	results, err := t.SearchMatches(ctx, term)
+	return resultNodes(results), err
+}
+
+// resultNodes returns the nodes of results, or nil if there are none.
+func resultNodes[T any](results []SearchResult[T]) []*Node[T] {
+	if len(results) == 0 {
+		return nil
+	}
+	nodes := make([]*Node[T], 0, len(results))
+	for _, result := range results {
+		nodes = append(nodes, result.Node)
+	}
+	return nodes
+}
+
+// SearchMatches is Search with the score and matched ranges of every result.
+// Returns context errors unwrapped.
+func (t *Tree[T]) SearchMatches(ctx context.Context, term string) ([]SearchResult[T], error) {
+	return t.searchMatches(ctx, term, nil)
+}
+
+// searchMatches collects the nodes accepted by match, best scores first. A
+// nil match uses the configured matcher and search index. If ctx ends early
+// the matches found so far are returned with the context error.
+func (t *Tree[T]) searchMatches(ctx context.Context, term string, match MatchFn[T]) ([]SearchResult[T], error) {
 	// Empty search term returns no results
 	if term == "" {
 		return nil, nil
 	}
 
-	// Collect all matching nodes using iterator
-	var results []*Node[T]
-	for info, err := range t.All(ctx) {
-		if err != nil {
-			return results, err
-		}
-		// Use the configured searcher to check for matches
-		// This allows custom search logic per tree instance
-		if t.searcher(ctx, info.Node, term) {
-			results = append(results, info.Node)
-		}
+	scan, err := t.StartSearch(ctx, term, match)
+	if err != nil {
+		return nil, err
+	}
+	err = scan.Continue(ctx)
+	return scan.Results(), err
+}
+
+// match runs the configured matcher, falling back to the plain searcher.
+func (t *Tree[T]) match(ctx context.Context, node *Node[T], term string) (Match, bool) {
+	if t.matcher != nil {
+		return t.matcher(ctx, node, term)
 	}
+	return Match{}, t.searcher(ctx, node, term)
+}
+
+// ClearSearch forgets the match ranges recorded by the last SearchAndExpand
+// so they are no longer highlighted.
+func (t *Tree[T]) ClearSearch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.setMatches(nil, nil)
+}
+
+// MatchFor returns the match details recorded for the node with id by the
+// last SearchAndExpand or Filter, such as the matching lines ContentMatcher
+// finds, so a detail panel can show them. It reports false if the node
+// didn't match or no search is active.
+func (t *Tree[T]) MatchFor(id string) (Match, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	m, ok := t.matches[id]
+	return m, ok
+}
 
-	return results, nil
+// matchRanges returns the highlight ranges recorded for the node with id.
+// Callers must hold t.mu.
+func (t *Tree[T]) matchRanges(id string) []MatchRange {
+	return t.matches[id].Ranges
 }
 
 // SearchAndExpand performs Search and then expands all ancestor nodes of each
 // match so the results become visible. The first match is focused.
 // Returns context errors unwrapped.
 func (t *Tree[T]) SearchAndExpand(ctx context.Context, term string) ([]*Node[T], error) {
+	return t.SearchAndExpandWith(ctx, term, nil)
+}
+
+// SearchAndExpandWith is SearchAndExpand using match instead of the tree's
+// configured matcher, for example a compiled query (see PredicateMatcher). A
+// nil match uses the configured matcher and search index. Returns context
+// errors unwrapped.
+func (t *Tree[T]) SearchAndExpandWith(ctx context.Context, term string, match MatchFn[T]) ([]*Node[T], error) {
 	// Empty search term means clear search but stay in search mode
 	if term == "" {
+		t.ClearSearch()
 		return nil, nil
 	}
 
 	// Find all matching nodes
-	matches, err := t.Search(ctx, term)
+	results, err := t.searchMatches(ctx, term, match)
 	if err != nil {
-		return matches, err
+		return nil, err
+	}
+	return t.expandMatches(ctx, results)
+}
+
+// expandMatches shows, expands towards and focuses the nodes of results, as
+// SearchAndExpand does. Returns context errors unwrapped.
+func (t *Tree[T]) expandMatches(ctx context.Context, results []SearchResult[T]) ([]*Node[T], error) {
+	matches := make([]*Node[T], 0, len(results))
+	ranges := make(map[string]Match, len(results))
+	for _, result := range results {
+		matches = append(matches, result.Node)
+		ranges[result.Node.ID()] = result.Match
 	}
 
 	// If no matches, expand all and return
 	if len(matches) == 0 {
+		t.ClearSearch()
 		// Just clear search highlights but don't change expansion state
 		if err := t.ShowAll(ctx); err != nil {
 			return nil, err
@@ -262,7 +507,7 @@ func (t *Tree[T]) SearchAndExpand(ctx context.Context, term string) ([]*Node[T],
 		if err := t.ExpandAll(ctx); err != nil {
 			return nil, err
 		}
-		return matches, nil
+		return nil, nil
 	}
 
 	// Collapse and hide all nodes before acquiring lock
@@ -278,25 +523,50 @@ func (t *Tree[T]) SearchAndExpand(ctx context.Context, term string) ([]*Node[T],
 	defer t.mu.Unlock()
 
 	// Mark all matches and their ancestors for expansion
+	var changes nodeStateChanges[T]
 	for _, match := range matches {
 		current := match
 		for current != nil {
-			current.Expand()
-			current.SetVisible(true)
+			changes.expand(current, true)
+			changes.show(current, true)
 			current = current.Parent()
 		}
 	}
+	t.commitStateChanges(changes)
+
+	// Remember the match details for highlighting
+	t.setMatches(ranges, matches)
 
 	// Focus all matching nodes
-	t.focusedNodes = make([]*Node[T], len(matches))
-	copy(t.focusedNodes, matches)
-	t.focusedIDs = make(map[string]bool, len(matches))
-	for _, match := range matches {
-		t.focusedIDs[match.ID()] = true
-	}
+	t.focusNodes(matches)
 	return matches, nil
 }
 
+// Reveal makes the node with the given ID visible by showing and expanding
+// all of its ancestors. Like search expansion, this is navigation and is not
+// recorded in the undo history. Returns ErrNodeNotFound if the ID doesn't
+// exist, or context errors unwrapped.
+func (t *Tree[T]) Reveal(ctx context.Context, id string) error {
+	node, err := t.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return ErrNodeNotFound
+	}
+	var changes nodeStateChanges[T]
+	changes.show(node, true)
+	for p := node.Parent(); p != nil; p = p.Parent() {
+		changes.expand(p, true)
+		changes.show(p, true)
+	}
+	t.commitStateChanges(changes)
+	return nil
+}
+
 // Render produces a string representation of the tree using the configured
 // renderer. Returns context errors unwrapped.
 func (t *Tree[T]) Render(ctx context.Context) (string, error) {
@@ -322,21 +592,30 @@ func (t *Tree[T]) visibleNodes(ctx context.Context) ([]*Node[T], error) {
 
 // setExpandedState is a helper method used by ExpandAll and CollapseAll.
 func (t *Tree[T]) setExpandedState(ctx context.Context, expanded bool) error {
-	for info, err := range t.All(ctx) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var changes nodeStateChanges[T]
+	defer func() { t.commitStateChanges(changes) }()
+	for info, err := range dfsSeq(ctx, t.nodes, true, nil) {
 		if err != nil {
 			return err
 		}
-		info.Node.SetExpanded(expanded)
+		changes.expand(info.Node, expanded)
 	}
 	return nil
 }
 
+// setVisibleState is a helper method used by ShowAll and HideAll.
 func (t *Tree[T]) setVisibleState(ctx context.Context, visible bool) error {
-	for info, err := range t.All(ctx) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var changes nodeStateChanges[T]
+	defer func() { t.commitStateChanges(changes) }()
+	for info, err := range dfsSeq(ctx, t.nodes, true, nil) {
 		if err != nil {
 			return err
 		}
-		info.Node.SetVisible(visible)
+		changes.show(info.Node, visible)
 	}
 	return nil
 }
@@ -381,6 +660,9 @@ func (t *Tree[T]) AddFocusedID(ctx context.Context, id string) error {
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if !t.contains(node) {
+		return ErrNodeNotFound
+	}
 
 	// Check if already focused
 	if t.focusedIDs[id] {
@@ -393,6 +675,7 @@ func (t *Tree[T]) AddFocusedID(ctx context.Context, id string) error {
 		t.focusedIDs = make(map[string]bool)
 	}
 	t.focusedIDs[id] = true
+	t.emitFocus()
 	return nil
 }
 
@@ -416,6 +699,7 @@ func (t *Tree[T]) RemoveFocusedID(ctx context.Context, id string) error {
 			break
 		}
 	}
+	t.emitFocus()
 	return nil
 }
 
@@ -433,6 +717,11 @@ func (t *Tree[T]) SetAllFocusedIDs(ctx context.Context, ids []string) error {
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	for _, node := range nodes {
+		if !t.contains(node) {
+			return ErrNodeNotFound
+		}
+	}
 
 	// Replace focused state
 	t.focusedNodes = nodes
@@ -440,6 +729,7 @@ func (t *Tree[T]) SetAllFocusedIDs(ctx context.Context, ids []string) error {
 	for _, id := range ids {
 		t.focusedIDs[id] = true
 	}
+	t.emitFocus()
 	return nil
 }
 
@@ -449,6 +739,7 @@ func (t *Tree[T]) ClearAllFocus() {
 	defer t.mu.Unlock()
 	t.focusedNodes = nil
 	t.focusedIDs = make(map[string]bool)
+	t.emitFocus()
 }
 
 // ToggleFocusedID toggles the focus state of the node with the given ID.
@@ -500,6 +791,7 @@ func (t *Tree[T]) MoveExtend(ctx context.Context, offset int) (bool, error) {
 		if next != nil {
 			t.focusedNodes = []*Node[T]{next}
 			t.focusedIDs = map[string]bool{next.ID(): true}
+			t.emitFocus()
 		}
 		return true, nil
 	}
@@ -527,7 +819,7 @@ func (t *Tree[T]) MoveExtend(ctx context.Context, offset int) (bool, error) {
 			break
 		}
 	}
-
+	t.emitFocus()
 	return true, nil
 }
 
@@ -3,7 +3,11 @@ package treeview
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestTree_GetFocusedID_NoFocus(t *testing.T) {
@@ -489,6 +493,76 @@ func TestTree_ConcurrentAccess(t *testing.T) {
 	// If we get here without data races, the test passes
 }
 
+// Run with -race: rendering, searching, navigating and mutating from several
+// goroutines must not touch node state outside the tree lock.
+func TestTree_ConcurrentStress(t *testing.T) {
+	ctx := context.Background()
+	var roots []*Node[string]
+	for i := range 5 {
+		root := NewNode(fmt.Sprint(i), fmt.Sprintf("root %d", i), "")
+		for j := range 10 {
+			root.AddChild(NewNode(fmt.Sprintf("%d.%d", i, j), fmt.Sprintf("leaf %d", j), ""))
+		}
+		roots = append(roots, root)
+	}
+	tree := NewTree(roots, WithExpandAll[string](), WithSearchIndex[string](nil))
+	model := NewTuiTreeModel(tree, WithTuiWidth[string](80), WithTuiHeight[string](10))
+	_, _ = tree.SetFocusedID(ctx, "0")
+
+	const rounds = 50
+	var wg sync.WaitGroup
+	run := func(step func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				step(i)
+			}
+		}()
+	}
+
+	// The model is not shared: one goroutine renders and navigates it
+	run(func(i int) {
+		_ = model.View()
+		model.NavigateDown()
+		model.Toggle()
+		model.Expand()
+		model.Collapse()
+		model.NavigateUp()
+	})
+	run(func(i int) {
+		_, _ = tree.Render(ctx)
+		for range tree.AllVisible(ctx) {
+		}
+		_, _ = tree.Move(ctx, 1)
+		_, _ = tree.MoveExtend(ctx, -1)
+	})
+	run(func(i int) {
+		_, _ = tree.SearchAndExpand(ctx, "leaf 3")
+		_, _ = tree.Filter(ctx, "root")
+		tree.ClearFilter()
+	})
+	run(func(i int) {
+		id := fmt.Sprintf("new.%d", i)
+		_ = tree.InsertNode(ctx, "1", 0, NewNode(id, "leaf new", ""))
+		_ = tree.SetNodeData(ctx, "2.3", fmt.Sprint(i))
+		_ = tree.Rename(ctx, "3.4", fmt.Sprintf("leaf %d", i))
+		_, _ = tree.SetExpanded(ctx, "4", i%2 == 0)
+		_, _ = tree.RemoveNode(ctx, id)
+	})
+	run(func(i int) {
+		_ = tree.CollapseAll(ctx)
+		_ = tree.ShowAll(ctx)
+		_ = tree.ExpandAll(ctx)
+		_, _ = tree.Undo(ctx)
+	})
+	wg.Wait()
+
+	if _, err := tree.Render(ctx); err != nil {
+		t.Errorf("Render() error = %v", err)
+	}
+}
+
 func TestTree_Move_VisibleNodesError(t *testing.T) {
 	tree := NewTree([]*Node[string]{NewNode("root", "root", "root")})
 
@@ -562,3 +636,71 @@ func TestTree_SearchAndExpand_WithMatches(t *testing.T) {
 		t.Errorf("SearchAndExpand(match) child1 visible = %v, want true", child1.IsVisible())
 	}
 }
+
+func TestTree_Rename(t *testing.T) {
+	ctx := context.Background()
+	node := NewNode("id", "old", "data")
+	tree := NewTree([]*Node[string]{node})
+
+	if err := tree.Rename(ctx, "id", "new"); err != nil {
+		t.Fatalf("Rename(id, new) error = %v, want nil", err)
+	}
+	if got := node.Name(); got != "new" {
+		t.Errorf("Rename(id, new) name = %q, want %q", got, "new")
+	}
+
+	if err := tree.Rename(ctx, "missing", "x"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Rename(missing) error = %v, want %v", err, ErrNodeNotFound)
+	}
+}
+
+func TestTree_SetNodes_DropsOldState(t *testing.T) {
+	ctx := context.Background()
+	tree := NewTree(createSimpleTree(t).Nodes())
+	tree.history.limit = defaultHistoryLimit
+	if _, err := tree.RemoveNode(ctx, "2"); err != nil {
+		t.Fatalf("RemoveNode() error = %v", err)
+	}
+	if err := tree.SetSelected(ctx, "1.2", true); err != nil {
+		t.Fatalf("SetSelected() error = %v", err)
+	}
+	if _, err := tree.Filter(ctx, "Node 1.1"); err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	kept := tree.Nodes()[0]
+	fresh := NewNode("new", "new", "new")
+	fresh.AddChild(NewNode("new.1", "Node 1.1 copy", "x"))
+	tree.SetNodes([]*Node[string]{kept, fresh})
+
+	// Undoing the removal would re-attach "2" to a tree it no longer belongs to
+	if undone, err := tree.Undo(ctx); err != nil || undone {
+		t.Errorf("Undo() = %v, %v after SetNodes, want false, nil", undone, err)
+	}
+	if tree.IsFiltering() {
+		t.Error("IsFiltering() = true after SetNodes, want false")
+	}
+	if ids := tree.SelectedIDs(); len(ids) != 0 {
+		t.Errorf("SelectedIDs() = %v after SetNodes, want none", ids)
+	}
+	if _, ok := tree.MatchFor("1.1"); ok {
+		t.Error("MatchFor(1.1) = true after SetNodes, want false")
+	}
+	// The filter's focus stays, since those nodes are still in the tree
+	if diff := cmp.Diff([]string{"1.1", "1.1.1"}, tree.GetAllFocusedIDs()); diff != "" {
+		t.Errorf("GetAllFocusedIDs() mismatch (-want +got):\n%s", diff)
+	}
+
+	// A new filter works on the new nodes
+	if _, err := tree.Filter(ctx, "copy"); err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"new", "new.1"}, visibleIDs(t, tree.AllVisible)); diff != "" {
+		t.Errorf("AllVisible() after filtering mismatch (-want +got):\n%s", diff)
+	}
+
+	// Focus on nodes that are gone is dropped
+	tree.SetNodes([]*Node[string]{kept})
+	if ids := tree.GetAllFocusedIDs(); len(ids) != 0 {
+		t.Errorf("GetAllFocusedIDs() = %v after removing the focused nodes, want none", ids)
+	}
+}
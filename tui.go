@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -34,7 +35,9 @@ func WithTuiNavigationTimeout[T any](d time.Duration) TuiTreeModelOption[T] {
 	return func(m *TuiTreeModel[T]) { m.navigationTimeout = d }
 }
 
-// WithTuiSearchTimeout sets the timeout for search operations.
+// WithTuiSearchTimeout sets how long a search may run per frame. A search
+// that needs longer shows the matches found so far and continues in the
+// background; see WithTuiSearchLimit.
 func WithTuiSearchTimeout[T any](d time.Duration) TuiTreeModelOption[T] {
 	return func(m *TuiTreeModel[T]) { m.searchTimeout = d }
 }
@@ -49,6 +52,84 @@ func WithTuiDisableNavBar[T any](disable bool) TuiTreeModelOption[T] {
 	return func(m *TuiTreeModel[T]) { m.disableNavBar = disable }
 }
 
+// WithTuiQuerySearch makes search mode interpret the typed text as a query
+// (see QueryParser) instead of a plain search term. It adds SearchQuery to the
+// available search modes and selects it. Parse errors are shown next to the
+// prompt. A nil parser uses NewQueryParser.
+func WithTuiQuerySearch[T any](parser *QueryParser[T]) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) {
+		if parser == nil {
+			parser = NewQueryParser[T]()
+		}
+		m.queryParser = parser
+		if !slices.Contains(m.searchModes, SearchQuery) {
+			m.searchModes = append(slices.Clone(m.searchModes), SearchQuery)
+		}
+		m.searchMode = SearchQuery
+	}
+}
+
+// WithTuiSearchModes sets the search modes the user can cycle through with
+// KeyMap.SearchModeNext and SearchModePrev. The first mode is active initially.
+func WithTuiSearchModes[T any](modes ...SearchMode) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) {
+		m.searchModes = slices.Clone(modes)
+		if len(modes) > 0 {
+			m.searchMode = modes[0]
+		}
+	}
+}
+
+// WithTuiSearchDebounce delays evaluating the search until no key has been
+// typed for d, which keeps typing responsive in large trees. Accepting the
+// search evaluates a pending term at once. Defaults to 100ms; 0 searches on
+// every key press.
+func WithTuiSearchDebounce[T any](d time.Duration) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) { m.searchDebounce = d }
+}
+
+// WithTuiFilterMode makes search filter the tree instead of expanding it:
+// only matches and their ancestors are shown (see Tree.Filter) and the
+// expansion state is left alone. Cancelling the search restores the expansion
+// and focus from before the search started.
+func WithTuiFilterMode[T any]() TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) { m.filterMode = true }
+}
+
+// WithTuiSearchLimit stops a search that is still running in the background
+// after d; the matches found by then are marked as partial results. The
+// default of 0 lets the search run until the whole tree was scanned.
+func WithTuiSearchLimit[T any](d time.Duration) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) { m.searchLimit = d }
+}
+
+// WithTuiSortModes sets the sort orders KeyMap.SortNext cycles through, such
+// as FileSortModes for file system trees. Without sort modes the binding does
+// nothing.
+func WithTuiSortModes[T any](modes ...SortMode[T]) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) { m.sortModes = slices.Clone(modes) }
+}
+
+// RenameValidateFn checks a candidate name while the user edits a node label.
+// A non-nil error is shown next to the input and blocks accepting the name.
+type RenameValidateFn[T any] func(node *Node[T], name string) error
+
+// RenameCommitFn is called when the user accepts a new name. It returns the
+// name that should be stored, which allows transforming the input, or an
+// error to veto the rename and keep the editor open.
+type RenameCommitFn[T any] func(node *Node[T], name string) (string, error)
+
+// WithTuiRenameValidator installs a validation hook for inline renaming.
+func WithTuiRenameValidator[T any](fn RenameValidateFn[T]) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) { m.renameValidate = fn }
+}
+
+// WithTuiRenameCommit installs a callback that can veto or transform a name
+// before it is applied to the node.
+func WithTuiRenameCommit[T any](fn RenameCommitFn[T]) TuiTreeModelOption[T] {
+	return func(m *TuiTreeModel[T]) { m.renameCommit = fn }
+}
+
 // KeyMap groups key bindings for the interactive TUI. Provide your own via
 // WithTuiKeyMap if you need to accommodate non-US layouts or match existing shortcuts.
 type KeyMap struct {
@@ -61,15 +142,67 @@ type KeyMap struct {
 	Toggle   []string
 	Reset    []string
 
+	// Activate emits a NodeActivatedMsg for the focused node.
+	Activate []string
+
 	// Multi-focus keys
 	ExtendUp   []string
 	ExtendDown []string
 
+	// Checkbox selection keys
+	SelectToggle []string
+	SelectAll    []string
+
+	// Structure editing keys
+	MoveUp   []string
+	MoveDown []string
+	Indent   []string
+	Outdent  []string
+
+	// History keys
+	Undo []string
+	Redo []string
+
 	// Search keys
 	SearchStart  []string
 	SearchAccept []string
 	SearchCancel []string
 	SearchDelete []string
+
+	// Match navigation keys step through the results of the last search
+	NextMatch []string
+	PrevMatch []string
+
+	// Search mode keys cycle through the configured search modes while the
+	// search prompt is open
+	SearchModeNext []string
+	SearchModePrev []string
+
+	// SortNext switches to the next sort mode; see WithTuiSortModes
+	SortNext []string
+
+	// Rename keys
+	RenameStart  []string
+	RenameAccept []string
+	RenameCancel []string
+
+	// Delete keys; see WithTuiDelete
+	Delete        []string
+	DeleteConfirm []string
+	DeleteCancel  []string
+
+	// Hoist keys show the focused subtree as the whole tree and step back
+	// out one level at a time
+	Hoist   []string
+	Unhoist []string
+
+	// Breadcrumb keys pick an ancestor from the header of a hoisted tree to
+	// hoist instead
+	BreadcrumbStart  []string
+	BreadcrumbPrev   []string
+	BreadcrumbNext   []string
+	BreadcrumbAccept []string
+	BreadcrumbCancel []string
 }
 
 // DefaultKeyMap returns a map of basic key bindings.
@@ -82,15 +215,60 @@ func DefaultKeyMap() KeyMap {
 		Toggle: []string{"right", "left"},
 		Reset:  []string{"ctrl+r"},
 
+		// Activation
+		Activate: []string{"enter"},
+
 		// Multi-focus
 		ExtendUp:   []string{"shift+up"},
 		ExtendDown: []string{"shift+down"},
 
+		// Selection
+		SelectToggle: []string{" "},
+		SelectAll:    []string{"a"},
+
+		// Structure editing
+		MoveUp:   []string{"alt+up"},
+		MoveDown: []string{"alt+down"},
+		Indent:   []string{"tab"},
+		Outdent:  []string{"shift+tab"},
+
+		// History
+		Undo: []string{"ctrl+z"},
+		Redo: []string{"ctrl+y"},
+
 		// Search
-		SearchStart:  []string{"enter"},
+		SearchStart:  []string{"/"},
 		SearchAccept: []string{"enter"},
 		SearchCancel: []string{"esc"},
 		SearchDelete: []string{"backspace", "delete"},
+		NextMatch:    []string{"n"},
+		PrevMatch:    []string{"N"},
+
+		// Sorting
+		SortNext: []string{"s"},
+
+		// Search modes
+		SearchModeNext: []string{"tab"},
+		SearchModePrev: []string{"shift+tab"},
+
+		// Rename
+		RenameStart:  []string{"f2"},
+		RenameAccept: []string{"enter"},
+		RenameCancel: []string{"esc"},
+
+		// Delete
+		Delete:        []string{"d"},
+		DeleteConfirm: []string{"y"},
+		DeleteCancel:  []string{"n", "esc"},
+
+		// Hoisting
+		Hoist:            []string{"z"},
+		Unhoist:          []string{"Z"},
+		BreadcrumbStart:  []string{"b"},
+		BreadcrumbPrev:   []string{"left"},
+		BreadcrumbNext:   []string{"right"},
+		BreadcrumbAccept: []string{"enter"},
+		BreadcrumbCancel: []string{"esc"},
 	}
 }
 
@@ -108,19 +286,75 @@ type TuiTreeModel[T any] struct {
 	allowResize bool
 	viewport    *viewport.Model
 
-	searchTerm string
-	showSearch bool
+	searchTerm     string
+	searchedTerm   string // Term of the last evaluated search
+	searchMatches  []*Node[T]
+	matchIndex     int // Position in searchMatches of the current match
+	searchErr      error
+	showSearch     bool
+	searchMode     SearchMode
+	searchModes    []SearchMode
+	queryParser    *QueryParser[T]
+	searchDebounce time.Duration
+	searchSeq      uint64 // Identifies the latest pending debounced search
+	searchPending  bool   // A debounced search has not been evaluated yet
+	searchLimit    time.Duration
+
+	// Background continuation of a search that ran out of time
+	scan          *SearchScan[T]
+	scanSeq       uint64 // Identifies the current scan
+	scanStarted   time.Time
+	searching     bool
+	searchPartial bool
+
+	// Sort modes cycled by KeyMap.SortNext; sortIndex is -1 until one is used
+	sortModes []SortMode[T]
+	sortIndex int
+
+	// Filter mode; preSearch* hold the view to restore on cancel
+	filterMode       bool
+	preSearch        bool
+	preSearchExpand  map[*Node[T]]bool
+	preSearchFocused []*Node[T]
 
 	navigationTimeout time.Duration
 	searchTimeout     time.Duration
 
 	disableNavBar bool
+
+	// Inline rename state
+	renaming       bool
+	renameNode     *Node[T]
+	renameInput    textinput.Model
+	renameErr      error
+	renameValidate RenameValidateFn[T]
+	renameCommit   RenameCommitFn[T]
+
+	// Delete confirmation state; see delete.go
+	deleteFn        DeleteFn[T]
+	confirmDelete   []*Node[T]
+	deleteSelection bool
+	deleteCancel    context.CancelFunc // Stops the running delete
+
+	// Breadcrumb picker state; crumb indexes Breadcrumbs; see hoist.go
+	pickingCrumb bool
+	crumb        int
+
+	// watchCmd waits for the next FileSystemChangedMsg; see WithTuiWatcher
+	watchCmd tea.Cmd
+
+	// expansionFlags holds the expanded flag of every node with children as
+	// of expansionVersion, for NodeExpandedMsg and NodeCollapsedMsg; see
+	// messages.go
+	expansionFlags   map[*Node[T]]bool
+	expansionVersion uint64
 }
 
 // NewTuiTreeModel creates an interactive Bubble Tea TUI model using functional options.
 // The zero-value configuration applies sensible defaults:
 //   - 100ms navigation timeout
 //   - 300ms search timeout
+//   - 100ms search debounce
 //   - DefaultKeyMap for key bindings
 //   - DefaultNodeProvider if none specified
 //
@@ -144,11 +378,14 @@ func NewTuiTreeModel[T any](tree *Tree[T], opts ...TuiTreeModelOption[T]) *TuiTr
 		allowResize: true,
 		viewport:    &vp,
 
-		searchTerm: "",
-		showSearch: false,
+		searchTerm:  "",
+		showSearch:  false,
+		searchModes: defaultSearchModes,
+		sortIndex:   -1,
 
 		searchTimeout:     300 * time.Millisecond,
 		navigationTimeout: 100 * time.Millisecond,
+		searchDebounce:    defaultSearchDebounce,
 
 		disableNavBar: false,
 	}
@@ -173,17 +410,58 @@ func (m *TuiTreeModel[T]) execWithNavigationTimeout(fn func(context.Context) err
 }
 
 // Init initializes the TUI model. Required by the Bubble Tea model interface.
+// With WithTuiWatcher it starts listening for file system changes.
 func (m *TuiTreeModel[T]) Init() tea.Cmd {
-	return nil
+	return m.watchCmd
 }
 
 // Update processes Bubble Tea messages and returns updated model and commands.
+// Key presses that change focus, expansion, multi-focus or the search term
+// return a command producing the matching change message (FocusChangedMsg,
+// NodeExpandedMsg, NodeCollapsedMsg, SelectionChangedMsg, SearchChangedMsg)
+// so parent models can react to them.
 func (m *TuiTreeModel[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Handle different message types from Bubble Tea
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		// Process keyboard input
-		return m.handleKeypress(msg)
+		// Process keyboard input and report what it changed
+		before := m.snapshot()
+		model, cmd := m.handleKeypress(msg)
+		return model, tea.Batch(cmd, m.changeCmds(before))
+
+	case searchDebounceMsg:
+		// Only the last key press of a burst triggers a search
+		if msg.seq != m.searchSeq || !m.showSearch {
+			return m, nil
+		}
+		m.searchPending = false
+		before := m.snapshot()
+		_, _ = m.Search(m.searchTerm)
+		return m, tea.Batch(m.continueScan(), m.changeCmds(before))
+
+	case searchScanMsg:
+		// Background steps find more matches, and may move focus and expand
+		before := m.snapshot()
+		cmd := m.handleScanMsg(msg)
+		return m, tea.Batch(cmd, m.changeCmds(before))
+
+	case nodesDeletedMsg[T]:
+		// Deleting drops nodes from the focus and may clear the selection
+		before := m.snapshot()
+		cmd := m.handleDeletedMsg(msg)
+		return m, tea.Batch(cmd, m.changeCmds(before))
+
+	case FileSystemChangedMsg:
+		// Removed files may take the focus with them
+		before := m.snapshot()
+		cmd := m.handleFileSystemChanged()
+		return m, tea.Batch(cmd, m.changeCmds(before))
+
+	case tea.MouseMsg:
+		// Clicks on the breadcrumb header
+		before := m.snapshot()
+		m.handleMouse(msg)
+		return m, m.changeCmds(before)
 
 	case tea.WindowSizeMsg:
 		// If resize is not allowed, do nothing
@@ -201,6 +479,12 @@ func (m *TuiTreeModel[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	default:
+		// Keep the rename input alive (cursor blinking etc.)
+		if m.renaming {
+			var cmd tea.Cmd
+			m.renameInput, cmd = m.renameInput.Update(msg)
+			return m, cmd
+		}
 		// Ignore other message types
 		return m, nil
 	}
@@ -209,13 +493,52 @@ func (m *TuiTreeModel[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *TuiTreeModel[T]) handleKeypress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
 
+	// In rename mode: every key goes to the input except accept and cancel
+	if m.renaming {
+		switch {
+		case slices.Contains(m.keyMap.RenameAccept, key):
+			return m, m.CommitRename()
+		case slices.Contains(m.keyMap.RenameCancel, key):
+			m.CancelRename()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.renameInput, cmd = m.renameInput.Update(msg)
+		m.validateRename()
+		return m, cmd
+	}
+
+	// While a delete runs, the cancel keys stop it
+	if m.DeleteRunning() && slices.Contains(m.keyMap.DeleteCancel, key) {
+		m.CancelDelete()
+		return m, nil
+	}
+
+	// While confirming a delete: only confirm and cancel apply
+	if m.Deleting() {
+		switch {
+		case slices.Contains(m.keyMap.DeleteConfirm, key):
+			return m, m.ConfirmDelete()
+		case slices.Contains(m.keyMap.DeleteCancel, key):
+			m.CancelDelete()
+		}
+		return m, nil
+	}
+
+	// While picking a breadcrumb: only the breadcrumb keys apply
+	if m.pickingCrumb {
+		m.handleBreadcrumbKey(key)
+		return m, nil
+	}
+
 	// In search mode: prioritize search keys
 	if m.showSearch {
 		switch {
 		case slices.Contains(m.keyMap.SearchAccept, key):
+			cmd := m.flushSearch()
 			m.showSearch = false
 			m.updateViewportDimensions()
-			return m, nil
+			return m, cmd
 
 		case slices.Contains(m.keyMap.SearchCancel, key):
 			m.EndSearch()
@@ -224,10 +547,18 @@ func (m *TuiTreeModel[T]) handleKeypress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case slices.Contains(m.keyMap.SearchDelete, key):
 			if len(m.searchTerm) > 0 {
 				m.searchTerm = m.searchTerm[:len(m.searchTerm)-1]
-				m.Search(m.searchTerm)
+				return m, m.scheduleSearch()
 			}
 			return m, nil
 
+		case slices.Contains(m.keyMap.SearchModeNext, key):
+			m.CycleSearchMode(1)
+			return m, m.continueScan()
+
+		case slices.Contains(m.keyMap.SearchModePrev, key):
+			m.CycleSearchMode(-1)
+			return m, m.continueScan()
+
 		case slices.Contains(m.keyMap.Reset, key):
 			m.EndSearch()
 			return m, nil
@@ -236,8 +567,7 @@ func (m *TuiTreeModel[T]) handleKeypress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Add printable characters to search
 		if len(key) == 1 && key >= " " && key <= "~" {
 			m.searchTerm += key
-			m.Search(m.searchTerm)
-			return m, nil
+			return m, m.scheduleSearch()
 		}
 	}
 
@@ -266,10 +596,63 @@ func (m *TuiTreeModel[T]) handleKeypress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case slices.Contains(m.keyMap.Toggle, key):
 		m.Toggle()
 		return m, nil
+	case slices.Contains(m.keyMap.SelectToggle, key):
+		m.ToggleSelection()
+		return m, nil
+	case slices.Contains(m.keyMap.SelectAll, key):
+		m.ToggleSelectAll()
+		return m, nil
+	case slices.Contains(m.keyMap.Undo, key):
+		m.UndoChange()
+		return m, nil
+	case slices.Contains(m.keyMap.Redo, key):
+		m.RedoChange()
+		return m, nil
+	case slices.Contains(m.keyMap.MoveUp, key):
+		return m, m.MoveFocusedUp()
+	case slices.Contains(m.keyMap.MoveDown, key):
+		return m, m.MoveFocusedDown()
+	case slices.Contains(m.keyMap.Indent, key):
+		return m, m.IndentFocused()
+	case slices.Contains(m.keyMap.Outdent, key):
+		return m, m.OutdentFocused()
+	case slices.Contains(m.keyMap.RenameStart, key):
+		return m, m.BeginRename()
 	case slices.Contains(m.keyMap.SearchStart, key):
+		// Checked before Activate, so binding SearchStart to enter again
+		// restores the old behaviour without unbinding Activate
 		m.BeginSearch()
 		return m, nil
+	case slices.Contains(m.keyMap.Activate, key):
+		if id := m.GetFocusedID(); id != "" {
+			return m, msgCmd(NodeActivatedMsg{ID: id})
+		}
+		return m, nil
+	case slices.Contains(m.keyMap.SortNext, key):
+		m.CycleSort()
+		return m, nil
+	case slices.Contains(m.keyMap.Delete, key):
+		m.BeginDelete()
+		return m, nil
+	case slices.Contains(m.keyMap.NextMatch, key):
+		m.NextMatch()
+		return m, nil
+	case slices.Contains(m.keyMap.PrevMatch, key):
+		m.PrevMatch()
+		return m, nil
+	case slices.Contains(m.keyMap.Hoist, key):
+		m.HoistFocused()
+		return m, nil
+	case slices.Contains(m.keyMap.Unhoist, key):
+		m.Unhoist()
+		return m, nil
+	case slices.Contains(m.keyMap.BreadcrumbStart, key):
+		m.BeginBreadcrumbs()
+		return m, nil
 	case slices.Contains(m.keyMap.Reset, key):
+		m.stopScan()
+		m.ClearFilter()
+		m.preSearch = false
 		m.ShowAll(context.Background())
 		return m, nil
 	}
@@ -312,79 +695,418 @@ func (m *TuiTreeModel[T]) ExtendFocusDown() {
 // Toggle expands or collapses all currently focused nodes.
 func (m *TuiTreeModel[T]) Toggle() {
 	m.execWithNavigationTimeout(func(ctx context.Context) error {
-		for info, err := range m.AllFocused(ctx) {
-			if err != nil {
-				return err
-			}
-			info.Node.Toggle()
-		}
+		m.ToggleFocused(ctx)
 		return nil
 	})
 }
 
 // Expand expands all currently focused nodes to show their children.
 func (m *TuiTreeModel[T]) Expand() {
+	m.setFocusedExpanded(true)
+}
+
+// Collapse collapses all currently focused nodes to hide their children.
+func (m *TuiTreeModel[T]) Collapse() {
+	m.setFocusedExpanded(false)
+}
+
+// setFocusedExpanded expands or collapses every focused node as one undo step.
+func (m *TuiTreeModel[T]) setFocusedExpanded(expanded bool) {
 	m.execWithNavigationTimeout(func(ctx context.Context) error {
-		for info, err := range m.AllFocused(ctx) {
-			if err != nil {
+		return m.Group(func() error {
+			for _, id := range m.GetAllFocusedIDs() {
+				if _, err := m.SetExpanded(ctx, id, expanded); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// UndoChange reverts the most recent change recorded in the tree's history.
+func (m *TuiTreeModel[T]) UndoChange() {
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		_, err := m.Undo(ctx)
+		return err
+	})
+}
+
+// RedoChange re-applies the most recently undone change.
+func (m *TuiTreeModel[T]) RedoChange() {
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		_, err := m.Redo(ctx)
+		return err
+	})
+}
+
+// ToggleSelection toggles the checkbox selection of every focused node and
+// its subtree.
+func (m *TuiTreeModel[T]) ToggleSelection() {
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		for _, id := range m.GetAllFocusedIDs() {
+			if err := m.ToggleSelected(ctx, id); err != nil {
 				return err
 			}
-			info.Node.Expand()
 		}
 		return nil
 	})
 }
 
-// Collapse collapses all currently focused nodes to hide their children.
-func (m *TuiTreeModel[T]) Collapse() {
+// ToggleSelectAll selects every node, or clears the selection if every root
+// is already fully checked.
+func (m *TuiTreeModel[T]) ToggleSelectAll() {
+	allChecked := true
+	for _, root := range m.Nodes() {
+		if m.CheckState(root.ID()) != Checked {
+			allChecked = false
+			break
+		}
+	}
+	if allChecked {
+		m.ClearSelection()
+		return
+	}
 	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		return m.SelectAll(ctx)
+	})
+}
+
+// MoveFocusedUp moves each focused node one position up among its siblings.
+// The returned command emits a NodeMovedMsg if anything moved.
+func (m *TuiTreeModel[T]) MoveFocusedUp() tea.Cmd {
+	return m.moveFocused(false, func(ctx context.Context, node *Node[T]) (bool, error) {
+		siblings := m.siblings(node)
+		i := slices.Index(siblings, node)
+		if i <= 0 || m.IsFocused(siblings[i-1].ID()) {
+			return false, nil // Already first, or blocked by another moving node
+		}
+		return true, m.MoveNode(ctx, node.ID(), parentIDOf(node), i-1)
+	})
+}
+
+// MoveFocusedDown moves each focused node one position down among its
+// siblings. The returned command emits a NodeMovedMsg if anything moved.
+func (m *TuiTreeModel[T]) MoveFocusedDown() tea.Cmd {
+	return m.moveFocused(true, func(ctx context.Context, node *Node[T]) (bool, error) {
+		siblings := m.siblings(node)
+		i := slices.Index(siblings, node)
+		if i < 0 || i >= len(siblings)-1 || m.IsFocused(siblings[i+1].ID()) {
+			return false, nil // Already last, or blocked by another moving node
+		}
+		return true, m.MoveNode(ctx, node.ID(), parentIDOf(node), i+1)
+	})
+}
+
+// IndentFocused makes each focused node the last child of its previous
+// sibling, like indenting a line in an outliner. The new parent is expanded
+// so the node stays visible.
+func (m *TuiTreeModel[T]) IndentFocused() tea.Cmd {
+	return m.moveFocused(false, func(ctx context.Context, node *Node[T]) (bool, error) {
+		siblings := m.siblings(node)
+		i := slices.Index(siblings, node)
+		if i <= 0 || m.IsFocused(siblings[i-1].ID()) {
+			return false, nil // No previous sibling to indent under
+		}
+		return true, m.MoveNode(ctx, node.ID(), siblings[i-1].ID(), -1)
+	})
+}
+
+// OutdentFocused makes each focused node the sibling directly after its
+// parent, like outdenting a line in an outliner.
+func (m *TuiTreeModel[T]) OutdentFocused() tea.Cmd {
+	return m.moveFocused(true, func(ctx context.Context, node *Node[T]) (bool, error) {
+		parent := node.Parent()
+		if parent == nil {
+			return false, nil // Already at the root level
+		}
+		i := slices.Index(m.siblings(parent), parent)
+		return true, m.MoveNode(ctx, node.ID(), parentIDOf(parent), i+1)
+	})
+}
+
+// moveFocused applies move to every focused node in tree order, or reverse
+// tree order when bottomUp is set so neighbouring nodes move as a block. It
+// expands the ancestors of the moved nodes so they stay visible and focused.
+// All moves are recorded as a single undo step.
+func (m *TuiTreeModel[T]) moveFocused(bottomUp bool, move func(context.Context, *Node[T]) (bool, error)) tea.Cmd {
+	var moved []string
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		var focused []*Node[T]
 		for info, err := range m.AllFocused(ctx) {
 			if err != nil {
 				return err
 			}
-			info.Node.Collapse()
+			focused = append(focused, info.Node)
 		}
+		if bottomUp {
+			slices.Reverse(focused)
+		}
+
+		return m.Group(func() error {
+			for _, node := range focused {
+				ok, err := move(ctx, node)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+				moved = append(moved, node.ID())
+				for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+					if _, err := m.SetExpanded(ctx, ancestor.ID(), true); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	})
+
+	if len(moved) == 0 {
+		return nil
+	}
+	return msgCmd(NodeMovedMsg{IDs: moved})
+}
+
+// parentIDOf returns the ID of node's parent, or "" for root nodes.
+func parentIDOf[T any](node *Node[T]) string {
+	if parent := node.Parent(); parent != nil {
+		return parent.ID()
+	}
+	return ""
+}
+
+// BeginRename opens an input over the focused row prefilled with the node's
+// name. It returns the command that starts the cursor blinking, or nil if no
+// node is focused.
+func (m *TuiTreeModel[T]) BeginRename() tea.Cmd {
+	node := m.GetFocusedNode()
+	if node == nil {
 		return nil
+	}
+
+	input := textinput.New()
+	input.Prompt = ""
+	input.SetValue(node.Name())
+	input.CursorEnd()
+
+	m.renaming = true
+	m.renameNode = node
+	m.renameInput = input
+	m.renameErr = nil
+	return m.renameInput.Focus()
+}
+
+// CancelRename closes the rename input without changing the node.
+func (m *TuiTreeModel[T]) CancelRename() {
+	m.renaming = false
+	m.renameNode = nil
+	m.renameErr = nil
+}
+
+// CommitRename validates the edited name, passes it through the commit
+// callback and applies it to the node. On success the editor closes and the
+// returned command emits a NodeRenamedMsg; if validation or the callback
+// fails, the error is shown inline and the editor stays open.
+func (m *TuiTreeModel[T]) CommitRename() tea.Cmd {
+	if !m.renaming {
+		return nil
+	}
+	node := m.renameNode
+	name := m.renameInput.Value()
+
+	if m.validateRename(); m.renameErr != nil {
+		return nil
+	}
+	if m.renameCommit != nil {
+		var err error
+		if name, err = m.renameCommit(node, name); err != nil {
+			m.renameErr = err
+			return nil
+		}
+	}
+
+	oldName := node.Name()
+	var err error
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		err = m.Rename(ctx, node.ID(), name)
+		return err
 	})
+	if err != nil {
+		m.renameErr = err
+		return nil
+	}
+
+	m.CancelRename()
+	return msgCmd(NodeRenamedMsg{ID: node.ID(), OldName: oldName, NewName: node.Name()})
+}
+
+// validateRename runs the validation hook against the current input.
+func (m *TuiTreeModel[T]) validateRename() {
+	m.renameErr = nil
+	if m.renameValidate != nil {
+		m.renameErr = m.renameValidate(m.renameNode, m.renameInput.Value())
+	}
+}
+
+// renameLine renders the rename input in place of the node being edited.
+func (m *TuiTreeModel[T]) renameLine(node *Node[T], prefix string) (string, bool) {
+	if !m.renaming || node != m.renameNode {
+		return "", false
+	}
+	line := prefix + NormalizeIconWidth(m.provider.Icon(node)) + m.renameInput.View()
+	if m.renameErr != nil {
+		line += "  ⚠ " + m.renameErr.Error()
+	}
+	return line, true
 }
 
 // BeginSearch switches the model into search mode and clears previous term.
 func (m *TuiTreeModel[T]) BeginSearch() {
 	m.showSearch = true
 	m.searchTerm = ""
+	m.searchedTerm = ""
+	m.searchMatches = nil
+	m.searchErr = nil
+	if m.filterMode && !m.preSearch {
+		m.capturePreSearch()
+	}
 
 	m.updateViewportDimensions()
 }
 
+// capturePreSearch records the expansion and focus that cancelling a filter
+// search restores.
+func (m *TuiTreeModel[T]) capturePreSearch() {
+	m.execWithNavigationTimeout(func(ctx context.Context) error {
+		expanded, err := m.expansionState(ctx)
+		if err != nil {
+			return err
+		}
+		m.preSearch = true
+		m.preSearchExpand = expanded
+		m.preSearchFocused = m.GetAllFocusedNodes()
+		return nil
+	})
+}
+
 // EndSearch exits search mode and clears highlights.
 func (m *TuiTreeModel[T]) EndSearch() {
 	m.showSearch = false
 	m.searchTerm = ""
+	m.searchedTerm = ""
+	m.searchMatches = nil
+	m.searchErr = nil
+	m.stopScan()
+	m.ClearSearch()
 	m.updateViewportDimensions()
 
+	if m.filterMode {
+		m.ClearFilter()
+		if m.preSearch {
+			expanded, focused := m.preSearchExpand, m.preSearchFocused
+			m.preSearch, m.preSearchExpand, m.preSearchFocused = false, nil, nil
+			m.execWithNavigationTimeout(func(ctx context.Context) error {
+				return m.restoreView(ctx, expanded, focused)
+			})
+		}
+		return
+	}
+
 	m.execWithNavigationTimeout(func(ctx context.Context) error {
 		return m.ShowAll(ctx)
 	})
 }
 
 // Search updates the term live as the user types and expands the tree so that
-// matches are visible. It returns the result slice so external code can, for
-// instance, display the hit count.
+// matches are visible, or filters it in filter mode (see WithTuiFilterMode).
+// It returns the result slice so external code can, for instance, display the
+// hit count. The term is interpreted according to the active SearchMode; a
+// term the mode can't interpret, such as an invalid regular expression or
+// query, keeps the previous results and returns the error, which is also
+// shown next to the prompt.
+//
+// If the search timeout passes before the whole tree was searched, the
+// matches found so far are shown and the context error is returned. The
+// search then continues in the background when driven through Update; see
+// Searching and SearchPartial.
 func (m *TuiTreeModel[T]) Search(term string) ([]*Node[T], error) {
 	// Update the current search term
 	m.searchTerm = term
+	m.searchedTerm = term
+	m.searchErr = nil
+	m.stopScan()
 
 	ctx, cancel := context.WithTimeout(context.Background(), m.searchTimeout)
 	defer cancel()
 
-	matches, err := m.SearchAndExpand(ctx, term)
-	return matches, err
+	var match MatchFn[T]
+	if term != "" {
+		var err error
+		if match, err = m.searchMatcher(term); err != nil {
+			// Partially typed patterns are common; keep the last good results
+			m.searchErr = err
+			return m.searchMatches, err
+		}
+	}
+
+	scan, err := m.StartSearch(ctx, term, match)
+	if err != nil {
+		return m.searchMatches, err
+	}
+	err = scan.Continue(ctx)
+	if err != nil {
+		// Out of time: show what we have and keep scanning in the background
+		m.scan = scan
+		m.searching = true
+		m.scanStarted = time.Now()
+	}
+	m.searchMatches = nil
+	m.applySearchResults(term, scan.Results())
+	return m.searchMatches, err
+}
+
+// defaultSearchDebounce is the typing pause after which the search is
+// evaluated unless WithTuiSearchDebounce says otherwise.
+const defaultSearchDebounce = 100 * time.Millisecond
+
+// searchDebounceMsg triggers a delayed search; see WithTuiSearchDebounce.
+type searchDebounceMsg struct {
+	seq uint64
+}
+
+// scheduleSearch evaluates the current term now, or after the debounce delay
+// if one is configured. Every call supersedes the previously scheduled one.
+func (m *TuiTreeModel[T]) scheduleSearch() tea.Cmd {
+	if m.searchDebounce <= 0 {
+		_, _ = m.Search(m.searchTerm)
+		return m.continueScan()
+	}
+	m.searchSeq++
+	m.searchPending = true
+	seq := m.searchSeq
+	return tea.Tick(m.searchDebounce, func(time.Time) tea.Msg {
+		return searchDebounceMsg{seq: seq}
+	})
+}
+
+// flushSearch cancels a pending debounced search and evaluates the current
+// term right away, so a term accepted within the delay is not lost.
+func (m *TuiTreeModel[T]) flushSearch() tea.Cmd {
+	if !m.searchPending {
+		return nil
+	}
+	m.searchSeq++
+	m.searchPending = false
+	_, _ = m.Search(m.searchTerm)
+	return m.continueScan()
 }
 
 // View renders the tree plus an optional search bar and navigation legend.
 func (m *TuiTreeModel[T]) View() string {
 	// Render the tree
-	result, err := renderTreeWithViewport(context.Background(), m.Tree, m.viewport)
+	result, err := renderTreeWithViewportOverride(context.Background(), m.Tree, m.viewport, m.renameLine)
 	if err != nil {
 		return "Error rendering tree: " + err.Error()
 	}
@@ -392,8 +1114,36 @@ func (m *TuiTreeModel[T]) View() string {
 	// Add search UI at the top if in search mode
 	if m.showSearch {
 		searchUI := "Search: " + m.searchTerm
+		if m.searchMode == SearchQuery {
+			searchUI = "Query: " + m.searchTerm
+		}
+		if len(m.searchModes) > 1 {
+			searchUI += "  [" + m.searchMode.String() + "]"
+		}
+		if current, total := m.MatchPosition(); total > 0 {
+			searchUI += fmt.Sprintf("  %d/%d", current, total)
+		} else if m.searchedTerm != "" && m.searchErr == nil && !m.searching {
+			searchUI += "  no matches"
+		}
+		switch {
+		case m.searching:
+			searchUI += "  searching…"
+		case m.searchPartial:
+			searchUI += "  partial results"
+		}
+		if m.searchErr != nil {
+			searchUI += "  ⚠ " + m.searchErr.Error()
+		}
 		result = searchUI + "\n\n" + result
 	}
+	if m.Deleting() {
+		result = m.deletePrompt() + "\n\n" + result
+	}
+
+	// The breadcrumb header goes first so clicks on it are on line 0
+	if m.HoistedNode() != nil {
+		result = m.breadcrumbHeader() + "\n\n" + result
+	}
 
 	// Add navigation bar if not disabled
 	if !m.disableNavBar {
@@ -419,19 +1169,72 @@ func (m *TuiTreeModel[T]) NavBar() string {
 		m.addNavItem(m.keyMap.Expand, "Expand"),
 		m.addNavItem(m.keyMap.Collapse, "Collapse"),
 		m.addNavItem(m.keyMap.Toggle, "Toggle"),
+		m.addNavItem(m.keyMap.Activate, "Activate"),
 	} {
 		if item != "" {
 			navItems = append(navItems, item)
 		}
 	}
 
+	if m.Deleting() {
+		// Confirming a delete: only confirm and cancel apply
+		return strings.Join([]string{
+			m.addNavItem(m.keyMap.DeleteConfirm, "Delete"),
+			m.addNavItem(m.keyMap.DeleteCancel, "Cancel"),
+		}, "  ")
+	}
+
+	if m.pickingCrumb {
+		// Picking a breadcrumb: only the picker keys apply
+		return strings.Join([]string{
+			m.addNavItem(append(slices.Clone(m.keyMap.BreadcrumbPrev), m.keyMap.BreadcrumbNext...), "Choose"),
+			m.addNavItem(m.keyMap.BreadcrumbAccept, "Hoist"),
+			m.addNavItem(m.keyMap.BreadcrumbCancel, "Cancel"),
+		}, "  ")
+	}
+
+	if m.renaming {
+		// In rename mode: only accept and cancel apply
+		return strings.Join([]string{
+			m.addNavItem(m.keyMap.RenameAccept, "Rename"),
+			m.addNavItem(m.keyMap.RenameCancel, "Cancel"),
+		}, "  ")
+	}
+
 	if m.showSearch {
 		// In search mode: show search-specific actions
 		navItems = append(navItems, m.addNavItem(m.keyMap.SearchAccept, "Accept"))
 		navItems = append(navItems, m.addNavItem(m.keyMap.SearchCancel, "Cancel"))
+		if len(m.searchModes) > 1 {
+			navItems = append(navItems, m.addNavItem(m.keyMap.SearchModeNext, "Mode"))
+		}
 	} else {
-		// In normal mode, add Search Option
+		// In normal mode, add Search and Rename Options
 		navItems = append(navItems, m.addNavItem(m.keyMap.SearchStart, "Search"))
+		if current, total := m.MatchPosition(); total > 0 {
+			keys := append(slices.Clone(m.keyMap.NextMatch), m.keyMap.PrevMatch...)
+			navItems = append(navItems, m.addNavItem(keys, fmt.Sprintf("Match %d/%d", current, total)))
+		}
+		if item := m.addNavItem(m.keyMap.RenameStart, "Rename"); item != "" {
+			navItems = append(navItems, item)
+		}
+		if len(m.sortModes) > 0 {
+			label := "Sort"
+			if name := m.SortModeName(); name != "" {
+				label += " (" + name + ")"
+			}
+			navItems = append(navItems, m.addNavItem(m.keyMap.SortNext, label))
+		}
+		if m.deleteFn != nil {
+			navItems = append(navItems, m.addNavItem(m.keyMap.Delete, "Delete"))
+		}
+		if item := m.addNavItem(m.keyMap.Hoist, "Hoist"); item != "" {
+			navItems = append(navItems, item)
+		}
+		if m.HoistedNode() != nil {
+			navItems = append(navItems, m.addNavItem(m.keyMap.Unhoist, "Unhoist"))
+			navItems = append(navItems, m.addNavItem(m.keyMap.BreadcrumbStart, "Breadcrumbs"))
+		}
 		// Add quit Option
 		navItems = append(navItems, m.addNavItem(m.keyMap.Quit, "Quit"))
 	}
@@ -458,6 +1261,12 @@ func (m *TuiTreeModel[T]) updateViewportDimensions() {
 	if m.showSearch {
 		viewHeight -= 2
 	}
+	if m.Deleting() {
+		viewHeight -= 2
+	}
+	if m.HoistedNode() != nil {
+		viewHeight -= 2
+	}
 
 	m.viewport.Width = m.width
 	m.viewport.Height = viewHeight
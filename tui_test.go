@@ -2,6 +2,7 @@ package treeview
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -226,17 +227,45 @@ func TestWithTuiKeyMap(t *testing.T) {
 
 func TestDefaultKeyMap(t *testing.T) {
 	want := KeyMap{
-		Quit:         []string{"esc"},
-		Up:           []string{"up"},
-		Down:         []string{"down"},
-		Toggle:       []string{"right", "left"},
-		Reset:        []string{"ctrl+r"},
-		ExtendUp:     []string{"shift+up"},
-		ExtendDown:   []string{"shift+down"},
-		SearchStart:  []string{"enter"},
-		SearchAccept: []string{"enter"},
-		SearchCancel: []string{"esc"},
-		SearchDelete: []string{"backspace", "delete"},
+		Quit:           []string{"esc"},
+		Up:             []string{"up"},
+		Down:           []string{"down"},
+		Toggle:         []string{"right", "left"},
+		Reset:          []string{"ctrl+r"},
+		Activate:       []string{"enter"},
+		ExtendUp:       []string{"shift+up"},
+		ExtendDown:     []string{"shift+down"},
+		SelectToggle:   []string{" "},
+		SelectAll:      []string{"a"},
+		MoveUp:         []string{"alt+up"},
+		MoveDown:       []string{"alt+down"},
+		Indent:         []string{"tab"},
+		Outdent:        []string{"shift+tab"},
+		Undo:           []string{"ctrl+z"},
+		Redo:           []string{"ctrl+y"},
+		SearchStart:    []string{"/"},
+		SearchAccept:   []string{"enter"},
+		SearchCancel:   []string{"esc"},
+		SearchDelete:   []string{"backspace", "delete"},
+		NextMatch:      []string{"n"},
+		PrevMatch:      []string{"N"},
+		SearchModeNext: []string{"tab"},
+		SearchModePrev: []string{"shift+tab"},
+		SortNext:       []string{"s"},
+		RenameStart:    []string{"f2"},
+		RenameAccept:   []string{"enter"},
+		RenameCancel:   []string{"esc"},
+		Delete:         []string{"d"},
+		DeleteConfirm:  []string{"y"},
+		DeleteCancel:   []string{"n", "esc"},
+
+		Hoist:            []string{"z"},
+		Unhoist:          []string{"Z"},
+		BreadcrumbStart:  []string{"b"},
+		BreadcrumbPrev:   []string{"left"},
+		BreadcrumbNext:   []string{"right"},
+		BreadcrumbAccept: []string{"enter"},
+		BreadcrumbCancel: []string{"esc"},
 	}
 
 	got := DefaultKeyMap()
@@ -478,7 +507,7 @@ func TestNavBar(t *testing.T) {
 		{
 			name:            "normal_mode",
 			showSearch:      false,
-			wantContains:    []string{"up: Up", "down: Down", "enter: Search", "esc: Quit", "ctrl+r: Reset"},
+			wantContains:    []string{"up: Up", "down: Down", "enter: Activate", "/: Search", "esc: Quit", "ctrl+r: Reset"},
 			wantNotContains: []string{"Accept", "Cancel"},
 		},
 		{
@@ -549,8 +578,13 @@ func TestHandleKeypress_Navigation(t *testing.T) {
 			wantCmd: nil,
 		},
 		{
-			name:    "search_start_key",
+			name:    "activate_key",
 			key:     "enter",
+			wantCmd: func() tea.Msg { return NodeActivatedMsg{ID: "root"} },
+		},
+		{
+			name:    "search_start_key",
+			key:     "/",
 			wantCmd: nil,
 		},
 		{
@@ -601,6 +635,22 @@ func TestHandleKeypress_Navigation(t *testing.T) {
 	}
 }
 
+func TestHandleKeypress_SearchStartOnEnter(t *testing.T) {
+	// The pre-Activate binding: SearchStart back on enter, Activate left as is
+	keyMap := DefaultKeyMap()
+	keyMap.SearchStart = []string{"enter"}
+	tree := NewTree([]*Node[string]{NewNode("root", "root", "root")})
+	model := NewTuiTreeModel(tree, WithTuiKeyMap[string](keyMap))
+
+	_, cmd := model.handleKeypress(tea.KeyMsg{Type: tea.KeyEnter})
+	if !model.showSearch {
+		t.Error("handleKeypress(enter) did not start a search")
+	}
+	if cmd != nil {
+		t.Errorf("handleKeypress(enter) cmd = %v, want nil", cmd)
+	}
+}
+
 func TestHandleKeypress_SearchMode(t *testing.T) {
 	nodes := []*Node[string]{NewNode("root", "root", "root")}
 	tree := NewTree(nodes)
@@ -912,3 +962,439 @@ func TestUpdate_UnknownMessage(t *testing.T) {
 		t.Errorf("Update(unknownMsg) cmd = %v, want nil", gotCmd)
 	}
 }
+
+// collectMsgs runs cmd and flattens any batched commands into their messages.
+func collectMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	batch, ok := msg.(tea.BatchMsg)
+	if !ok {
+		return []tea.Msg{msg}
+	}
+	var msgs []tea.Msg
+	for _, c := range batch {
+		msgs = append(msgs, collectMsgs(c)...)
+	}
+	return msgs
+}
+
+func TestUpdate_ChangeMessages(t *testing.T) {
+	newModel := func() *TuiTreeModel[string] {
+		parent := NewNode("parent", "parent", "parent")
+		parent.AddChild(NewNode("child", "child", "child"))
+		tree := NewTree([]*Node[string]{parent, NewNode("other", "other", "other")})
+		return NewTuiTreeModel(tree, WithTuiSearchDebounce[string](0))
+	}
+
+	tests := []struct {
+		name string
+		keys []tea.KeyMsg
+		want []tea.Msg
+	}{
+		{
+			name: "move_down",
+			keys: []tea.KeyMsg{{Type: tea.KeyDown}},
+			want: []tea.Msg{FocusChangedMsg{ID: "other", PreviousID: "parent"}},
+		},
+		{
+			name: "toggle_expand",
+			keys: []tea.KeyMsg{{Type: tea.KeyRight}},
+			want: []tea.Msg{NodeExpandedMsg{IDs: []string{"parent"}}},
+		},
+		{
+			name: "toggle_collapse",
+			keys: []tea.KeyMsg{{Type: tea.KeyRight}, {Type: tea.KeyLeft}},
+			want: []tea.Msg{NodeCollapsedMsg{IDs: []string{"parent"}}},
+		},
+		{
+			name: "extend_down",
+			keys: []tea.KeyMsg{{Type: tea.KeyShiftDown}},
+			want: []tea.Msg{
+				FocusChangedMsg{ID: "other", PreviousID: "parent", IDs: []string{"other", "parent"}},
+			},
+		},
+		{
+			name: "select_toggle",
+			keys: []tea.KeyMsg{{Type: tea.KeySpace, Runes: []rune(" ")}},
+			want: []tea.Msg{SelectionChangedMsg{IDs: []string{"parent", "child"}}},
+		},
+		{
+			name: "select_all",
+			keys: []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("a")}},
+			want: []tea.Msg{SelectionChangedMsg{IDs: []string{"parent", "child", "other"}}},
+		},
+		{
+			name: "move_keeps_selection",
+			keys: []tea.KeyMsg{{Type: tea.KeySpace, Runes: []rune(" ")}, {Type: tea.KeyDown}},
+			want: []tea.Msg{FocusChangedMsg{ID: "other", PreviousID: "parent"}},
+		},
+		{
+			name: "activate",
+			keys: []tea.KeyMsg{{Type: tea.KeyEnter}},
+			want: []tea.Msg{NodeActivatedMsg{ID: "parent"}},
+		},
+		{
+			name: "undo_expand",
+			keys: []tea.KeyMsg{{Type: tea.KeyRight}, {Type: tea.KeyCtrlZ}},
+			want: []tea.Msg{NodeCollapsedMsg{IDs: []string{"parent"}}},
+		},
+		{
+			name: "hoist_expands",
+			keys: []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("z")}},
+			want: []tea.Msg{
+				NodeExpandedMsg{IDs: []string{"parent"}},
+				HoistChangedMsg{ID: "parent", Path: []string{"parent"}},
+			},
+		},
+		{
+			name: "search_expands",
+			keys: []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("/")}, {Type: tea.KeyRunes, Runes: []rune("c")}},
+			want: []tea.Msg{
+				FocusChangedMsg{ID: "child", PreviousID: "parent"},
+				NodeExpandedMsg{IDs: []string{"parent"}},
+				SearchChangedMsg{Term: "c", MatchIDs: []string{"child"}},
+			},
+		},
+		{
+			name: "search_expands_unfocused",
+			keys: []tea.KeyMsg{
+				{Type: tea.KeyDown},
+				{Type: tea.KeyRunes, Runes: []rune("/")},
+				{Type: tea.KeyRunes, Runes: []rune("c")},
+			},
+			want: []tea.Msg{
+				FocusChangedMsg{ID: "child", PreviousID: "other"},
+				NodeExpandedMsg{IDs: []string{"parent"}},
+				SearchChangedMsg{Term: "c", MatchIDs: []string{"child"}},
+			},
+		},
+		{
+			name: "unknown_key",
+			keys: []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("x")}},
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			model := newModel()
+			var got []tea.Msg
+			for _, key := range test.keys {
+				_, cmd := model.Update(key)
+				got = collectMsgs(cmd)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Update(%v) messages mismatch (-want +got):\n%s", test.keys, diff)
+			}
+		})
+	}
+}
+
+func TestUpdate_ExpansionMessagesPerModel(t *testing.T) {
+	ctx := context.Background()
+	parent := NewNode("parent", "parent", "parent")
+	parent.AddChild(NewNode("child", "child", "child"))
+	other := NewNode("other", "other", "other")
+	other.AddChild(NewNode("other1", "other1", "other1"))
+	tree := NewTree([]*Node[string]{parent, other})
+	first, second := NewTuiTreeModel(tree), NewTuiTreeModel(tree)
+	right := tea.KeyMsg{Type: tea.KeyRight}
+
+	// Each model reports the expansions made while it handles a message
+	_, cmd := first.Update(right)
+	if diff := cmp.Diff([]tea.Msg{NodeExpandedMsg{IDs: []string{"parent"}}}, collectMsgs(cmd)); diff != "" {
+		t.Errorf("first Update(right) messages mismatch (-want +got):\n%s", diff)
+	}
+
+	// Changes made elsewhere in between are not reported as its own
+	if _, err := tree.SetExpanded(ctx, "other", true); err != nil {
+		t.Fatalf("SetExpanded() error = %v", err)
+	}
+	_, cmd = second.Update(right)
+	if diff := cmp.Diff([]tea.Msg{NodeCollapsedMsg{IDs: []string{"parent"}}}, collectMsgs(cmd)); diff != "" {
+		t.Errorf("second Update(right) messages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdate_SearchChangedMsg(t *testing.T) {
+	nodes := []*Node[string]{
+		NewNode("apple", "apple", "apple"),
+		NewNode("banana", "banana", "banana"),
+	}
+	model := NewTuiTreeModel(NewTree(nodes), WithTuiSearchDebounce[string](0))
+	model.BeginSearch()
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+
+	var got *SearchChangedMsg
+	for _, msg := range collectMsgs(cmd) {
+		if m, ok := msg.(SearchChangedMsg); ok {
+			got = &m
+		}
+	}
+	want := &SearchChangedMsg{Term: "b", MatchIDs: []string{"banana"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Update(\"b\") SearchChangedMsg mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRename(t *testing.T) {
+	typeText := func(m *TuiTreeModel[string], text string) {
+		for _, r := range text {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	clearInput := func(m *TuiTreeModel[string]) {
+		m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	}
+	errEmpty := errors.New("name must not be empty")
+
+	tests := []struct {
+		name         string
+		opts         []TuiTreeModelOption[string]
+		edit         func(m *TuiTreeModel[string])
+		finish       tea.KeyMsg
+		wantName     string
+		wantRenaming bool
+		wantMsg      tea.Msg
+	}{
+		{
+			name:     "accept",
+			edit:     func(m *TuiTreeModel[string]) { typeText(m, "2") },
+			finish:   tea.KeyMsg{Type: tea.KeyEnter},
+			wantName: "item2",
+			wantMsg:  NodeRenamedMsg{ID: "item", OldName: "item", NewName: "item2"},
+		},
+		{
+			name:     "cancel",
+			edit:     func(m *TuiTreeModel[string]) { typeText(m, "2") },
+			finish:   tea.KeyMsg{Type: tea.KeyEsc},
+			wantName: "item",
+		},
+		{
+			name: "validation_blocks_accept",
+			opts: []TuiTreeModelOption[string]{
+				WithTuiRenameValidator(func(_ *Node[string], name string) error {
+					if name == "" {
+						return errEmpty
+					}
+					return nil
+				}),
+			},
+			edit:         clearInput,
+			finish:       tea.KeyMsg{Type: tea.KeyEnter},
+			wantName:     "item",
+			wantRenaming: true,
+		},
+		{
+			name: "commit_transforms",
+			opts: []TuiTreeModelOption[string]{
+				WithTuiRenameCommit(func(_ *Node[string], name string) (string, error) {
+					return strings.ToUpper(name), nil
+				}),
+			},
+			edit:     func(m *TuiTreeModel[string]) { typeText(m, "x") },
+			finish:   tea.KeyMsg{Type: tea.KeyEnter},
+			wantName: "ITEMX",
+			wantMsg:  NodeRenamedMsg{ID: "item", OldName: "item", NewName: "ITEMX"},
+		},
+		{
+			name: "commit_vetoes",
+			opts: []TuiTreeModelOption[string]{
+				WithTuiRenameCommit(func(_ *Node[string], name string) (string, error) {
+					return "", errors.New("read-only")
+				}),
+			},
+			edit:         func(m *TuiTreeModel[string]) { typeText(m, "x") },
+			finish:       tea.KeyMsg{Type: tea.KeyEnter},
+			wantName:     "item",
+			wantRenaming: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := NewNode("item", "item", "item")
+			model := NewTuiTreeModel(NewTree([]*Node[string]{node}), test.opts...)
+
+			model.Update(tea.KeyMsg{Type: tea.KeyF2})
+			if !model.renaming {
+				t.Fatalf("Update(f2) renaming = false, want true")
+			}
+			if got := model.renameInput.Value(); got != "item" {
+				t.Errorf("rename input value = %q, want %q", got, "item")
+			}
+
+			test.edit(model)
+			_, cmd := model.Update(test.finish)
+
+			if got := node.Name(); got != test.wantName {
+				t.Errorf("node name = %q, want %q", got, test.wantName)
+			}
+			if model.renaming != test.wantRenaming {
+				t.Errorf("renaming = %v, want %v", model.renaming, test.wantRenaming)
+			}
+			if test.wantRenaming && !strings.Contains(model.View(), "⚠") {
+				t.Errorf("View() does not show the rename error")
+			}
+
+			var gotMsg tea.Msg
+			for _, msg := range collectMsgs(cmd) {
+				if renamed, ok := msg.(NodeRenamedMsg); ok {
+					gotMsg = renamed
+				}
+			}
+			if diff := cmp.Diff(test.wantMsg, gotMsg); diff != "" {
+				t.Errorf("NodeRenamedMsg mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStructureEditing(t *testing.T) {
+	newModel := func() *TuiTreeModel[string] {
+		tree := createSimpleTree(t)
+		tree.focusPol = defaultFocusPolicy[string]
+		tree.focusedIDs = map[string]bool{}
+		return NewTuiTreeModel(tree)
+	}
+
+	tests := []struct {
+		name      string
+		focus     []string
+		key       tea.KeyMsg
+		wantShape []string
+		wantMoved []string
+	}{
+		{
+			name:      "move_down",
+			focus:     []string{"1.1"},
+			key:       tea.KeyMsg{Type: tea.KeyDown, Alt: true},
+			wantShape: []string{"1(1.2,1.1(1.1.1))", "2(2.1)"},
+			wantMoved: []string{"1.1"},
+		},
+		{
+			name:      "move_up_first_is_noop",
+			focus:     []string{"1.1"},
+			key:       tea.KeyMsg{Type: tea.KeyUp, Alt: true},
+			wantShape: []string{"1(1.1(1.1.1),1.2)", "2(2.1)"},
+		},
+		{
+			name:      "indent",
+			focus:     []string{"1.2"},
+			key:       tea.KeyMsg{Type: tea.KeyTab},
+			wantShape: []string{"1(1.1(1.1.1,1.2))", "2(2.1)"},
+			wantMoved: []string{"1.2"},
+		},
+		{
+			name:      "outdent",
+			focus:     []string{"1.1.1"},
+			key:       tea.KeyMsg{Type: tea.KeyShiftTab},
+			wantShape: []string{"1(1.1,1.1.1,1.2)", "2(2.1)"},
+			wantMoved: []string{"1.1.1"},
+		},
+		{
+			name:      "move_up_in_different_parents",
+			focus:     []string{"1.2", "2"},
+			key:       tea.KeyMsg{Type: tea.KeyUp, Alt: true},
+			wantShape: []string{"2(2.1)", "1(1.2,1.1(1.1.1))"},
+			wantMoved: []string{"1.2", "2"},
+		},
+		{
+			name:      "move_block_down_at_end_is_noop",
+			focus:     []string{"1.2", "1.1"},
+			key:       tea.KeyMsg{Type: tea.KeyDown, Alt: true},
+			wantShape: []string{"1(1.1(1.1.1),1.2)", "2(2.1)"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			model := newModel()
+			ctx := context.Background()
+			if err := model.SetAllFocusedIDs(ctx, test.focus); err != nil {
+				t.Fatalf("SetAllFocusedIDs(%v) error = %v", test.focus, err)
+			}
+
+			_, cmd := model.Update(test.key)
+
+			if diff := cmp.Diff(test.wantShape, treeShape(model.Nodes())); diff != "" {
+				t.Errorf("Update(%v) shape mismatch (-want +got):\n%s", test.key, diff)
+			}
+			var gotMoved []string
+			for _, msg := range collectMsgs(cmd) {
+				if moved, ok := msg.(NodeMovedMsg); ok {
+					gotMoved = moved.IDs
+				}
+			}
+			if diff := cmp.Diff(test.wantMoved, gotMoved); diff != "" {
+				t.Errorf("Update(%v) NodeMovedMsg mismatch (-want +got):\n%s", test.key, diff)
+			}
+			if got := model.GetFocusedID(); got != test.focus[0] {
+				t.Errorf("Update(%v) focused = %q, want %q", test.key, got, test.focus[0])
+			}
+		})
+	}
+}
+
+func TestUndoRedoKeys(t *testing.T) {
+	tree := createSimpleTree(t)
+	tree.history.limit = defaultHistoryLimit
+	tree.focusPol = defaultFocusPolicy[string]
+	model := NewTuiTreeModel(tree)
+	ctx := context.Background()
+	if err := model.SetAllFocusedIDs(ctx, []string{"1.2", "2"}); err != nil {
+		t.Fatalf("SetAllFocusedIDs() error = %v", err)
+	}
+	original := treeShape(model.Nodes())
+
+	model.Update(tea.KeyMsg{Type: tea.KeyUp, Alt: true})
+	moved := treeShape(model.Nodes())
+	if cmp.Equal(original, moved) {
+		t.Fatalf("Update(alt+up) did not move any node")
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlZ})
+	if diff := cmp.Diff(original, treeShape(model.Nodes())); diff != "" {
+		t.Errorf("Update(ctrl+z) did not undo the grouped move (-want +got):\n%s", diff)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlY})
+	if diff := cmp.Diff(moved, treeShape(model.Nodes())); diff != "" {
+		t.Errorf("Update(ctrl+y) did not redo the grouped move (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearch_QueryMode(t *testing.T) {
+	nodes := []*Node[string]{
+		NewNode("main.go", "main.go", ""),
+		NewNode("README.md", "README.md", ""),
+	}
+	model := NewTuiTreeModel(NewTree(nodes), WithTuiQuerySearch[string](nil))
+	model.BeginSearch()
+
+	matches, err := model.Search("ext:go")
+	if err != nil {
+		t.Fatalf("Search(ext:go) error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"main.go"}, nodeIDs(matches)); diff != "" {
+		t.Errorf("Search(ext:go) mismatch (-want +got):\n%s", diff)
+	}
+	if view := model.View(); !strings.Contains(view, "Query: ext:go") {
+		t.Errorf("View() = %q, want the query prompt", view)
+	}
+
+	// A broken query keeps the previous results and shows the error inline
+	matches, err = model.Search("ext:go AND (")
+	if !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("Search(broken) error = %v, want %v", err, ErrInvalidQuery)
+	}
+	if diff := cmp.Diff([]string{"main.go"}, nodeIDs(matches)); diff != "" {
+		t.Errorf("Search(broken) mismatch (-want +got):\n%s", diff)
+	}
+	if view := model.View(); !strings.Contains(view, "⚠ invalid query: unexpected end of query at position 12") {
+		t.Errorf("View() = %q, want the parse error next to the prompt", view)
+	}
+}
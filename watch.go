@@ -0,0 +1,491 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Digital-Shane/treeview/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A FileSystemWatcher keeps a tree built by NewTreeFromFileSystem in step
+// with the disk. Whenever a watched directory changes, its entries are read
+// again and compared with the node's children: new entries are scanned and
+// inserted, vanished ones removed, and changed ones get fresh FileInfo. The
+// nodes that stay are left alone, so expansion, focus and selection survive.
+// A rename shows up as a removal plus an addition.
+//
+// On Linux directories are watched with inotify; elsewhere, or with
+// WithWatchPolling, every watched directory is re-read on a timer.
+
+const (
+	defaultWatchPollInterval = time.Second
+	defaultWatchDebounce     = 50 * time.Millisecond
+)
+
+// fileSystemSource is how NewTreeFromFileSystem built a tree, so entries that
+// appear later are scanned the same way.
+type fileSystemSource struct {
+	followSymlinks bool
+	cfg            *MasterConfig[FileInfo]
+}
+
+// WatchOption configures WatchFileSystem.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	poll     time.Duration // Polling interval; 0 uses file system notifications
+	debounce time.Duration // Quiet time before notified changes are applied
+}
+
+// WithWatchPolling re-reads the watched directories every interval instead
+// of relying on file system notifications. Polling works everywhere but
+// costs a directory read per watched directory and tick.
+func WithWatchPolling(interval time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		if interval <= 0 {
+			interval = defaultWatchPollInterval
+		}
+		c.poll = interval
+	}
+}
+
+// WithWatchDebounce sets how long the watcher waits for a burst of
+// notifications to settle before applying them. The default is 50ms.
+func WithWatchDebounce(d time.Duration) WatchOption {
+	return func(c *watchConfig) { c.debounce = d }
+}
+
+// watchBackend reports directories whose entries may have changed.
+type watchBackend interface {
+	// watch makes the backend watch exactly dirs.
+	watch(dirs []string) error
+	// changed delivers the paths of directories with changes.
+	changed() <-chan string
+	close() error
+}
+
+// FileSystemWatcher applies file system changes to a tree; see
+// WatchFileSystem.
+type FileSystemWatcher struct {
+	tree    *Tree[FileInfo]
+	src     *fileSystemSource
+	cfg     watchConfig
+	changes chan FileSystemChangedMsg
+	cancel  context.CancelFunc
+	done    chan struct{}
+	polling bool
+}
+
+// WatchFileSystem starts applying changes on disk to tree, which must have
+// been built by NewTreeFromFileSystem. New entries are scanned with the same
+// options as the tree: the filter function (use it for .gitignore style
+// rules), WithMaxDepth, WithExpandFunc, WithSort and symbolic link handling
+// all apply, and directories at the depth limit are not watched. Changes
+// are not recorded in the undo history. Instead, undo and redo steps that
+// involve a changed entry or a directory whose entries changed are dropped
+// from it, since replaying them could bring back files that no longer exist;
+// steps elsewhere in the tree are kept.
+//
+// The watcher runs until ctx is cancelled or Close is called. Each batch of
+// changes is reported on Changes; Wait turns that into a tea.Cmd, and
+// WithTuiWatcher hooks it up to a TuiTreeModel. Returns ErrNotFileSystemTree
+// for other trees.
+func WatchFileSystem(ctx context.Context, tree *Tree[FileInfo], opts ...WatchOption) (*FileSystemWatcher, error) {
+	tree.mu.RLock()
+	src := tree.fsSource
+	tree.mu.RUnlock()
+	if src == nil {
+		return nil, ErrNotFileSystemTree
+	}
+
+	cfg := watchConfig{debounce: defaultWatchDebounce}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	// Fall back to polling where notifications aren't available
+	var backend watchBackend
+	if cfg.poll == 0 {
+		var err error
+		if backend, err = newNotifyBackend(); err != nil {
+			cfg.poll = defaultWatchPollInterval
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &FileSystemWatcher{
+		tree:    tree,
+		src:     src,
+		cfg:     cfg,
+		changes: make(chan FileSystemChangedMsg, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		polling: backend == nil,
+	}
+	if backend != nil {
+		if err := backend.watch(w.watchedDirs(ctx)); err != nil {
+			_ = backend.close()
+			backend = nil
+			w.polling = true
+			w.cfg.poll = max(w.cfg.poll, defaultWatchPollInterval)
+		}
+	}
+	go w.run(ctx, backend)
+	return w, nil
+}
+
+// Changes delivers a FileSystemChangedMsg for every batch of changes applied
+// to the tree. Batches the receiver hasn't picked up yet are merged, so a
+// slow receiver gets fewer, larger messages. The channel is closed when the
+// watcher stops.
+func (w *FileSystemWatcher) Changes() <-chan FileSystemChangedMsg {
+	return w.changes
+}
+
+// Wait returns a command that waits for the next batch of changes and
+// returns it as a FileSystemChangedMsg, or nil once the watcher has stopped.
+// Issue it again after each message to keep listening.
+func (w *FileSystemWatcher) Wait() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-w.changes
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// Polling reports whether the watcher polls rather than using file system
+// notifications.
+func (w *FileSystemWatcher) Polling() bool {
+	return w.polling
+}
+
+// Close stops the watcher and waits for it to finish.
+func (w *FileSystemWatcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// run applies changes until ctx is done.
+func (w *FileSystemWatcher) run(ctx context.Context, backend watchBackend) {
+	defer close(w.done)
+	defer close(w.changes)
+
+	if backend == nil {
+		ticker := time.NewTicker(w.cfg.poll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				dirs := w.dirNodes(ctx)
+				w.apply(ctx, watched(dirs, w.src.cfg), dirs)
+			}
+		}
+	}
+
+	defer backend.close()
+	pending := make(map[string]bool)
+	timer := time.NewTimer(0)
+	<-timer.C
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case dir, ok := <-backend.changed():
+			if !ok {
+				return
+			}
+			if len(pending) == 0 {
+				timer.Reset(w.cfg.debounce)
+			}
+			pending[dir] = true
+		case <-timer.C:
+			dirs := make([]string, 0, len(pending))
+			for dir := range pending {
+				dirs = append(dirs, dir)
+			}
+			clear(pending)
+			slices.Sort(dirs) // Parents before their children
+			if w.apply(ctx, dirs, w.dirNodes(ctx)) {
+				if err := backend.watch(w.watchedDirs(ctx)); err != nil {
+					w.publish(FileSystemChangedMsg{Err: err})
+				}
+			}
+		}
+	}
+}
+
+// apply reconciles the directories at paths, looked up in dirs, with the
+// disk and publishes what changed. It reports whether nodes were added or
+// removed.
+func (w *FileSystemWatcher) apply(ctx context.Context, paths []string, dirs map[string]dirNode) bool {
+	var batch FileSystemChangedMsg
+	for _, path := range paths {
+		dir, ok := dirs[path]
+		if !ok {
+			continue // Gone with its parent
+		}
+		change, err := w.reconcile(ctx, dir)
+		batch = batch.merge(change)
+		if err != nil && batch.Err == nil && ctx.Err() == nil {
+			batch.Err = err
+		}
+	}
+	if !batch.empty() {
+		w.publish(batch)
+	}
+	return len(batch.Added)+len(batch.Removed) > 0
+}
+
+// publish hands msg to the receiver, merging it with a batch that hasn't
+// been picked up yet.
+func (w *FileSystemWatcher) publish(msg FileSystemChangedMsg) {
+	for {
+		select {
+		case w.changes <- msg:
+			return
+		default:
+		}
+		select {
+		case old := <-w.changes:
+			msg = old.merge(msg)
+		default:
+		}
+	}
+}
+
+// dirNode is a directory node of the watched tree and its depth.
+type dirNode struct {
+	node  *Node[FileInfo]
+	depth int
+}
+
+// dirNodes returns the directory nodes of the tree by path, collected in one
+// walk under the tree lock so that a batch of changes doesn't search the
+// tree once per directory.
+func (w *FileSystemWatcher) dirNodes(ctx context.Context) map[string]dirNode {
+	w.tree.mu.RLock()
+	defer w.tree.mu.RUnlock()
+	dirs := make(map[string]dirNode)
+	for info, err := range dfsSeq(ctx, w.tree.nodes, true, nil) {
+		if err != nil {
+			return dirs
+		}
+		if isDir(info.Node) {
+			dirs[info.Node.ID()] = dirNode{node: info.Node, depth: info.Depth}
+		}
+	}
+	return dirs
+}
+
+// watchedDirs returns the paths of the directory nodes whose entries are
+// part of the tree, that is all directories above the depth limit.
+func (w *FileSystemWatcher) watchedDirs(ctx context.Context) []string {
+	return watched(w.dirNodes(ctx), w.src.cfg)
+}
+
+// watched returns the sorted paths of the directories in dirs above the
+// depth limit of cfg. Sorting puts parents before their children.
+func watched(dirs map[string]dirNode, cfg *MasterConfig[FileInfo]) []string {
+	paths := make([]string, 0, len(dirs))
+	for path, dir := range dirs {
+		if !cfg.HasDepthLimitBeenReached(dir.depth) {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// reconcile reads the directory of d and brings the children of its node in
+// line with it.
+func (w *FileSystemWatcher) reconcile(ctx context.Context, d dirNode) (FileSystemChangedMsg, error) {
+	var change FileSystemChangedMsg
+	dir, depth, path := d.node, d.depth, d.node.ID()
+	if w.src.cfg.HasDepthLimitBeenReached(depth) {
+		return change, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return change, nil // The parent's change removes it
+		}
+		return change, pathError(ErrDirectoryScan, path, err)
+	}
+
+	// Read the entries the way scanDir does
+	cfg := w.src.cfg
+	visited := make(map[string]struct{})
+	listed := make(map[string]os.FileInfo, len(entries))
+	var order []string
+	for _, entry := range entries {
+		childPath := filepath.Join(path, entry.Name())
+		info, err := utils.SafeStat(childPath, w.src.followSymlinks, visited)
+		if err != nil {
+			continue // Vanished while reading, or a symbolic link loop
+		}
+		if cfg.ShouldFilter(FileInfo{FileInfo: info, Path: childPath}) {
+			continue
+		}
+		listed[childPath] = info
+		order = append(order, childPath)
+	}
+
+	existing := make(map[string]*Node[FileInfo])
+	var removed []*Node[FileInfo]
+	var modified []*Node[FileInfo]
+	var updates []FileInfo
+	w.tree.mu.RLock()
+	for _, child := range dir.Children() {
+		info, ok := listed[child.ID()]
+		old := child.Data()
+		switch {
+		case !ok || info.IsDir() != old.IsDir():
+			removed = append(removed, child)
+		case !sameFileState(old.FileInfo, info):
+			existing[child.ID()] = child
+			modified = append(modified, child)
+			updates = append(updates, FileInfo{FileInfo: info, Path: old.Path, Extra: old.Extra})
+		default:
+			existing[child.ID()] = child
+		}
+	}
+	w.tree.mu.RUnlock()
+
+	var added []*Node[FileInfo]
+	var scanErr error
+	for _, childPath := range order {
+		if existing[childPath] != nil {
+			continue
+		}
+		info := listed[childPath]
+		node := NewFileSystemNode(childPath, info)
+		cfg.HandleExpansion(node)
+		if info.IsDir() {
+			count := 0
+			if err := scanDir(ctx, node, depth+1, w.src.followSymlinks, cfg, visited, &count); err != nil && scanErr == nil {
+				scanErr = err
+			}
+			if cfg.sortLess != nil && node.HasChildren() {
+				sortNodes(node.children, cfg.sortLess)
+			}
+		}
+		added = append(added, node)
+	}
+
+	t := w.tree
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.contains(dir) {
+		return change, scanErr
+	}
+	// Undo steps involving these nodes would replay a state the disk no
+	// longer has
+	changed := make(map[*Node[FileInfo]]bool)
+	for _, node := range removed {
+		if node.Parent() == dir {
+			t.removeNode(node)
+			change.Removed = append(change.Removed, node.ID())
+			changed[dir] = true
+			for info := range dfsSeq(context.Background(), []*Node[FileInfo]{node}, true, nil) {
+				changed[info.Node] = true
+			}
+		}
+	}
+	for i, node := range modified {
+		if node.Parent() == dir {
+			changed[node] = true
+			t.replaceNodeData(node, updates[i])
+			change.Modified = append(change.Modified, node.ID())
+		}
+	}
+	for _, node := range added {
+		if slices.ContainsFunc(dir.children, func(c *Node[FileInfo]) bool { return c.ID() == node.ID() }) {
+			continue // Added meanwhile
+		}
+		t.attachNode(node, dir, insertIndex(dir.children, node, cfg.sortLess))
+		change.Added = append(change.Added, node.ID())
+		changed[dir] = true
+	}
+	if len(added) > 0 {
+		t.refreshAncestorSelection(dir)
+	}
+	t.history.forget(changed)
+	return change, scanErr
+}
+
+// merge returns the changes of m followed by those of other.
+func (m FileSystemChangedMsg) merge(other FileSystemChangedMsg) FileSystemChangedMsg {
+	m.Added = append(m.Added, other.Added...)
+	m.Removed = append(m.Removed, other.Removed...)
+	m.Modified = append(m.Modified, other.Modified...)
+	if m.Err == nil {
+		m.Err = other.Err
+	}
+	return m
+}
+
+// empty reports whether m carries nothing.
+func (m FileSystemChangedMsg) empty() bool {
+	return len(m.Added)+len(m.Removed)+len(m.Modified) == 0 && m.Err == nil
+}
+
+// contains reports whether node is part of the tree. Callers must hold t.mu.
+func (t *Tree[T]) contains(node *Node[T]) bool {
+	for node.parent != nil {
+		node = node.parent
+	}
+	return slices.Contains(t.nodes, node)
+}
+
+// insertIndex returns where node goes among siblings: the sort position if
+// the tree was built with WithSort, otherwise by name as directories are
+// read.
+func insertIndex(siblings []*Node[FileInfo], node *Node[FileInfo], less LessFn[FileInfo]) int {
+	if less == nil {
+		less = func(a, b *Node[FileInfo]) bool {
+			return strings.Compare(filepath.Base(a.ID()), filepath.Base(b.ID())) < 0
+		}
+	}
+	for i, sibling := range siblings {
+		if less(node, sibling) {
+			return i
+		}
+	}
+	return len(siblings)
+}
+
+// sameFileState reports whether a file looks unchanged.
+func sameFileState(a, b os.FileInfo) bool {
+	return a.Mode() == b.Mode() && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// WithTuiWatcher delivers the changes of w to the model: the view follows
+// the tree as it changes, focus moves to the first root if the focused
+// entries were removed, and the FileSystemChangedMsg passes through Update
+// for the parent model to see. The model starts listening in Init.
+func WithTuiWatcher(w *FileSystemWatcher) TuiTreeModelOption[FileInfo] {
+	return func(m *TuiTreeModel[FileInfo]) { m.watchCmd = w.Wait() }
+}
+
+// handleFileSystemChanged refocuses after removals and keeps listening.
+func (m *TuiTreeModel[T]) handleFileSystemChanged() tea.Cmd {
+	if len(m.GetAllFocusedIDs()) == 0 {
+		if roots := m.Nodes(); len(roots) > 0 {
+			_, _ = m.SetFocusedID(context.Background(), roots[0].ID())
+		}
+	}
+	return m.watchCmd
+}
@@ -0,0 +1,41 @@
+package treeview
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// BenchmarkWatchPoll runs one polling pass of a watcher over an unchanged
+// directory of 300 subdirectories holding 10 files each.
+func BenchmarkWatchPoll(b *testing.B) {
+	dir := b.TempDir()
+	for i := 0; i < 300; i++ {
+		sub := filepath.Join(dir, fmt.Sprintf("d%03d", i))
+		if err := os.Mkdir(sub, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < 10; j++ {
+			if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("f%d", j)), nil, 0o644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	ctx := context.Background()
+	tree, err := NewTreeFromFileSystem(ctx, dir, false)
+	if err != nil {
+		b.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	w := &FileSystemWatcher{tree: tree, src: tree.fsSource, changes: make(chan FileSystemChangedMsg, 1)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dirs := w.dirNodes(ctx)
+		if w.apply(ctx, watched(dirs, w.src.cfg), dirs) {
+			b.Fatal("apply() = true for an unchanged directory")
+		}
+	}
+}
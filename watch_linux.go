@@ -0,0 +1,133 @@
+//go:build linux
+// +build linux
+
+package treeview
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the events that change a directory's entries or their
+// FileInfo.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_ONLYDIR
+
+// inotifyBackend watches directories with inotify.
+type inotifyBackend struct {
+	file *os.File
+	fd   int
+	out  chan string
+	done chan struct{}
+
+	mu    sync.Mutex
+	wds   map[string]int // Watch descriptor by directory
+	paths map[int]string // Directory by watch descriptor
+}
+
+// newNotifyBackend returns the inotify backend.
+func newNotifyBackend() (watchBackend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	b := &inotifyBackend{
+		// A non-blocking descriptor is served by the runtime poller, so
+		// closing the file interrupts a pending read
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		fd:    fd,
+		out:   make(chan string),
+		done:  make(chan struct{}),
+		wds:   make(map[string]int),
+		paths: make(map[int]string),
+	}
+	go b.read()
+	return b, nil
+}
+
+func (b *inotifyBackend) watch(dirs []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keep := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		keep[dir] = true
+		if _, ok := b.wds[dir]; ok {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(b.fd, dir, inotifyMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ENOTDIR) {
+				continue // Gone already; its parent reports that
+			}
+			return pathError(ErrFileSystem, dir, os.NewSyscallError("inotify_add_watch", err))
+		}
+		b.wds[dir] = wd
+		b.paths[wd] = dir
+	}
+	for dir, wd := range b.wds {
+		if !keep[dir] {
+			_, _ = syscall.InotifyRmWatch(b.fd, uint32(wd))
+			delete(b.wds, dir)
+			delete(b.paths, wd)
+		}
+	}
+	return nil
+}
+
+func (b *inotifyBackend) changed() <-chan string {
+	return b.out
+}
+
+func (b *inotifyBackend) close() error {
+	close(b.done)
+	return b.file.Close()
+}
+
+// read turns inotify events into the directories they happened in.
+func (b *inotifyBackend) read() {
+	defer close(b.out)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := b.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			var dirs []string
+			b.mu.Lock()
+			switch {
+			case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+				// Events were lost; look at everything
+				for dir := range b.wds {
+					dirs = append(dirs, dir)
+				}
+			case event.Mask&syscall.IN_IGNORED != 0:
+				// The directory is gone or no longer watched
+				if dir, ok := b.paths[int(event.Wd)]; ok {
+					delete(b.wds, dir)
+					delete(b.paths, int(event.Wd))
+				}
+			default:
+				if dir, ok := b.paths[int(event.Wd)]; ok {
+					dirs = append(dirs, dir)
+				}
+			}
+			b.mu.Unlock()
+
+			for _, dir := range dirs {
+				select {
+				case b.out <- dir:
+				case <-b.done:
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package treeview
+
+import "errors"
+
+// newNotifyBackend reports that notifications are unavailable, so
+// WatchFileSystem polls.
+func newNotifyBackend() (watchBackend, error) {
+	return nil, errors.New("file system notifications are not supported on this platform")
+}
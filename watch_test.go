@@ -0,0 +1,377 @@
+package treeview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// relIDs lists the IDs of all nodes relative to dir.
+func relIDs(t *testing.T, tree *Tree[FileInfo], dir string) []string {
+	t.Helper()
+	var ids []string
+	for info, err := range tree.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("All() error = %v", err)
+		}
+		rel, _ := filepath.Rel(dir, info.Node.ID())
+		ids = append(ids, filepath.ToSlash(rel))
+	}
+	return ids
+}
+
+// waitForTree collects changes from w until the tree below dir matches want.
+func waitForTree(t *testing.T, w *FileSystemWatcher, tree *Tree[FileInfo], dir string, want []string) FileSystemChangedMsg {
+	t.Helper()
+	var all FileSystemChangedMsg
+	timeout := time.After(5 * time.Second)
+	for {
+		if cmp.Equal(want, relIDs(t, tree, dir)) {
+			return all
+		}
+		select {
+		case msg, ok := <-w.Changes():
+			if !ok {
+				t.Fatalf("Changes() closed early")
+			}
+			all = all.merge(msg)
+		case <-timeout:
+			t.Fatalf("tree mismatch after waiting (-want +got):\n%s", cmp.Diff(want, relIDs(t, tree, dir)))
+		}
+	}
+}
+
+// watchBackends runs a test with notifications and with polling.
+var watchBackends = []struct {
+	name string
+	opts []WatchOption
+}{
+	{name: "notify"},
+	{name: "polling", opts: []WatchOption{WithWatchPolling(10 * time.Millisecond)}},
+}
+
+func TestWatchFileSystem(t *testing.T) {
+	for _, backend := range watchBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := createDiskUsageDir(t, map[string]int{
+				"a.txt":       1,
+				"c.txt":       1,
+				"sub/old.txt": 1,
+			})
+			tree, err := NewTreeFromFileSystem(ctx, dir, false, WithExpandAll[FileInfo]())
+			if err != nil {
+				t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+			}
+			if _, err := tree.SetFocusedID(ctx, filepath.Join(dir, "c.txt")); err != nil {
+				t.Fatalf("SetFocusedID() error = %v", err)
+			}
+			if _, err := tree.SetExpanded(ctx, filepath.Join(dir, "sub"), false); err != nil {
+				t.Fatalf("SetExpanded() error = %v", err)
+			}
+
+			w, err := WatchFileSystem(ctx, tree, backend.opts...)
+			if err != nil {
+				t.Fatalf("WatchFileSystem() error = %v", err)
+			}
+			defer w.Close()
+			if polling := w.Polling(); polling != (backend.opts != nil) && runtime.GOOS == "linux" {
+				t.Errorf("Polling() = %v, want %v", polling, backend.opts != nil)
+			}
+
+			// Create, delete, rename and modify
+			write := func(name string, size int) {
+				t.Helper()
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("b.txt", 1)
+			write("new/deep.txt", 1)
+			if err := os.Remove(filepath.Join(dir, "a.txt")); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Rename(filepath.Join(dir, "sub", "old.txt"), filepath.Join(dir, "sub", "renamed.txt")); err != nil {
+				t.Fatal(err)
+			}
+			write("c.txt", 100)
+
+			want := []string{".", "b.txt", "c.txt", "new", "new/deep.txt", "sub", "sub/renamed.txt"}
+			waitForTree(t, w, tree, dir, want)
+
+			c, err := tree.FindByID(ctx, filepath.Join(dir, "c.txt"))
+			if err != nil {
+				t.Fatalf("FindByID(c.txt) error = %v", err)
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for c.Data().Size() != 100 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if size := c.Data().Size(); size != 100 {
+				t.Errorf("c.txt size = %d, want 100", size)
+			}
+			if id := tree.GetFocusedID(); id != c.ID() {
+				t.Errorf("GetFocusedID() = %q, want the focus kept on c.txt", id)
+			}
+			sub, _ := tree.FindByID(ctx, filepath.Join(dir, "sub"))
+			if sub.IsExpanded() {
+				t.Errorf("sub expanded after changes, want its expansion kept")
+			}
+		})
+	}
+}
+
+func TestWatchFileSystem_BuildOptions(t *testing.T) {
+	for _, backend := range watchBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := createDiskUsageDir(t, map[string]int{"keep.txt": 1, "sub/x.txt": 1})
+			tree, err := NewTreeFromFileSystem(ctx, dir, false,
+				WithMaxDepth[FileInfo](1),
+				WithFilterFunc(func(fi FileInfo) bool { return !strings.HasSuffix(fi.Name(), ".log") }),
+			)
+			if err != nil {
+				t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+			}
+			w, err := WatchFileSystem(ctx, tree, backend.opts...)
+			if err != nil {
+				t.Fatalf("WatchFileSystem() error = %v", err)
+			}
+			defer w.Close()
+
+			// Filtered entries and entries below the depth limit stay out
+			for _, name := range []string{"skip.log", "sub/deeper.txt", "newdir/inside.txt", "zz.txt"} {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			waitForTree(t, w, tree, dir, []string{".", "keep.txt", "newdir", "sub", "zz.txt"})
+		})
+	}
+}
+
+func TestWatchFileSystem_Sorted(t *testing.T) {
+	ctx := context.Background()
+	dir := createDiskUsageDir(t, map[string]int{"a.txt": 1, "sub/x.txt": 1})
+	tree, err := NewTreeFromFileSystem(ctx, dir, false, WithSort(ByDirsFirst()))
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	w, err := WatchFileSystem(ctx, tree, WithWatchPolling(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchFileSystem() error = %v", err)
+	}
+	defer w.Close()
+
+	if err := os.Mkdir(filepath.Join(dir, "zdir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitForTree(t, w, tree, dir, []string{".", "sub", "sub/x.txt", "zdir", "a.txt"})
+}
+
+func TestWatchFileSystem_History(t *testing.T) {
+	ctx := context.Background()
+	dir := createDiskUsageDir(t, map[string]int{"keep/a.txt": 1, "sub/x.txt": 1})
+	tree, err := NewTreeFromFileSystem(ctx, dir, false, WithExpandAll[FileInfo]())
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	a := filepath.Join(dir, "keep", "a.txt")
+	if err := tree.Rename(ctx, a, "A"); err != nil {
+		t.Fatalf("Rename(a.txt) error = %v", err)
+	}
+	if err := tree.Rename(ctx, filepath.Join(dir, "sub", "x.txt"), "X"); err != nil {
+		t.Fatalf("Rename(x.txt) error = %v", err)
+	}
+
+	w, err := WatchFileSystem(ctx, tree, WithWatchPolling(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchFileSystem() error = %v", err)
+	}
+	defer w.Close()
+	if err := os.Remove(filepath.Join(dir, "sub", "x.txt")); err != nil {
+		t.Fatal(err)
+	}
+	waitForTree(t, w, tree, dir, []string{".", "keep", "keep/a.txt", "sub"})
+
+	// The rename of the removed file is gone, the other one can be undone
+	if ok, err := tree.Undo(ctx); !ok || err != nil {
+		t.Fatalf("Undo() = %v, %v, want true, nil", ok, err)
+	}
+	if node, _ := tree.FindByID(ctx, a); node.Name() != "a.txt" {
+		t.Errorf("a.txt name after Undo() = %q, want %q", node.Name(), "a.txt")
+	}
+	if tree.CanUndo() {
+		t.Errorf("CanUndo() = true, want the step for the removed file dropped")
+	}
+}
+
+// Run with -race: the watcher refreshes FileInfo while other goroutines
+// render, search, aggregate and snapshot the tree.
+func TestWatchFileSystem_ConcurrentReaders(t *testing.T) {
+	ctx := context.Background()
+	dir := createDiskUsageDir(t, map[string]int{"a.txt": 1, "sub/b.txt": 1})
+	tree, err := NewTreeFromFileSystem(ctx, dir, false, WithExpandAll[FileInfo](), WithSearchIndex[FileInfo](nil))
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	stats, err := AggregateFileStats(ctx, tree)
+	if err != nil {
+		t.Fatalf("AggregateFileStats() error = %v", err)
+	}
+	tree.SetProvider(NewDefaultNodeProvider(WithFileStatsFormatter(stats)))
+	w, err := WatchFileSystem(ctx, tree, WithWatchPolling(time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchFileSystem() error = %v", err)
+	}
+	defer w.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	run := func(step func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					step()
+				}
+			}
+		}()
+	}
+	run(func() { _, _ = tree.Render(ctx) })
+	run(func() { _, _ = tree.SearchMatches(ctx, "b.txt") })
+	run(func() { stats.Value(tree.Nodes()[0]) })
+	run(func() { _, _ = tree.Snapshot().Render(ctx) })
+	go func() {
+		for range w.Changes() {
+		}
+	}()
+
+	b := filepath.Join(dir, "sub", "b.txt")
+	for size := 2; size <= 20; size++ {
+		if err := os.WriteFile(b, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for stats.Value(tree.Nodes()[0]).Bytes != 21 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	if got := stats.Value(tree.Nodes()[0]).Bytes; got != 21 {
+		t.Errorf("total bytes = %d after the watcher caught up, want 21", got)
+	}
+}
+
+func TestWatchFileSystem_NotFileSystemTree(t *testing.T) {
+	tree := NewTree([]*Node[FileInfo]{NewNode("a", "a", FileInfo{})})
+	if _, err := WatchFileSystem(context.Background(), tree); !errors.Is(err, ErrNotFileSystemTree) {
+		t.Errorf("WatchFileSystem() error = %v, want %v", err, ErrNotFileSystemTree)
+	}
+}
+
+func TestWatchFileSystem_Close(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dir := createDiskUsageDir(t, map[string]int{"a.txt": 1})
+	tree, err := NewTreeFromFileSystem(ctx, dir, false)
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	w, err := WatchFileSystem(ctx, tree)
+	if err != nil {
+		t.Fatalf("WatchFileSystem() error = %v", err)
+	}
+	cancel()
+	w.Close()
+	if msg := w.Wait()(); msg != nil {
+		t.Errorf("Wait() after Close = %v, want nil", msg)
+	}
+}
+
+func TestFileSystemChangedMsg_Merge(t *testing.T) {
+	errFirst := errors.New("first")
+	a := FileSystemChangedMsg{Added: []string{"x"}, Err: errFirst}
+	b := FileSystemChangedMsg{Added: []string{"y"}, Removed: []string{"x"}, Err: errors.New("second")}
+	got := a.merge(b)
+	want := FileSystemChangedMsg{Added: []string{"x", "y"}, Removed: []string{"x"}, Err: errFirst}
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b error) bool { return a == b })); diff != "" {
+		t.Errorf("merge() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTuiWatcher(t *testing.T) {
+	ctx := context.Background()
+	dir := createDiskUsageDir(t, map[string]int{"a.txt": 1, "b.txt": 1})
+	tree, err := NewTreeFromFileSystem(ctx, dir, false, WithExpandAll[FileInfo]())
+	if err != nil {
+		t.Fatalf("NewTreeFromFileSystem() error = %v", err)
+	}
+	w, err := WatchFileSystem(ctx, tree, WithWatchPolling(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchFileSystem() error = %v", err)
+	}
+	defer w.Close()
+	model := NewTuiTreeModel(tree, WithTuiWatcher(w))
+	if _, err := model.SetFocusedID(ctx, filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatalf("SetFocusedID() error = %v", err)
+	}
+
+	cmd := model.Init()
+	if cmd == nil {
+		t.Fatalf("Init() = nil, want the watcher command")
+	}
+	if err := os.Remove(filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	msg, ok := cmd().(FileSystemChangedMsg)
+	if !ok {
+		t.Fatalf("Init() command produced %T, want FileSystemChangedMsg", msg)
+	}
+	if diff := cmp.Diff([]string{filepath.Join(dir, "b.txt")}, msg.Removed); diff != "" {
+		t.Errorf("Removed mismatch (-want +got):\n%s", diff)
+	}
+
+	_, next := model.Update(msg)
+	if next == nil {
+		t.Fatalf("Update(FileSystemChangedMsg) = nil, want the watcher command again")
+	}
+	root := tree.Nodes()[0].ID()
+	if id := model.GetFocusedID(); id != root {
+		t.Errorf("GetFocusedID() = %q, want the root after the focused file was removed", id)
+	}
+
+	// Stop the watcher so its command returns instead of waiting
+	w.Close()
+	var focus []FocusChangedMsg
+	for _, msg := range collectMsgs(next) {
+		if m, ok := msg.(FocusChangedMsg); ok {
+			focus = append(focus, m)
+		}
+	}
+	if diff := cmp.Diff([]FocusChangedMsg{{ID: root}}, focus); diff != "" {
+		t.Errorf("FocusChangedMsg mismatch (-want +got):\n%s", diff)
+	}
+}